@@ -0,0 +1,273 @@
+// Package access authenticates API clients by key and enforces their limits: a token-bucket
+// request rate, a cap on concurrent searches, and the deepest and longest search they may ask
+// for. Keys are read from a local JSON file:
+//
+//	{
+//	  "keys": [
+//	    {"name": "partner-a", "key_sha256": "9f86d0...", "rate_per_minute": 120, "burst": 20,
+//	     "max_concurrent_searches": 2, "max_depth": 6, "max_move_time_ms": 10000}
+//	  ],
+//	  "anonymous": {"rate_per_minute": 10, "burst": 5, "max_concurrent_searches": 1, "max_depth": 4}
+//	}
+//
+// A key may be given in plain text as "key" instead of "key_sha256". Requests without a key
+// are limited per IP address by the "anonymous" limits, and refused when there are none.
+// Zero limits are unlimited.
+package access
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	ErrMissingKey = errors.New("an API key is required")
+	ErrUnknownKey = errors.New("unknown API key")
+)
+
+// Anonymous is the client name of requests without a key.
+const Anonymous = "anonymous"
+
+// anonymousIdle is how long an anonymous client's state is kept after its last request.
+const anonymousIdle = 10 * time.Minute
+
+type Limits struct {
+	RatePerMinute         float64 `json:"rate_per_minute"`
+	Burst                 int     `json:"burst"` // requests allowed at once after a quiet spell; the per-minute rate when zero
+	MaxConcurrentSearches int     `json:"max_concurrent_searches"`
+	MaxDepth              int     `json:"max_depth"`
+	MaxMoveTimeMS         int     `json:"max_move_time_ms"`
+}
+
+type keyEntry struct {
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	KeySHA256 string `json:"key_sha256"`
+	Limits
+}
+
+type keyFile struct {
+	Keys      []keyEntry `json:"keys"`
+	Anonymous *Limits    `json:"anonymous"`
+}
+
+// Registry holds the clients from a key file.
+type Registry struct {
+	byHash    map[[sha256.Size]byte]*Client
+	anonymous *Limits
+
+	mu     sync.Mutex
+	byIP   map[string]*Client // anonymous clients
+	pruned time.Time
+}
+
+// Load reads a key file.
+func Load(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f keyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	r := &Registry{byHash: make(map[[sha256.Size]byte]*Client), anonymous: f.Anonymous, byIP: make(map[string]*Client)}
+	names := make(map[string]bool)
+	for i, k := range f.Keys {
+		if k.Name == "" || k.Name == Anonymous || names[k.Name] {
+			return nil, fmt.Errorf("%s: key %d needs a unique name other than %q", path, i+1, Anonymous)
+		}
+		names[k.Name] = true
+
+		var hash [sha256.Size]byte
+		switch {
+		case k.KeySHA256 != "":
+			b, err := hex.DecodeString(k.KeySHA256)
+			if err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("%s: key %q: key_sha256 must be 64 hex digits", path, k.Name)
+			}
+			copy(hash[:], b)
+		case k.Key != "":
+			hash = sha256.Sum256([]byte(k.Key))
+		default:
+			return nil, fmt.Errorf("%s: key %q has neither key nor key_sha256", path, k.Name)
+		}
+		if err := k.Limits.validate(); err != nil {
+			return nil, fmt.Errorf("%s: key %q: %w", path, k.Name, err)
+		}
+		r.byHash[hash] = newClient(k.Name, k.Limits)
+	}
+	if f.Anonymous != nil {
+		if err := f.Anonymous.validate(); err != nil {
+			return nil, fmt.Errorf("%s: anonymous: %w", path, err)
+		}
+	}
+	return r, nil
+}
+
+func (l Limits) validate() error {
+	if l.RatePerMinute < 0 || l.Burst < 0 || l.MaxConcurrentSearches < 0 || l.MaxDepth < 0 || l.MaxMoveTimeMS < 0 {
+		return errors.New("limits must not be negative")
+	}
+	return nil
+}
+
+// Len is the number of keys.
+func (r *Registry) Len() int {
+	return len(r.byHash)
+}
+
+// Authenticate finds the client for key, or the anonymous client for ip when key is empty.
+func (r *Registry) Authenticate(key, ip string) (*Client, error) {
+	if key == "" {
+		if r.anonymous == nil {
+			return nil, ErrMissingKey
+		}
+		return r.anonymousClient(ip), nil
+	}
+
+	hash := sha256.Sum256([]byte(key))
+	var found *Client
+	// compare against every key so timing does not reveal how close a guess was
+	for h, c := range r.byHash {
+		if subtle.ConstantTimeCompare(h[:], hash[:]) == 1 {
+			found = c
+		}
+	}
+	if found == nil {
+		return nil, ErrUnknownKey
+	}
+	return found, nil
+}
+
+func (r *Registry) anonymousClient(ip string) *Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.pruned) > time.Minute {
+		for addr, c := range r.byIP {
+			if c.idleSince(now) > anonymousIdle {
+				delete(r.byIP, addr)
+			}
+		}
+		r.pruned = now
+	}
+
+	c, ok := r.byIP[ip]
+	if !ok {
+		c = newClient(Anonymous, *r.anonymous)
+		c.id = Anonymous + " " + ip
+		r.byIP[ip] = c
+	}
+	return c
+}
+
+// Client is an authenticated caller and the state of its limits. An anonymous client is
+// dropped once idle and comes back as a new Client, so compare clients by ID.
+type Client struct {
+	Name   string
+	Limits Limits
+	id     string
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time // last refill of tokens
+	seen     time.Time // last request or finished search
+	searches int
+}
+
+func newClient(name string, limits Limits) *Client {
+	if limits.Burst == 0 {
+		limits.Burst = max(1, int(limits.RatePerMinute))
+	}
+	now := time.Now()
+	return &Client{Name: name, Limits: limits, id: name, tokens: float64(limits.Burst), last: now, seen: now}
+}
+
+// ID identifies the client across requests: the key's name, or the address of an anonymous
+// client. It is empty for a nil client, as without API keys.
+func (c *Client) ID() string {
+	if c == nil {
+		return ""
+	}
+	return c.id
+}
+
+// Allow takes a token for one request. When the bucket is empty it reports how long until
+// the next token.
+func (c *Client) Allow() (ok bool, retryAfter time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	c.seen = now
+	if c.Limits.RatePerMinute == 0 {
+		return true, 0
+	}
+	perSecond := c.Limits.RatePerMinute / 60
+	c.tokens = math.Min(float64(c.Limits.Burst), c.tokens+now.Sub(c.last).Seconds()*perSecond)
+	c.last = now
+	if c.tokens >= 1 {
+		c.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - c.tokens) / perSecond * float64(time.Second))
+}
+
+// AcquireSearch reserves one of the client's concurrent searches. release must be called
+// once the search is over. A nil client, as without API keys, has no quota.
+func (c *Client) AcquireSearch() (release func(), ok bool) {
+	if c == nil {
+		return func() {}, true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Limits.MaxConcurrentSearches > 0 && c.searches >= c.Limits.MaxConcurrentSearches {
+		return nil, false
+	}
+	c.searches++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			c.searches--
+			c.seen = time.Now()
+			c.mu.Unlock()
+		})
+	}, true
+}
+
+// MaxDepth caps a server-wide depth limit by the client's own.
+func (c *Client) MaxDepth(serverMax int) int {
+	if c == nil || c.Limits.MaxDepth == 0 {
+		return serverMax
+	}
+	return min(serverMax, c.Limits.MaxDepth)
+}
+
+// MaxMoveTimeMS caps a server-wide search time limit by the client's own.
+func (c *Client) MaxMoveTimeMS(serverMax int) int {
+	if c == nil || c.Limits.MaxMoveTimeMS == 0 {
+		return serverMax
+	}
+	return min(serverMax, c.Limits.MaxMoveTimeMS)
+}
+
+func (c *Client) idleSince(now time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.searches > 0 {
+		return 0
+	}
+	return now.Sub(c.seen)
+}
@@ -0,0 +1,243 @@
+package access
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    Limits
+		idle      time.Duration // time since the bucket was last refilled before the requests
+		requests  int
+		wantAllow int
+	}{
+		{"unlimited", Limits{}, 0, 100, 100},
+		{"burst then refused", Limits{RatePerMinute: 60, Burst: 3}, 0, 5, 3},
+		{"burst defaults to the rate", Limits{RatePerMinute: 4}, 0, 6, 4},
+		{"burst above the rate", Limits{RatePerMinute: 2, Burst: 5}, 0, 7, 5},
+		{"refill is capped at the burst", Limits{RatePerMinute: 60, Burst: 2}, time.Hour, 5, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient("test", tt.limits)
+			c.last = c.last.Add(-tt.idle)
+			allowed := 0
+			for i := 0; i < tt.requests; i++ {
+				if ok, _ := c.Allow(); ok {
+					allowed++
+				}
+			}
+			if allowed != tt.wantAllow {
+				t.Errorf("allowed %d of %d requests, want %d", allowed, tt.requests, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestAllowRefill(t *testing.T) {
+	c := newClient("test", Limits{RatePerMinute: 60, Burst: 2})
+	c.Allow()
+	c.Allow()
+
+	ok, retryAfter := c.Allow()
+	if ok {
+		t.Fatal("allowed a request with an empty bucket")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("retryAfter = %v, want up to one second at 60 per minute", retryAfter)
+	}
+
+	c.last = c.last.Add(-time.Second)
+	if ok, _ := c.Allow(); !ok {
+		t.Error("a second at 60 per minute did not refill a token")
+	}
+	if ok, _ := c.Allow(); ok {
+		t.Error("refilled more than one token")
+	}
+}
+
+func TestAcquireSearch(t *testing.T) {
+	c := newClient("test", Limits{MaxConcurrentSearches: 2})
+	release1, ok1 := c.AcquireSearch()
+	_, ok2 := c.AcquireSearch()
+	_, ok3 := c.AcquireSearch()
+	if !ok1 || !ok2 || ok3 {
+		t.Fatalf("acquired %v %v %v, want true true false", ok1, ok2, ok3)
+	}
+
+	release1()
+	release1() // a second release must not free another slot
+	if _, ok := c.AcquireSearch(); !ok {
+		t.Error("release did not free a slot")
+	}
+	if _, ok := c.AcquireSearch(); ok {
+		t.Error("double release freed two slots")
+	}
+
+	var none *Client
+	release, ok := none.AcquireSearch()
+	if !ok {
+		t.Error("nil client was refused")
+	}
+	release()
+}
+
+func TestClientCaps(t *testing.T) {
+	tests := []struct {
+		name                   string
+		client                 *Client
+		serverDepth, wantDepth int
+		serverTime, wantTime   int
+	}{
+		{"nil client", nil, 8, 8, 5000, 5000},
+		{"no limits", newClient("a", Limits{}), 8, 8, 5000, 5000},
+		{"lower client limits", newClient("a", Limits{MaxDepth: 4, MaxMoveTimeMS: 1000}), 8, 4, 5000, 1000},
+		{"higher client limits", newClient("a", Limits{MaxDepth: 12, MaxMoveTimeMS: 9000}), 8, 8, 5000, 5000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.client.MaxDepth(tt.serverDepth); got != tt.wantDepth {
+				t.Errorf("MaxDepth = %d, want %d", got, tt.wantDepth)
+			}
+			if got := tt.client.MaxMoveTimeMS(tt.serverTime); got != tt.wantTime {
+				t.Errorf("MaxMoveTimeMS = %d, want %d", got, tt.wantTime)
+			}
+		})
+	}
+}
+
+func writeKeys(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	hashed := sha256.Sum256([]byte("secret-b"))
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"plain and hashed keys", `{"keys": [{"name": "a", "key": "secret-a"}, {"name": "b", "key_sha256": "` + hex.EncodeToString(hashed[:]) + `"}]}`, ""},
+		{"duplicate name", `{"keys": [{"name": "a", "key": "x"}, {"name": "a", "key": "y"}]}`, "unique name"},
+		{"reserved name", `{"keys": [{"name": "anonymous", "key": "x"}]}`, "unique name"},
+		{"no key", `{"keys": [{"name": "a"}]}`, "neither key nor key_sha256"},
+		{"short hash", `{"keys": [{"name": "a", "key_sha256": "abcd"}]}`, "64 hex digits"},
+		{"negative limit", `{"keys": [{"name": "a", "key": "x", "max_depth": -1}]}`, "must not be negative"},
+		{"negative anonymous limit", `{"anonymous": {"burst": -1}}`, "anonymous"},
+		{"bad json", `{"keys": [`, "keys.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeKeys(t, tt.content))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Load = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	hashed := sha256.Sum256([]byte("secret-b"))
+	withAnonymous := `{"keys": [{"name": "a", "key": "secret-a", "max_depth": 6}, {"name": "b", "key_sha256": "` + hex.EncodeToString(hashed[:]) + `"}], "anonymous": {"max_depth": 3}}`
+	keysOnly := `{"keys": [{"name": "a", "key": "secret-a"}]}`
+
+	tests := []struct {
+		name     string
+		content  string
+		key, ip  string
+		wantName string
+		wantErr  error
+	}{
+		{"plain key", withAnonymous, "secret-a", "10.0.0.1", "a", nil},
+		{"hashed key", withAnonymous, "secret-b", "10.0.0.1", "b", nil},
+		{"unknown key", withAnonymous, "secret-c", "10.0.0.1", "", ErrUnknownKey},
+		{"anonymous", withAnonymous, "", "10.0.0.1", Anonymous, nil},
+		{"no anonymous limits", keysOnly, "", "10.0.0.1", "", ErrMissingKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := Load(writeKeys(t, tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			c, err := r.Authenticate(tt.key, tt.ip)
+			if err != tt.wantErr {
+				t.Fatalf("Authenticate err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && c.Name != tt.wantName {
+				t.Errorf("client = %q, want %q", c.Name, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestAnonymousClientsPerIP(t *testing.T) {
+	r, err := Load(writeKeys(t, `{"anonymous": {"rate_per_minute": 1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := r.Authenticate("", "10.0.0.1")
+	again, _ := r.Authenticate("", "10.0.0.1")
+	other, _ := r.Authenticate("", "10.0.0.2")
+	if first != again {
+		t.Error("the same IP got a new client")
+	}
+	if first == other {
+		t.Error("different IPs share a client")
+	}
+
+	if ok, _ := first.Allow(); !ok {
+		t.Fatal("first anonymous request refused")
+	}
+	if ok, _ := again.Allow(); ok {
+		t.Error("the same IP got a fresh bucket")
+	}
+	if ok, _ := other.Allow(); !ok {
+		t.Error("another IP shares the bucket")
+	}
+}
+
+func TestClientIDSurvivesPrune(t *testing.T) {
+	r, err := Load(writeKeys(t, `{"keys": [{"name": "a", "key": "secret-a"}], "anonymous": {}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyed, _ := r.Authenticate("secret-a", "10.0.0.1")
+	first, _ := r.Authenticate("", "10.0.0.1")
+	other, _ := r.Authenticate("", "10.0.0.2")
+	if keyed.ID() != "a" || first.ID() == other.ID() || first.ID() == keyed.ID() {
+		t.Fatalf("IDs %q, %q and %q are not distinct", keyed.ID(), first.ID(), other.ID())
+	}
+
+	// idle past anonymousIdle, the next request prunes the client and gets a new one
+	first.seen = first.seen.Add(-anonymousIdle - time.Minute)
+	r.pruned = r.pruned.Add(-2 * time.Minute)
+	again, _ := r.Authenticate("", "10.0.0.1")
+	if again == first {
+		t.Fatal("the idle client was not pruned")
+	}
+	if again.ID() != first.ID() {
+		t.Errorf("ID after the prune = %q, want %q", again.ID(), first.ID())
+	}
+	if (*Client)(nil).ID() != "" {
+		t.Error("a nil client has an ID")
+	}
+}
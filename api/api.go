@@ -0,0 +1,46 @@
+// Package api holds the OpenAPI 3 document of the HTTP API, served at /openapi.json. The
+// client package mirrors its operations and schemas; a change to a route or a response
+// shape belongs in both, and in Version.
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+//go:embed openapi.json
+var Spec []byte
+
+// Version is info.version of the document.
+var Version string
+
+// operations are "METHOD /path" for every documented operation, with gin's :param syntax.
+var operations = make(map[string]bool)
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func init() {
+	var doc struct {
+		Info  struct{ Version string }
+		Paths map[string]map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		panic(fmt.Sprintf("api: openapi.json: %v", err))
+	}
+	Version = doc.Info.Version
+	for path, methods := range doc.Paths {
+		path = pathParam.ReplaceAllString(path, ":$1")
+		for method := range methods {
+			operations[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+}
+
+// Documented reports whether the document describes method on a gin route path such as
+// /games/:id.
+func Documented(method, path string) bool {
+	return operations[method+" "+path]
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDocumented(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{"GET", "/best-move", true},
+		{"GET", "/games/:id", true},
+		{"POST", "/games/:id/moves", true},
+		{"DELETE", "/analyze/batch/:id", true},
+		{"GET", "/openapi.json", true},
+		{"POST", "/best-move", false},
+		{"GET", "/games/{id}", false}, // gin syntax only
+		{"get", "/best-move", false},
+		{"GET", "/nowhere", false},
+	}
+	for _, tt := range tests {
+		if got := Documented(tt.method, tt.path); got != tt.want {
+			t.Errorf("Documented(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+	if Version == "" {
+		t.Error("the document has no info.version")
+	}
+}
+
+func TestSpecReferencesResolve(t *testing.T) {
+	var doc struct {
+		Components struct {
+			Schemas    map[string]json.RawMessage
+			Parameters map[string]json.RawMessage
+			Responses  map[string]json.RawMessage
+		}
+	}
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+	sections := map[string]map[string]json.RawMessage{
+		"schemas":    doc.Components.Schemas,
+		"parameters": doc.Components.Parameters,
+		"responses":  doc.Components.Responses,
+	}
+	refs := regexp.MustCompile(`"\$ref"\s*:\s*"([^"]*)"`).FindAllSubmatch(Spec, -1)
+	if len(refs) == 0 {
+		t.Fatal("no $ref in the document")
+	}
+	for _, m := range refs {
+		ref := string(m[1])
+		parts := strings.Split(strings.TrimPrefix(ref, "#/components/"), "/")
+		if !strings.HasPrefix(ref, "#/components/") || len(parts) != 2 {
+			t.Errorf("unexpected reference %s", ref)
+			continue
+		}
+		if _, ok := sections[parts[0]][parts[1]]; !ok {
+			t.Errorf("%s does not resolve", ref)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/access"
+	"github.com/g0g05arui/chess-engine/enginepb"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	clientContextKey = "access.client"
+	searchContextKey = "access.search"
+)
+
+// publicRoutes are served without a key, for the orchestrator and client generators.
+var publicRoutes = map[string]bool{"/healthz": true, "/readyz": true, "/openapi.json": true}
+
+// keyRoutes need a key even when anonymous access is allowed. /metrics names every key.
+var keyRoutes = map[string]bool{"/metrics": true}
+
+// searchRoutes start searches and count against a client's concurrent-search quota.
+// Asynchronous batches keep holding it until they finish; see holdSearch.
+var searchRoutes = map[string]bool{
+	"/best-move":        true,
+	"/best-move/stream": true,
+	"/games":            true,
+	"/games/:id/moves":  true,
+	"/analyze/batch":    true,
+	"/review":           true,
+}
+
+// authenticate identifies the client by its API key (X-API-Key, or Authorization: Bearer)
+// and applies its rate limit and search quota. Rejections are 401, or 429 with Retry-After.
+func authenticate(keys *access.Registry, m *serverMetrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if publicRoutes[route] || c.Request.Method == "OPTIONS" {
+			c.Next()
+			return
+		}
+
+		key := apiKey(c)
+		client, err := keys.Authenticate(key, c.ClientIP())
+		if err == nil && key == "" && keyRoutes[route] {
+			err = access.ErrMissingKey
+		}
+		if err != nil {
+			name := "unknown"
+			if errors.Is(err, access.ErrMissingKey) {
+				name = "none"
+			}
+			m.apiRejected.Inc(name, "unauthorized")
+			c.Header("WWW-Authenticate", `Bearer realm="chess-engine"`)
+			c.AbortWithStatusJSON(401, gin.H{"error": err.Error()})
+			return
+		}
+
+		if ok, retryAfter := client.Allow(); !ok {
+			m.apiRejected.Inc(client.Name, "rate_limit")
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatusJSON(429, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		if searchRoutes[route] {
+			release, ok := client.AcquireSearch()
+			if !ok {
+				m.apiRejected.Inc(client.Name, "concurrent_searches")
+				c.Header("Retry-After", "1")
+				c.AbortWithStatusJSON(429, gin.H{"error": "too many searches in progress for this key"})
+				return
+			}
+			slot := &searchSlot{release: release}
+			c.Set(searchContextKey, slot)
+			defer func() {
+				if !slot.held {
+					release()
+				}
+			}()
+		}
+
+		c.Set(clientContextKey, client)
+		c.Next()
+		m.apiRequests.Inc(client.Name, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// searchSlot is the concurrent search authenticate reserved for a request.
+type searchSlot struct {
+	release func()
+	held    bool // taken over by the handler
+}
+
+// holdSearch keeps the request's search reserved after the handler returns, for work that
+// outlives the request. The caller must call release once that work is over.
+func holdSearch(c *gin.Context) (release func()) {
+	v, ok := c.Get(searchContextKey)
+	if !ok {
+		return func() {}
+	}
+	slot := v.(*searchSlot)
+	slot.held = true
+	return slot.release
+}
+
+func apiKey(c *gin.Context) string {
+	return keyFrom(c.GetHeader("X-API-Key"), c.GetHeader("Authorization"))
+}
+
+// keyFrom picks the key from the X-API-Key or Authorization: Bearer header.
+func keyFrom(apiKey, authorization string) string {
+	if apiKey != "" {
+		return apiKey
+	}
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
+
+// grpcSearchMethods count against a client's concurrent-search quota.
+var grpcSearchMethods = map[string]bool{
+	enginepb.Engine_Analyze_FullMethodName:  true,
+	enginepb.Engine_BestMove_FullMethodName: true,
+	enginepb.Engine_Perft_FullMethodName:    true,
+}
+
+type grpcClientKey struct{}
+
+// grpcAuthenticate is authenticate for gRPC calls, with the key in the x-api-key or
+// authorization metadata. Rejections are Unauthenticated, or ResourceExhausted with a
+// retry-after header.
+func grpcAuthenticate(keys *access.Registry, m *serverMetrics) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor) {
+	admit := func(ctx context.Context, method string) (context.Context, func(error), error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		var ip string
+		if p, ok := peer.FromContext(ctx); ok {
+			ip, _, _ = net.SplitHostPort(p.Addr.String())
+		}
+		client, err := keys.Authenticate(keyFrom(first(md.Get("x-api-key")), first(md.Get("authorization"))), ip)
+		if err != nil {
+			name := "unknown"
+			if errors.Is(err, access.ErrMissingKey) {
+				name = "none"
+			}
+			m.apiRejected.Inc(name, "unauthorized")
+			return nil, nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+
+		if ok, retryAfter := client.Allow(); !ok {
+			m.apiRejected.Inc(client.Name, "rate_limit")
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))))
+			return nil, nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		}
+		release := func() {}
+		if grpcSearchMethods[method] {
+			var ok bool
+			if release, ok = client.AcquireSearch(); !ok {
+				m.apiRejected.Inc(client.Name, "concurrent_searches")
+				grpc.SetHeader(ctx, metadata.Pairs("retry-after", "1"))
+				return nil, nil, status.Error(codes.ResourceExhausted, "too many searches in progress for this key")
+			}
+		}
+		done := func(err error) {
+			release()
+			m.apiRequests.Inc(client.Name, status.Code(err).String())
+		}
+		return context.WithValue(ctx, grpcClientKey{}, client), done, nil
+	}
+
+	// done runs deferred so a panicking call still gives its search back
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		ctx, done, err := admit(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer func() { done(err) }()
+		return handler(ctx, req)
+	}
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, done, err := admit(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer func() { done(err) }()
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+	return unary, stream
+}
+
+// authenticatedStream carries the client in its context.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// grpcClientFrom is clientFrom for gRPC calls.
+func grpcClientFrom(ctx context.Context) *access.Client {
+	client, _ := ctx.Value(grpcClientKey{}).(*access.Client)
+	return client
+}
+
+// searchTimeoutFor is the deadline of a search run for client: the server's searchTimeout,
+// or the client's max_move_time_ms when that is shorter.
+func searchTimeoutFor(client *access.Client, searchTimeout time.Duration, maxMoveTimeMS int) time.Duration {
+	return min(searchTimeout, time.Duration(client.MaxMoveTimeMS(maxMoveTimeMS))*time.Millisecond)
+}
+
+// clientFrom returns the authenticated client, or nil when keys are not enabled. The
+// limit methods of a nil client return the server's limits unchanged.
+func clientFrom(c *gin.Context) *access.Client {
+	if v, ok := c.Get(clientContextKey); ok {
+		return v.(*access.Client)
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/access"
+	"github.com/g0g05arui/chess-engine/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+// newAuthServer puts the routes behind authenticate. /best-move blocks until release is
+// closed, once started is signalled, so a search can be kept in progress.
+func newAuthServer(t *testing.T, keys string) (r *gin.Engine, started chan struct{}, release chan struct{}) {
+	t.Helper()
+	registry, err := access.Load(writeKeysFile(t, keys))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	started, release = make(chan struct{}, 8), make(chan struct{})
+	r = gin.New()
+	r.Use(authenticate(registry, newServerMetrics(jobs.New(jobs.Options{}))))
+	r.GET("/healthz", func(c *gin.Context) { c.Status(200) })
+	r.GET("/position", func(c *gin.Context) { c.String(200, clientFrom(c).Name) })
+	r.GET("/metrics", func(c *gin.Context) { c.String(200, clientFrom(c).Name) })
+	r.GET("/best-move", func(c *gin.Context) {
+		started <- struct{}{}
+		<-release
+		c.Status(200)
+	})
+	return r, started, release
+}
+
+func request(r *gin.Engine, url string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", url, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func writeKeysFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keys.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestAuthenticate(t *testing.T) {
+	r, _, _ := newAuthServer(t, `{"keys": [{"name": "a", "key": "secret-a"}]}`)
+	tests := []struct {
+		name   string
+		url    string
+		header []string
+		code   int
+		body   string
+	}{
+		{"public route", "/healthz", nil, 200, ""},
+		{"X-API-Key", "/position", []string{"X-API-Key", "secret-a"}, 200, "a"},
+		{"bearer token", "/position", []string{"Authorization", "Bearer secret-a"}, 200, "a"},
+		{"no key", "/position", nil, 401, "an API key is required"},
+		{"unknown key", "/position", []string{"X-API-Key", "secret-b"}, 401, "unknown API key"},
+		{"other scheme", "/position", []string{"Authorization", "Basic secret-a"}, 401, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(r, tt.url, tt.header...)
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("%d %s, want %d with %q", w.Code, w.Body, tt.code, tt.body)
+			}
+			if w.Code == 401 && w.Header().Get("WWW-Authenticate") == "" {
+				t.Error("401 without WWW-Authenticate")
+			}
+		})
+	}
+}
+
+func TestAuthenticateMetricsNeedKey(t *testing.T) {
+	r, _, _ := newAuthServer(t, `{"keys": [{"name": "a", "key": "secret-a"}], "anonymous": {}}`)
+	tests := []struct {
+		name   string
+		url    string
+		header []string
+		code   int
+		body   string
+	}{
+		{"anonymous request", "/position", nil, 200, "anonymous"},
+		{"anonymous metrics", "/metrics", nil, 401, "an API key is required"},
+		{"metrics with a key", "/metrics", []string{"Authorization", "Bearer secret-a"}, 200, "a"},
+		{"metrics with an unknown key", "/metrics", []string{"X-API-Key", "secret-b"}, 401, "unknown API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := request(r, tt.url, tt.header...); w.Code != tt.code || !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("%d %s, want %d with %q", w.Code, w.Body, tt.code, tt.body)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRateLimit(t *testing.T) {
+	r, _, _ := newAuthServer(t, `{"keys": [{"name": "a", "key": "secret-a", "rate_per_minute": 6, "burst": 2}]}`)
+	for i := range 2 {
+		if w := request(r, "/position", "X-API-Key", "secret-a"); w.Code != 200 {
+			t.Fatalf("request %d within the burst = %d", i+1, w.Code)
+		}
+	}
+	w := request(r, "/position", "X-API-Key", "secret-a")
+	if w.Code != 429 || w.Header().Get("Retry-After") != "10" {
+		t.Errorf("request over the burst = %d, Retry-After %q; want 429 after 10s", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := request(r, "/healthz", "X-API-Key", "secret-a"); w.Code != 200 {
+		t.Errorf("a public route was rate limited: %d", w.Code)
+	}
+}
+
+func TestAuthenticateSearchQuota(t *testing.T) {
+	r, started, release := newAuthServer(t, `{"keys": [{"name": "a", "key": "secret-a", "max_concurrent_searches": 1}]}`)
+	first := make(chan int)
+	go func() { first <- request(r, "/best-move", "X-API-Key", "secret-a").Code }()
+	<-started
+
+	if w := request(r, "/best-move", "X-API-Key", "secret-a"); w.Code != 429 || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("second search = %d, Retry-After %q; want 429", w.Code, w.Header().Get("Retry-After"))
+	}
+	if w := request(r, "/position", "X-API-Key", "secret-a"); w.Code != 200 {
+		t.Errorf("a request that does not search = %d while a search runs", w.Code)
+	}
+	close(release)
+	if code := <-first; code != 200 {
+		t.Errorf("first search = %d", code)
+	}
+
+	// the slot is given back with the response
+	go func() { first <- request(r, "/best-move", "X-API-Key", "secret-a").Code }()
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the search slot was not released")
+	}
+	if code := <-first; code != 200 {
+		t.Errorf("search after the first finished = %d", code)
+	}
+}
+
+func TestKeyFrom(t *testing.T) {
+	tests := []struct {
+		apiKey, authorization, want string
+	}{
+		{"k1", "", "k1"},
+		{"", "Bearer k2", "k2"},
+		{"", "bearer k3", "k3"},
+		{"k1", "Bearer k2", "k1"},
+		{"", "Basic k2", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if got := keyFrom(tt.apiKey, tt.authorization); got != tt.want {
+			t.Errorf("keyFrom(%q, %q) = %q, want %q", tt.apiKey, tt.authorization, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/access"
+	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/games"
+	"github.com/g0g05arui/chess-engine/pgn"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	maxBatchPositions = 5000
+	maxBatchDepth     = 6
+	maxAsyncBatches   = 100 // running, and again as many finished ones kept for collection
+	maxClientBatches  = 10  // running asynchronous batches per API key or anonymous address
+	asyncBatchTTL     = time.Hour
+)
+
+type batchRequest struct {
+	FENs    []string `json:"fens"`
+	PGN     string   `json:"pgn"` // every position before each move of every game
+	Depth   int      `json:"depth"`
+	Profile string   `json:"profile"`
+	Async   bool     `json:"async"` // return a batch id to poll instead of streaming
+
+	searchTimeout time.Duration // of each search, within the client's move time limit
+}
+
+type batchItem struct {
+	fen    string
+	board  game_state.Board
+	source string
+}
+
+type batchResult struct {
+	Index    int    `json:"index"`
+	FEN      string `json:"fen"`
+	Source   string `json:"source,omitempty"` // "game 2, move 14..." for PGN input
+	BestMove string `json:"best_move,omitempty"`
+	SAN      string `json:"san,omitempty"`
+	Score    int    `json:"score"`
+	Depth    int    `json:"depth"`
+	Cached   bool   `json:"cached"`
+	Error    string `json:"error,omitempty"`
+}
+
+// batchRunner analyses batches of positions. All batches share one concurrency budget so a
+// large submission cannot starve the rest of the server.
+type batchRunner struct {
+	results       resultCache
+	optsFor       searchOptsFunc
+	slots         chan struct{}
+	searchWorkers int
+	maxDepth      int // server-wide cap on top of maxBatchDepth and maxReviewDepth
+	searchTimeout time.Duration
+	maxMoveTimeMS int
+	shutdown      context.Context // done once the server starts shutting down
+
+	mu      sync.Mutex
+	batches map[string]*asyncBatch
+}
+
+type asyncBatch struct {
+	ID        string        `json:"id"`
+	Total     int           `json:"total"`
+	Completed int           `json:"completed"`
+	Done      bool          `json:"done"`
+	Results   []batchResult `json:"results"`
+
+	cancel   context.CancelFunc
+	finished time.Time
+	owner    string // ID of the only client that can see or cancel it, empty without API keys
+}
+
+func newBatchRunner(results resultCache, optsFor searchOptsFunc, cfg Config, shutdown context.Context) *batchRunner {
+	return &batchRunner{
+		results:       results,
+		optsFor:       optsFor,
+		slots:         make(chan struct{}, max(cfg.BatchConcurrency, 1)),
+		searchWorkers: cfg.JobSearchWorkers,
+		maxDepth:      cfg.MaxDepth,
+		searchTimeout: time.Duration(cfg.SearchTimeoutSeconds) * time.Second,
+		maxMoveTimeMS: cfg.MaxMoveTimeMS,
+		shutdown:      shutdown,
+		batches:       make(map[string]*asyncBatch),
+	}
+}
+
+func (br *batchRunner) register(r *gin.Engine) {
+	// Streams one JSON result per line as positions finish, or returns a batch id with "async"
+	r.POST("/analyze/batch", func(c *gin.Context) {
+		var req batchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		items, err := batchItems(req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Depth == 0 {
+			req.Depth = min(br.results.defaultDepth, clientFrom(c).MaxDepth(br.maxDepth))
+		}
+		if maxDepth := clientFrom(c).MaxDepth(min(maxBatchDepth, br.maxDepth)); req.Depth < 1 || req.Depth > maxDepth {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("depth must be between 1 and %d", maxDepth)})
+			return
+		}
+		opts, err := br.optsFor(req.Profile)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		req.searchTimeout = searchTimeoutFor(clientFrom(c), br.searchTimeout, br.maxMoveTimeMS)
+
+		if req.Async {
+			// the batch counts against the client's search quota until it finishes
+			b, err := br.startAsync(items, req, opts, clientFrom(c), holdSearch(c))
+			if errors.Is(err, errClientBatches) {
+				c.Header("Retry-After", "1")
+				c.JSON(429, gin.H{"error": err.Error()})
+				return
+			}
+			if err != nil {
+				c.JSON(503, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(202, gin.H{"id": b.ID, "total": b.Total})
+			return
+		}
+
+		// the stream ends early, with what it has, when the server shuts down
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		defer context.AfterFunc(br.shutdown, cancel)()
+
+		out := make(chan batchResult)
+		go func() {
+			defer close(out)
+			br.run(ctx, items, req, opts, func(res batchResult) {
+				select {
+				case out <- res:
+				case <-ctx.Done():
+				}
+			})
+		}()
+
+		clearWriteDeadline(c)
+		c.Header("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(c.Writer)
+		completed := 0
+		c.Stream(func(w io.Writer) bool {
+			res, ok := <-out
+			if !ok {
+				return false
+			}
+			completed++
+			enc.Encode(res)
+			return true
+		})
+		enc.Encode(gin.H{"done": true, "total": len(items), "completed": completed})
+	})
+
+	r.GET("/analyze/batch/:id", func(c *gin.Context) {
+		br.mu.Lock()
+		defer br.mu.Unlock()
+		b, ok := br.batches[c.Param("id")]
+		// another key's batch is not found either, so ids cannot be probed
+		if !ok || b.owner != clientFrom(c).ID() {
+			c.JSON(404, gin.H{"error": "batch not found"})
+			return
+		}
+		c.JSON(200, b)
+	})
+
+	r.DELETE("/analyze/batch/:id", func(c *gin.Context) {
+		br.mu.Lock()
+		b, ok := br.batches[c.Param("id")]
+		br.mu.Unlock()
+		if !ok || b.owner != clientFrom(c).ID() {
+			c.JSON(404, gin.H{"error": "batch not found"})
+			return
+		}
+		b.cancel()
+		c.Status(204)
+	})
+}
+
+// batchItems turns the request's FENs or PGN into positions to analyse.
+func batchItems(req batchRequest) ([]batchItem, error) {
+	var items []batchItem
+	for _, fen := range req.FENs {
+		fen = strings.TrimSpace(fen)
+		board, err := game_state.ParseFEN(fen)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, batchItem{fen: fen, board: board})
+	}
+
+	if req.PGN != "" {
+		parsed, err := pgn.Parse(req.PGN)
+		if err != nil {
+			return nil, err
+		}
+		for gi, g := range parsed {
+			steps, err := g.Replay()
+			if err != nil {
+				return nil, fmt.Errorf("game %d: %w", gi+1, err)
+			}
+			for _, st := range steps {
+				_, fullmove := game_state.FENCounters(st.FEN)
+				dots := "."
+				if !st.Before.WhiteTurn {
+					dots = "..."
+				}
+				items = append(items, batchItem{
+					fen:    st.FEN,
+					board:  st.Before,
+					source: fmt.Sprintf("game %d, move %d%s %s", gi+1, fullmove, dots, st.SAN),
+				})
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, errors.New("expected fens or pgn")
+	}
+	if len(items) > maxBatchPositions {
+		return nil, fmt.Errorf("at most %d positions per batch, got %d", maxBatchPositions, len(items))
+	}
+	return items, nil
+}
+
+// run analyses items and calls emit from several goroutines as results finish.
+func (br *batchRunner) run(ctx context.Context, items []batchItem, req batchRequest, opts []game_state.SearchOption, emit func(batchResult)) {
+	br.forEach(ctx, len(items), func(i int) {
+		if !br.acquire(ctx) {
+			return
+		}
+		res := br.analyse(ctx, i, items[i], req, opts)
+		<-br.slots
+		if ctx.Err() == nil {
+			emit(res)
+		}
+	})
+}
+
+// forEach calls do with 0 to n-1 on no more goroutines than there are slots, and stops
+// handing out indexes once ctx is done.
+func (br *batchRunner) forEach(ctx context.Context, n int, do func(i int)) {
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(n, cap(br.slots)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				do(i)
+			}
+		}()
+	}
+
+	for i := 0; i < n && ctx.Err() == nil; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+}
+
+// acquire takes one of the slots every batch and review shares, or gives up once ctx is done.
+// The caller frees the slot by receiving from br.slots.
+func (br *batchRunner) acquire(ctx context.Context) bool {
+	select {
+	case br.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (br *batchRunner) analyse(ctx context.Context, index int, item batchItem, req batchRequest, opts []game_state.SearchOption) batchResult {
+	res := batchResult{Index: index, FEN: item.fen, Source: item.source, Depth: req.Depth}
+
+	if status := game_state.GameStatus(item.board, 0); status.State == game_state.Checkmate || status.State == game_state.Stalemate {
+		res.Error = "no legal moves: " + status.State
+		return res
+	}
+
+	key := newResultKey(item.board, item.board.WhiteTurn, req.Profile)
+	value, ok := br.results.lookup(key, req.Depth)
+	if ok {
+		res.Cached = true
+	} else {
+		searchCtx, cancel := context.WithTimeout(ctx, req.searchTimeout)
+		defer cancel()
+		opts = append(slices.Clip(opts), game_state.WithContext(searchCtx), game_state.WithWorkers(br.searchWorkers))
+		move, score := game_state.BestMove(item.board, req.Depth, game_state.SideToMove(item.board), opts...)
+		if searchCtx.Err() != nil {
+			res.Error = searchErr(searchCtx).Error()
+			return res
+		}
+		value = computed.CacheValue{BestMove: move, Depth: req.Depth, Score: score}
+		br.results.save(key, value)
+	}
+
+	res.BestMove = game_state.MoveToUCI(item.board, value.BestMove)
+	res.SAN = game_state.MoveToSAN(item.board, value.BestMove)
+	res.Score = value.Score
+	return res
+}
+
+var errClientBatches = fmt.Errorf("too many batches in progress for this key, at most %d", maxClientBatches)
+
+// startAsync runs a batch in the background and calls release when it is over, or straight
+// away when the batch is refused.
+func (br *batchRunner) startAsync(items []batchItem, req batchRequest, opts []game_state.SearchOption, owner *access.Client, release func()) (*asyncBatch, error) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	// forget finished batches nobody has collected in time
+	running, mine := 0, 0
+	var finished []*asyncBatch
+	for id, b := range br.batches {
+		switch {
+		case !b.Done:
+			running++
+			if owner != nil && b.owner == owner.ID() {
+				mine++
+			}
+		case time.Since(b.finished) > asyncBatchTTL:
+			delete(br.batches, id)
+		default:
+			finished = append(finished, b)
+		}
+	}
+	if running >= maxAsyncBatches {
+		release()
+		return nil, errors.New("too many batches in progress, try again later")
+	}
+	if mine >= maxClientBatches {
+		release()
+		return nil, errClientBatches
+	}
+	// keep at most maxAsyncBatches finished ones too, dropping the oldest first
+	if excess := len(finished) - maxAsyncBatches + 1; excess > 0 {
+		slices.SortFunc(finished, func(a, b *asyncBatch) int {
+			return a.finished.Compare(b.finished)
+		})
+		for _, b := range finished[:excess] {
+			delete(br.batches, b.ID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(br.shutdown)
+	b := &asyncBatch{ID: games.NewID(), Total: len(items), Results: []batchResult{}, cancel: cancel, owner: owner.ID()}
+	br.batches[b.ID] = b
+
+	go func() {
+		defer release()
+		defer cancel()
+		br.run(ctx, items, req, opts, func(res batchResult) {
+			br.mu.Lock()
+			b.Results = append(b.Results, res)
+			b.Completed++
+			br.mu.Unlock()
+		})
+		br.mu.Lock()
+		b.Done = true
+		b.finished = time.Now()
+		br.mu.Unlock()
+	}()
+	return b, nil
+}
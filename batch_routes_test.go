@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/access"
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+func TestBatchItems(t *testing.T) {
+	const game = `[Event "one"]
+
+1. e4 e5 2. Nf3 *
+`
+	tooMany := slices.Repeat([]string{"4k3/8/8/8/8/8/8/4K3 w"}, maxBatchPositions+1)
+	tests := []struct {
+		name    string
+		req     batchRequest
+		fens    []string
+		sources []string
+		wantErr string
+	}{
+		{
+			name: "fens",
+			req:  batchRequest{FENs: []string{" 4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 ", "4k3/8/8/8/8/8/8/4K3 b"}},
+			fens: []string{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", "4k3/8/8/8/8/8/8/4K3 b"},
+		},
+		{
+			name:    "pgn",
+			req:     batchRequest{PGN: game},
+			fens:    []string{"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b - - 0 1", "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w - - 0 2"},
+			sources: []string{"game 1, move 1. e4", "game 1, move 1... e5", "game 1, move 2. Nf3"},
+		},
+		{
+			name:    "fens then pgn",
+			req:     batchRequest{FENs: []string{"4k3/8/8/8/8/8/8/4K3 w"}, PGN: "1. d4 *"},
+			fens:    []string{"4k3/8/8/8/8/8/8/4K3 w", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"},
+			sources: []string{"", "game 1, move 1. d4"},
+		},
+		{name: "empty", req: batchRequest{}, wantErr: "expected fens or pgn"},
+		{name: "bad fen", req: batchRequest{FENs: []string{"8/8/8 w"}}, wantErr: "expected 8 ranks"},
+		{name: "illegal move", req: batchRequest{PGN: "1. e4 *\n\n1. e5 *\n"}, wantErr: "game 2"},
+		{name: "too many", req: batchRequest{FENs: tooMany}, wantErr: "at most 5000 positions per batch, got 5001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := batchItems(tt.req)
+			if tt.fens == nil {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("batchItems = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) != len(tt.fens) {
+				t.Fatalf("%d items, want %d", len(items), len(tt.fens))
+			}
+			for i, item := range items {
+				if item.fen != tt.fens[i] {
+					t.Errorf("item %d fen = %q, want %q", i, item.fen, tt.fens[i])
+				}
+				if tt.sources != nil && item.source != tt.sources[i] {
+					t.Errorf("item %d source = %q, want %q", i, item.source, tt.sources[i])
+				}
+			}
+		})
+	}
+}
+
+// newBatchServer serves the batch routes, behind API keys when keysFile is not empty.
+func newBatchServer(t *testing.T, keysFile string, shutdown context.Context) (*gin.Engine, *batchRunner) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rc := resultCache{defaultDepth: 2, evalID: func(string) string { return "batch-test" }}
+	optsFor := func(string) ([]game_state.SearchOption, error) { return nil, nil }
+	cfg := Config{BatchConcurrency: 2, JobSearchWorkers: 1, MaxDepth: 8, SearchTimeoutSeconds: 30, MaxMoveTimeMS: 30000}
+	br := newBatchRunner(rc, optsFor, cfg, shutdown)
+	r := gin.New()
+	if keysFile != "" {
+		keys, err := access.Load(keysFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Use(authenticate(keys, newServerMetrics(jobs.New(jobs.Options{}))))
+	}
+	br.register(r)
+	return r, br
+}
+
+func sendJSON(r *gin.Engine, method, url, key string, body any) *httptest.ResponseRecorder {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, url, bytes.NewReader(data))
+	if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(streamRecorder{w}, req)
+	return w
+}
+
+// streamRecorder lets gin's Context.Stream, which watches for the client going away, write
+// to a ResponseRecorder.
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (streamRecorder) CloseNotify() <-chan bool {
+	return make(chan bool)
+}
+
+func TestBatchStream(t *testing.T) {
+	r, _ := newBatchServer(t, "", context.Background())
+	req := gin.H{"fens": []string{
+		"6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1",
+		"R5k1/5ppp/8/8/8/8/8/6K1 b - - 1 1",
+		"7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+	}}
+
+	for _, cached := range []bool{false, true} {
+		w := sendJSON(r, "POST", "/analyze/batch", "", req)
+		if w.Code != 200 || w.Header().Get("Content-Type") != "application/x-ndjson" {
+			t.Fatalf("status %d, %s: %s", w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+		lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+		if len(lines) != 4 {
+			t.Fatalf("%d lines, want 3 results and done:\n%s", len(lines), w.Body)
+		}
+		byIndex := map[int]batchResult{}
+		for _, line := range lines[:3] {
+			var res batchResult
+			if err := json.Unmarshal([]byte(line), &res); err != nil {
+				t.Fatal(err)
+			}
+			byIndex[res.Index] = res
+		}
+		if res := byIndex[0]; res.BestMove != "a1a8" || res.SAN != "Ra8#" || res.Depth != 2 || res.Cached != cached {
+			t.Errorf("mate in one = %+v, cached %v", res, cached)
+		}
+		if res := byIndex[1]; res.Error != "no legal moves: checkmate" || res.BestMove != "" {
+			t.Errorf("checkmated = %+v", res)
+		}
+		if res := byIndex[2]; res.Error != "no legal moves: stalemate" {
+			t.Errorf("stalemated = %+v", res)
+		}
+		if lines[3] != `{"completed":3,"done":true,"total":3}` {
+			t.Errorf("done line = %s", lines[3])
+		}
+	}
+
+	tests := []struct {
+		name string
+		body gin.H
+		want string
+	}{
+		{"no positions", gin.H{}, "expected fens or pgn"},
+		{"too deep", gin.H{"fens": []string{"4k3/8/8/8/8/8/8/4K3 w"}, "depth": maxBatchDepth + 1}, "depth must be between 1 and 6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := sendJSON(r, "POST", "/analyze/batch", "", tt.body)
+			if w.Code != 400 || !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("status %d: %s, want 400 with %q", w.Code, w.Body, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsyncBatchOwnership(t *testing.T) {
+	keys := writeKeysFile(t, `{"keys": [{"name": "a", "key": "secret-a"}, {"name": "b", "key": "secret-b"}]}`)
+	r, _ := newBatchServer(t, keys, context.Background())
+
+	w := sendJSON(r, "POST", "/analyze/batch", "secret-a", gin.H{"fens": []string{"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"}, "async": true})
+	if w.Code != 202 {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var started struct {
+		ID    string `json:"id"`
+		Total int    `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &started); err != nil || started.ID == "" || started.Total != 1 {
+		t.Fatalf("started %+v, %v", started, err)
+	}
+
+	var b asyncBatch
+	for deadline := time.Now().Add(10 * time.Second); !b.Done; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the batch did not finish")
+		}
+		w := sendJSON(r, "GET", "/analyze/batch/"+started.ID, "secret-a", nil)
+		if w.Code != 200 {
+			t.Fatalf("owner GET: %d %s", w.Code, w.Body)
+		}
+		json.Unmarshal(w.Body.Bytes(), &b)
+	}
+	if b.Completed != 1 || len(b.Results) != 1 || b.Results[0].BestMove == "" {
+		t.Errorf("finished batch = %+v", b)
+	}
+
+	for _, method := range []string{"GET", "DELETE"} {
+		if w := sendJSON(r, method, "/analyze/batch/"+started.ID, "secret-b", nil); w.Code != 404 {
+			t.Errorf("another key's %s = %d, want 404", method, w.Code)
+		}
+	}
+	if w := sendJSON(r, "GET", "/analyze/batch/missing", "secret-a", nil); w.Code != 404 {
+		t.Errorf("GET of an unknown batch = %d", w.Code)
+	}
+	if w := sendJSON(r, "DELETE", "/analyze/batch/"+started.ID, "secret-a", nil); w.Code != 204 {
+		t.Errorf("owner DELETE = %d", w.Code)
+	}
+}
+
+func TestStartAsyncLimits(t *testing.T) {
+	keys, err := access.Load(writeKeysFile(t, `{"keys": [{"name": "a", "key": "secret-a"}, {"name": "b", "key": "secret-b"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := keys.Authenticate("secret-a", "")
+	b, _ := keys.Authenticate("secret-b", "")
+
+	shutdown, stop := context.WithCancel(context.Background())
+	defer stop()
+	_, br := newBatchServer(t, "", shutdown)
+	// with every slot taken the batches stay running
+	for range cap(br.slots) {
+		br.slots <- struct{}{}
+	}
+	items, err := batchItems(batchRequest{FENs: []string{"4k3/8/8/8/8/8/4P3/4K3 w"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	released := 0
+	release := func() { released++ }
+	req := batchRequest{Depth: 1, searchTimeout: time.Minute}
+
+	for range maxClientBatches {
+		if _, err := br.startAsync(items, req, nil, a, func() {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := br.startAsync(items, req, nil, a, release); err != errClientBatches || released != 1 {
+		t.Errorf("batch over the key's limit: %v, released %d times", err, released)
+	}
+	if _, err := br.startAsync(items, req, nil, b, func() {}); err != nil {
+		t.Errorf("another key was refused: %v", err)
+	}
+
+	// finished batches past the TTL are dropped, and the oldest beyond maxAsyncBatches
+	br.mu.Lock()
+	now := time.Now()
+	br.batches["expired"] = &asyncBatch{ID: "expired", Done: true, finished: now.Add(-asyncBatchTTL - time.Minute)}
+	for i := range maxAsyncBatches {
+		id := "finished-" + string(rune('a'+i/26)) + string(rune('a'+i%26))
+		br.batches[id] = &asyncBatch{ID: id, Done: true, finished: now.Add(-time.Duration(maxAsyncBatches-i) * time.Second)}
+	}
+	br.mu.Unlock()
+	if _, err := br.startAsync(items, req, nil, b, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	br.mu.Lock()
+	_, expired := br.batches["expired"]
+	_, oldest := br.batches["finished-aa"]
+	_, next := br.batches["finished-ab"]
+	br.mu.Unlock()
+	if expired || oldest || !next {
+		t.Errorf("kept expired %v, oldest %v, next %v; want only the next", expired, oldest, next)
+	}
+}
+
+func TestForEachBoundsGoroutines(t *testing.T) {
+	_, br := newBatchServer(t, "", context.Background())
+	var mu sync.Mutex
+	active, peak := 0, 0
+	seen := make([]bool, 50)
+	br.forEach(context.Background(), len(seen), func(i int) {
+		mu.Lock()
+		active++
+		peak = max(peak, active)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		active--
+		seen[i] = true
+		mu.Unlock()
+	})
+	if peak > cap(br.slots) {
+		t.Errorf("%d calls ran at once, want at most %d", peak, cap(br.slots))
+	}
+	if i := slices.Index(seen, false); i >= 0 {
+		t.Errorf("index %d was skipped", i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	br.forEach(ctx, 10, func(int) { t.Error("called after ctx was done") })
+}
@@ -0,0 +1,206 @@
+// Package book reads and writes Polyglot opening books (.bin) so the engine can play sound,
+// varied openings without searching. A book is a file of 16-byte big-endian entries sorted
+// by position key: the key (see Key), a move, its weight and a learning value.
+//
+// The engine has no castling and always promotes to a queen, so book moves that castle or
+// under-promote are never played.
+package book
+
+import (
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+// EntrySize is the size of an entry in a book file.
+const EntrySize = 16
+
+type Entry struct {
+	Key    uint64
+	Move   uint16 // see DecodeMove
+	Weight uint16
+	Learn  uint32
+}
+
+// Selection is how Pick chooses among the book moves of a position.
+type Selection string
+
+const (
+	Weighted Selection = "weighted" // at random, in proportion to the weights
+	Best     Selection = "best"     // the highest weight, the first in the file on ties
+)
+
+type Options struct {
+	Depth     int       // plies from the start of the game the book is used for; no limit when 0
+	Selection Selection // Weighted when empty
+	Rand      *rand.Rand
+}
+
+// Book is an opening book loaded into memory. It is safe for concurrent use.
+type Book struct {
+	entries []Entry
+	opts    Options
+
+	mu sync.Mutex // guards opts.Rand
+}
+
+// Candidate is a playable book move.
+type Candidate struct {
+	Move   game_state.Move
+	Weight uint16
+}
+
+// Open reads a book file.
+func Open(path string, opts Options) (*Book, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%EntrySize != 0 {
+		return nil, fmt.Errorf("%s: not a Polyglot book: size is not a multiple of %d", path, EntrySize)
+	}
+	entries := make([]Entry, len(data)/EntrySize)
+	for i := range entries {
+		b := data[i*EntrySize:]
+		entries[i] = Entry{
+			Key:    binary.BigEndian.Uint64(b),
+			Move:   binary.BigEndian.Uint16(b[8:]),
+			Weight: binary.BigEndian.Uint16(b[10:]),
+			Learn:  binary.BigEndian.Uint32(b[12:]),
+		}
+	}
+	return New(entries, opts)
+}
+
+// Write writes entries in the book file format, sorted by key. The entries of a position
+// keep their order, which Best uses to break ties.
+func Write(w io.Writer, entries []Entry) error {
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b Entry) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	buf := make([]byte, 0, len(sorted)*EntrySize)
+	for _, e := range sorted {
+		buf = binary.BigEndian.AppendUint64(buf, e.Key)
+		buf = binary.BigEndian.AppendUint16(buf, e.Move)
+		buf = binary.BigEndian.AppendUint16(buf, e.Weight)
+		buf = binary.BigEndian.AppendUint32(buf, e.Learn)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// New builds a book from entries, which need not be sorted.
+func New(entries []Entry, opts Options) (*Book, error) {
+	switch opts.Selection {
+	case "":
+		opts.Selection = Weighted
+	case Weighted, Best:
+	default:
+		return nil, fmt.Errorf("book selection must be %s or %s", Weighted, Best)
+	}
+	if opts.Depth < 0 {
+		return nil, fmt.Errorf("book depth must not be negative")
+	}
+	// stable, so entries of a position keep their order for Best's tie-break
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return &Book{entries: entries, opts: opts}, nil
+}
+
+// Len is the number of entries.
+func (b *Book) Len() int {
+	return len(b.entries)
+}
+
+// Entries returns the entries for a position key, in file order.
+func (b *Book) Entries(key uint64) []Entry {
+	i, _ := slices.BinarySearchFunc(b.entries, key, func(e Entry, k uint64) int {
+		return cmp.Compare(e.Key, k)
+	})
+	j := i
+	for j < len(b.entries) && b.entries[j].Key == key {
+		j++
+	}
+	return b.entries[i:j]
+}
+
+// Candidates returns the book moves the engine can play in board, whose key is key, by
+// descending weight. Moves with zero weight are left out, as Polyglot does.
+func (b *Book) Candidates(board game_state.Board, key uint64) []Candidate {
+	legal := game_state.LegalMoves(board)
+	var out []Candidate
+	for _, e := range b.Entries(key) {
+		if e.Weight == 0 {
+			continue
+		}
+		m, promotion := DecodeMove(e.Move)
+		if promotion != 0 && promotion != PromoteQueen {
+			continue
+		}
+		// castling, as king takes rook, is never among the engine's legal moves
+		if !slices.Contains(legal, m) {
+			continue
+		}
+		out = append(out, Candidate{Move: m, Weight: e.Weight})
+	}
+	slices.SortStableFunc(out, func(a, c Candidate) int {
+		return int(c.Weight) - int(a.Weight)
+	})
+	return out
+}
+
+// Pick chooses a book move for board, whose key is key, ply halfmoves into the game (0 at
+// the standard start). It reports false past the book depth or when the book has no
+// playable move. A nil book has no moves, so callers need not check for one.
+func (b *Book) Pick(board game_state.Board, key uint64, ply int) (game_state.Move, bool) {
+	if b == nil || b.opts.Depth > 0 && ply >= b.opts.Depth {
+		return game_state.Move{}, false
+	}
+	cands := b.Candidates(board, key)
+	if len(cands) == 0 {
+		return game_state.Move{}, false
+	}
+	if b.opts.Selection == Best {
+		return cands[0].Move, true
+	}
+
+	total := 0
+	for _, c := range cands {
+		total += int(c.Weight)
+	}
+	r := b.intN(total)
+	for _, c := range cands {
+		if r -= int(c.Weight); r < 0 {
+			return c.Move, true
+		}
+	}
+	return cands[len(cands)-1].Move, true // unreachable
+}
+
+func (b *Book) intN(n int) int {
+	if b.opts.Rand == nil {
+		return rand.IntN(n)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.opts.Rand.IntN(n)
+}
+
+// Ply is the number of halfmoves from the standard start to a position with the given
+// fullmove number and side to move, for Pick.
+func Ply(fullmove int, color game_state.PieceColor) int {
+	ply := 2 * (max(fullmove, 1) - 1)
+	if color == game_state.BlackColor {
+		ply++
+	}
+	return ply
+}
@@ -0,0 +1,165 @@
+package book
+
+import (
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"
+
+func uciMove(t *testing.T, s string) game_state.Move {
+	t.Helper()
+	m, err := game_state.ParseUCIMove(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// testBook writes entries to a file and opens it, so the tests go through the file format.
+func testBook(t *testing.T, entries []Entry, opts Options) *Book {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "book.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(f, entries); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open(path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestCandidates(t *testing.T) {
+	board, err := game_state.ParseFEN(startFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := KeyFEN(startFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := testBook(t, []Entry{
+		{Key: key + 1, Move: EncodeMove(uciMove(t, "a2a3"), 0), Weight: 50}, // another position
+		{Key: key, Move: EncodeMove(uciMove(t, "d2d4"), 0), Weight: 10},
+		{Key: key, Move: EncodeMove(uciMove(t, "e2e4"), 0), Weight: 30},
+		{Key: key, Move: EncodeMove(uciMove(t, "g1f3"), 0), Weight: 0},  // never played
+		{Key: key, Move: EncodeMove(uciMove(t, "e2e5"), 0), Weight: 40}, // illegal
+		{Key: key, Move: EncodeMove(uciMove(t, "c2c4"), 0), Weight: 10},
+	}, Options{})
+
+	var got []string
+	for _, c := range b.Candidates(board, key) {
+		got = append(got, game_state.MoveToUCI(board, c.Move))
+	}
+	// by descending weight, ties in file order
+	if want := []string{"e2e4", "d2d4", "c2c4"}; !slices.Equal(got, want) {
+		t.Errorf("Candidates = %v, want %v", got, want)
+	}
+	if n := b.Len(); n != 6 {
+		t.Errorf("Len = %d, want 6", n)
+	}
+}
+
+func TestPick(t *testing.T) {
+	board, err := game_state.ParseFEN(startFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := KeyFEN(startFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []Entry{
+		{Key: key, Move: EncodeMove(uciMove(t, "d2d4"), 0), Weight: 1},
+		{Key: key, Move: EncodeMove(uciMove(t, "e2e4"), 0), Weight: 3},
+	}
+
+	tests := []struct {
+		name   string
+		opts   Options
+		ply    int
+		want   string
+		wantOK bool
+	}{
+		{"best", Options{Selection: Best}, 0, "e2e4", true},
+		{"within depth", Options{Selection: Best, Depth: 1}, 0, "e2e4", true},
+		{"past depth", Options{Selection: Best, Depth: 1}, 1, "", false},
+		{"weighted", Options{Rand: rand.New(rand.NewPCG(1, 2))}, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, ok := testBook(t, entries, tt.opts).Pick(board, key, tt.ply)
+			if ok != tt.wantOK {
+				t.Fatalf("Pick ok = %v, want %v", ok, tt.wantOK)
+			}
+			if got := game_state.MoveToUCI(board, m); ok && tt.want != "" && got != tt.want {
+				t.Errorf("Pick = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	// weighted picks follow the weights: e2e4 three times as often as d2d4
+	b := testBook(t, entries, Options{Rand: rand.New(rand.NewPCG(1, 2))})
+	e4 := 0
+	for range 2000 {
+		if m, _ := b.Pick(board, key, 0); game_state.MoveToUCI(board, m) == "e2e4" {
+			e4++
+		}
+	}
+	if e4 < 1400 || e4 > 1600 {
+		t.Errorf("e2e4 picked %d times out of 2000, want about 1500", e4)
+	}
+
+	var none *Book
+	if _, ok := none.Pick(board, key, 0); ok {
+		t.Error("a nil book picked a move")
+	}
+}
+
+func TestNewRejectsBadOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"selection", Options{Selection: "random"}},
+		{"depth", Options{Depth: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(nil, tt.opts); err == nil {
+				t.Error("New succeeded")
+			}
+		})
+	}
+}
+
+func TestPly(t *testing.T) {
+	tests := []struct {
+		fullmove int
+		color    game_state.PieceColor
+		want     int
+	}{
+		{1, game_state.WhiteColor, 0},
+		{1, game_state.BlackColor, 1},
+		{10, game_state.WhiteColor, 18},
+		{0, game_state.BlackColor, 1}, // missing counters count as the first move
+	}
+	for _, tt := range tests {
+		if got := Ply(tt.fullmove, tt.color); got != tt.want {
+			t.Errorf("Ply(%d, %v) = %d, want %d", tt.fullmove, tt.color, got, tt.want)
+		}
+	}
+}
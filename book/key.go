@@ -0,0 +1,141 @@
+package book
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+// Offsets into random64 after the 768 piece keys
+const (
+	castlingOffset  = 768 // white short, white long, black short, black long
+	enPassantOffset = 772 // a-file to h-file
+	turnOffset      = 780
+)
+
+// polyglotKind is the Polyglot piece index: black pawn 0, white pawn 1, black knight 2, and
+// so on up to white king 11.
+var polyglotKind = map[game_state.PieceType]int{
+	game_state.Pawn:   0,
+	game_state.Knight: 1,
+	game_state.Bishop: 2,
+	game_state.Rook:   3,
+	game_state.Queen:  4,
+	game_state.King:   5,
+}
+
+// Key is the Polyglot key of board. The board does not track castling rights or en passant,
+// so a side is taken to have each right whose king and rook stand on their starting
+// squares, and the en passant file comes from lastMove, the move that reached the
+// position, when it was a double pawn push. lastMove may be nil.
+func Key(board game_state.Board, lastMove *game_state.Move) uint64 {
+	epFile := 0
+	if m := lastMove; m != nil {
+		p := board.PiecesMatrix[m.To.Line][m.To.Column]
+		if p.Type == game_state.Pawn && m.From.Column == m.To.Column && abs(m.To.Line-m.From.Line) == 2 {
+			epFile = int(m.To.Column)
+		}
+	}
+	return key(board, inferCastling(board), epFile)
+}
+
+// KeyFEN is the Polyglot key of a FEN, with the castling rights and en passant square of
+// its third and fourth fields. Rights are inferred as in Key when the FEN has no third field.
+func KeyFEN(fen string) (uint64, error) {
+	board, err := game_state.ParseFEN(fen)
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(fen)
+
+	rights := inferCastling(board)
+	if len(fields) > 2 {
+		rights = [4]bool{}
+		if fields[2] != "-" {
+			for _, ch := range fields[2] {
+				i := strings.IndexRune("KQkq", ch)
+				if i < 0 {
+					return 0, fmt.Errorf("invalid FEN %q: unexpected castling right %q", fen, ch)
+				}
+				rights[i] = true
+			}
+		}
+	}
+
+	epFile := 0
+	if len(fields) > 3 && fields[3] != "-" {
+		sq, err := game_state.ParseSquare(fields[3])
+		if err != nil {
+			return 0, fmt.Errorf("invalid FEN %q: %w", fen, err)
+		}
+		epFile = int(sq.Column)
+	}
+	return key(board, rights, epFile), nil
+}
+
+// key hashes the placement, rights, en passant file (1-8, or 0 for none) and side to move.
+// Polyglot only counts the en passant file when a pawn of the side to move could capture.
+func key(board game_state.Board, castling [4]bool, epFile int) uint64 {
+	var k uint64
+	for _, p := range board.PiecesSlice {
+		kind := 2 * polyglotKind[p.Type]
+		if p.Color == game_state.WhiteColor {
+			kind++
+		}
+		k ^= random64[64*kind+8*int(p.Pos.Line-1)+int(p.Pos.Column-1)]
+	}
+	for i, ok := range castling {
+		if ok {
+			k ^= random64[castlingOffset+i]
+		}
+	}
+	if epFile != 0 && canCaptureEnPassant(board, epFile) {
+		k ^= random64[enPassantOffset+epFile-1]
+	}
+	if board.WhiteTurn {
+		k ^= random64[turnOffset]
+	}
+	return k
+}
+
+func canCaptureEnPassant(board game_state.Board, file int) bool {
+	side := game_state.SideToMove(board)
+	line := int8(5) // where a white pawn captures a black pawn that just advanced two squares
+	if side == game_state.BlackColor {
+		line = 4
+	}
+	for _, f := range []int{file - 1, file + 1} {
+		if f < 1 || f > 8 {
+			continue
+		}
+		p := board.PiecesMatrix[line][f]
+		if p.Type == game_state.Pawn && p.Color == side {
+			return true
+		}
+	}
+	return false
+}
+
+// inferCastling grants each right whose king and rook are on their starting squares.
+func inferCastling(board game_state.Board) [4]bool {
+	at := func(line, column int8, t game_state.PieceType, c game_state.PieceColor) bool {
+		p := board.PiecesMatrix[line][column]
+		return p.Type == t && p.Color == c
+	}
+	white := at(1, 5, game_state.King, game_state.WhiteColor)
+	black := at(8, 5, game_state.King, game_state.BlackColor)
+	return [4]bool{
+		white && at(1, 8, game_state.Rook, game_state.WhiteColor),
+		white && at(1, 1, game_state.Rook, game_state.WhiteColor),
+		black && at(8, 8, game_state.Rook, game_state.BlackColor),
+		black && at(8, 1, game_state.Rook, game_state.BlackColor),
+	}
+}
+
+func abs(x int8) int8 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,81 @@
+package book
+
+import (
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+// The reference keys from the Polyglot book format documentation.
+var polyglotVectors = []struct {
+	name  string
+	fen   string
+	moves []string // from the standard start, for Key
+	want  uint64
+}{
+	{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1", nil, 0x463b96181691fc9c},
+	{"e4", "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b KQkq e3 0 1", []string{"e2e4"}, 0x823c9b50fd114196},
+	{"e4 d5", "rnbqkbnr/ppp1pppp/8/3p4/4P3/8/PPPP1PPP/RNBQKBNR w KQkq d6 0 2", []string{"e2e4", "d7d5"}, 0x0756b94461c50fb0},
+	{"e4 d5 e5", "rnbqkbnr/ppp1pppp/8/3pP3/8/8/PPPP1PPP/RNBQKBNR b KQkq - 0 2", []string{"e2e4", "d7d5", "e4e5"}, 0x662fafb965db29d4},
+	{"e4 d5 e5 f5", "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPP1PPP/RNBQKBNR w KQkq f6 0 3", []string{"e2e4", "d7d5", "e4e5", "f7f5"}, 0x22a48b5a8e47ff78},
+	{"e4 d5 e5 f5 Ke2", "rnbqkbnr/ppp1p1pp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR b kq - 0 3", []string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2"}, 0x652a607ca3f242c1},
+	{"e4 d5 e5 f5 Ke2 Kf7", "rnbq1bnr/ppp1pkpp/8/3pPp2/8/8/PPPPKPPP/RNBQ1BNR w - - 0 4", []string{"e2e4", "d7d5", "e4e5", "f7f5", "e1e2", "e8f7"}, 0x00fdd303c946bdd9},
+	{"a4 b5 h4 b4 c4", "rnbqkbnr/p1pppppp/8/8/PpP4P/8/1P1PPPP1/RNBQKBNR b KQkq c3 0 3", []string{"a2a4", "b7b5", "h2h4", "b5b4", "c2c4"}, 0x3c8123ea7b067637},
+}
+
+func TestKeyFEN(t *testing.T) {
+	for _, tt := range polyglotVectors {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := KeyFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("KeyFEN = %016x, want %016x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyAfterMoves(t *testing.T) {
+	for _, tt := range polyglotVectors {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := game_state.ParseFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var last *game_state.Move
+			for _, s := range tt.moves {
+				m, err := game_state.ParseUCIMove(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				board = game_state.BoardAfterMove(m, board)
+				last = &m
+			}
+			// Key infers castling rights from the pieces, which only differs from the
+			// FEN once a king has moved and come back; none of these do
+			if got := Key(board, last); got != tt.want {
+				t.Errorf("Key = %016x, want %016x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyFENRejectsBadFields(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+	}{
+		{"castling", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQxq - 0 1"},
+		{"en passant", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq z9 0 1"},
+		{"placement", "rnbqkbnr/pppppppp/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := KeyFEN(tt.fen); err == nil {
+				t.Errorf("KeyFEN(%q) succeeded", tt.fen)
+			}
+		})
+	}
+}
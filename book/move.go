@@ -0,0 +1,30 @@
+package book
+
+import "github.com/g0g05arui/chess-engine/game_state"
+
+// Promotion pieces of a Polyglot move
+const (
+	PromoteKnight = 1
+	PromoteBishop = 2
+	PromoteRook   = 3
+	PromoteQueen  = 4
+)
+
+// DecodeMove unpacks a Polyglot move: the to file and rank in bits 0-5, the from file and
+// rank in bits 6-11 and the promotion piece in bits 12-14. Castling is encoded as the king
+// capturing its own rook, e.g. e1h1.
+func DecodeMove(m uint16) (move game_state.Move, promotion int) {
+	square := func(bits uint16) game_state.Position {
+		return game_state.Position{Line: int8(bits>>3&7) + 1, Column: int8(bits&7) + 1}
+	}
+	return game_state.Move{From: square(m >> 6), To: square(m)}, int(m >> 12 & 7)
+}
+
+// EncodeMove packs a move the other way round from DecodeMove. promotion is 0 for moves
+// that do not promote.
+func EncodeMove(m game_state.Move, promotion int) uint16 {
+	square := func(p game_state.Position) uint16 {
+		return uint16(p.Line-1)<<3 | uint16(p.Column-1)
+	}
+	return uint16(promotion&7)<<12 | square(m.From)<<6 | square(m.To)
+}
@@ -0,0 +1,41 @@
+package book
+
+import (
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+func TestMoveEncoding(t *testing.T) {
+	sq := func(s string) game_state.Position {
+		p, err := game_state.ParseSquare(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	tests := []struct {
+		name      string
+		from, to  string
+		promotion int
+		code      uint16
+	}{
+		{"e2e4", "e2", "e4", 0, 12<<6 | 28},
+		{"g1f3", "g1", "f3", 0, 6<<6 | 21},
+		{"castling as king takes rook", "e1", "h1", 0, 4<<6 | 7},
+		{"queen promotion", "a7", "a8", PromoteQueen, PromoteQueen<<12 | 48<<6 | 56},
+		{"knight promotion", "h2", "h1", PromoteKnight, PromoteKnight<<12 | 15<<6 | 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := game_state.Move{From: sq(tt.from), To: sq(tt.to)}
+			if got := EncodeMove(m, tt.promotion); got != tt.code {
+				t.Errorf("EncodeMove = %#04x, want %#04x", got, tt.code)
+			}
+			move, promotion := DecodeMove(tt.code)
+			if move != m || promotion != tt.promotion {
+				t.Errorf("DecodeMove = %v, %d, want %v, %d", move, promotion, m, tt.promotion)
+			}
+		})
+	}
+}
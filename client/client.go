@@ -0,0 +1,446 @@
+// Package client is a typed Go client for the engine's HTTP API. It follows the OpenAPI
+// document in the api package, which the server also serves at /openapi.json; every
+// operation there has a method here.
+//
+//	c := client.New("http://localhost:8080", client.WithAPIKey(key))
+//	res, err := c.BestMove(ctx, client.BestMoveRequest{FEN: fen})
+//	fmt.Println(res.BestMove.UCI(), res.Score)
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Error is a response with a non-2xx status.
+type Error struct {
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration // from the Retry-After header of a 429 or 503
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("chess engine: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("chess engine: %d %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+type Option func(*Client)
+
+// WithAPIKey sends key in the X-API-Key header.
+func WithAPIKey(key string) Option {
+	return func(c *Client) { c.apiKey = key }
+}
+
+// WithHTTPClient replaces http.DefaultClient. Streams run as long as the search, so its
+// Timeout should leave room for them; prefer deadlines on the context.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// New returns a client for the server at baseURL, e.g. http://localhost:8080.
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{baseURL: strings.TrimRight(baseURL, "/"), http: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type BestMoveRequest struct {
+	FEN     string
+	Turn    string // white (default) or black
+	Profile string
+	NoBook  bool // search even when the server's opening book has a move
+}
+
+// BestMove answers with a book move, or else from the deepest cached result, searching to
+// the server's default depth when there is none.
+func (c *Client) BestMove(ctx context.Context, req BestMoveRequest) (BestMove, error) {
+	var res BestMove
+	q := url.Values{"fen": {req.FEN}}
+	setNonEmpty(q, "turn", req.Turn)
+	setNonEmpty(q, "profile", req.Profile)
+	if req.NoBook {
+		q.Set("book", "false")
+	}
+	err := c.do(ctx, "GET", "/best-move", q, nil, &res)
+	return res, err
+}
+
+type StreamRequest struct {
+	FEN        string
+	Turn       string
+	Depth      int // deepest iteration; the server's default when zero
+	MoveTimeMS int
+	Profile    string
+}
+
+// BestMoveStream deepens a search, calling fn with each completed iteration until the
+// requested depth or time is reached. An error from fn stops the stream and is returned.
+func (c *Client) BestMoveStream(ctx context.Context, req StreamRequest, fn func(SearchResult) error) (StreamDone, error) {
+	var done StreamDone
+	q := url.Values{"fen": {req.FEN}}
+	setNonEmpty(q, "turn", req.Turn)
+	setNonEmpty(q, "profile", req.Profile)
+	setPositive(q, "depth", req.Depth)
+	setPositive(q, "movetime", req.MoveTimeMS)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resp, err := c.send(ctx, "GET", "/best-move/stream", q, nil)
+	if err != nil {
+		return done, err
+	}
+	defer resp.Body.Close()
+
+	var event string
+	sc := bufio.NewScanner(resp.Body)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data := []byte(strings.TrimPrefix(line, "data:"))
+			switch event {
+			case "depth":
+				var res SearchResult
+				if err := json.Unmarshal(data, &res); err != nil {
+					return done, err
+				}
+				if err := fn(res); err != nil {
+					return done, err
+				}
+			case "done":
+				err := json.Unmarshal(data, &done)
+				return done, err
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return done, err
+	}
+	return done, io.ErrUnexpectedEOF
+}
+
+type ImageRequest struct {
+	FEN         string
+	Format      string // png (default) or svg
+	Size        int
+	Orientation string // white (default) or black
+	Coordinates bool
+	LastMove    string   // UCI move to highlight
+	Arrows      []string // UCI moves to draw as arrows
+}
+
+// Image renders the board, returning the image and its content type.
+func (c *Client) Image(ctx context.Context, req ImageRequest) ([]byte, string, error) {
+	q := url.Values{"fen": {req.FEN}}
+	setNonEmpty(q, "format", req.Format)
+	setPositive(q, "size", req.Size)
+	setNonEmpty(q, "orientation", req.Orientation)
+	if req.Coordinates {
+		q.Set("coords", "true")
+	}
+	setNonEmpty(q, "lastmove", req.LastMove)
+	setNonEmpty(q, "arrows", strings.Join(req.Arrows, ","))
+
+	resp, err := c.send(ctx, "GET", "/image", q, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return data, resp.Header.Get("Content-Type"), err
+}
+
+// Position lists the legal moves, check, game status and material of a position.
+func (c *Client) Position(ctx context.Context, fen string) (Position, error) {
+	var res Position
+	err := c.do(ctx, "GET", "/position", url.Values{"fen": {fen}}, nil, &res)
+	return res, err
+}
+
+// Eval breaks the static evaluation of a position down by term. profile may be empty.
+func (c *Client) Eval(ctx context.Context, fen, profile string) (EvalTrace, error) {
+	var res EvalTrace
+	q := url.Values{"fen": {fen}}
+	setNonEmpty(q, "profile", profile)
+	err := c.do(ctx, "GET", "/eval", q, nil, &res)
+	return res, err
+}
+
+// Profile returns the weights of a named evaluation profile, or the active one when name
+// is empty.
+func (c *Client) Profile(ctx context.Context, name string) (Profile, error) {
+	var res Profile
+	q := url.Values{}
+	setNonEmpty(q, "name", name)
+	err := c.do(ctx, "GET", "/profile", q, nil, &res)
+	return res, err
+}
+
+// Jobs lists the queued and running background searches the client started.
+func (c *Client) Jobs(ctx context.Context) ([]Job, error) {
+	var res struct {
+		Jobs []Job `json:"jobs"`
+	}
+	err := c.do(ctx, "GET", "/jobs", nil, nil, &res)
+	return res.Jobs, err
+}
+
+func (c *Client) CancelJob(ctx context.Context, id int64) error {
+	return c.do(ctx, "DELETE", "/jobs/"+strconv.FormatInt(id, 10), nil, nil, nil)
+}
+
+func (c *Client) CreateGame(ctx context.Context, req NewGame) (Game, error) {
+	var res Game
+	err := c.do(ctx, "POST", "/games", nil, req, &res)
+	return res, err
+}
+
+func (c *Client) Game(ctx context.Context, id string) (Game, error) {
+	var res Game
+	err := c.do(ctx, "GET", "/games/"+url.PathEscape(id), nil, nil, &res)
+	return res, err
+}
+
+func (c *Client) DeleteGame(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/games/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// PlayMove plays a move in UCI or SAN. The engine answers when it plays the side now to
+// move, or when reply is set.
+func (c *Client) PlayMove(ctx context.Context, id, move string, reply bool) (GameMoveResult, error) {
+	var res GameMoveResult
+	body := struct {
+		Move  string `json:"move"`
+		Reply bool   `json:"reply,omitempty"`
+	}{move, reply}
+	err := c.do(ctx, "POST", "/games/"+url.PathEscape(id)+"/moves", nil, body, &res)
+	return res, err
+}
+
+// Undo takes back plies moves, or back to the human's turn when plies is zero.
+func (c *Client) Undo(ctx context.Context, id string, plies int) (Game, error) {
+	var res Game
+	q := url.Values{}
+	setPositive(q, "plies", plies)
+	err := c.do(ctx, "POST", "/games/"+url.PathEscape(id)+"/undo", q, nil, &res)
+	return res, err
+}
+
+// Resign ends the game with color losing. color may be empty when the engine plays a side.
+func (c *Client) Resign(ctx context.Context, id, color string) (Game, error) {
+	var res Game
+	body := struct {
+		Color string `json:"color,omitempty"`
+	}{color}
+	err := c.do(ctx, "POST", "/games/"+url.PathEscape(id)+"/resign", nil, body, &res)
+	return res, err
+}
+
+// AnalyzeBatch analyses many positions, calling fn with each result as it finishes, in no
+// particular order. An error from fn stops the batch and is returned.
+func (c *Client) AnalyzeBatch(ctx context.Context, req BatchRequest, fn func(BatchResult) error) (BatchDone, error) {
+	var done BatchDone
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	resp, err := c.send(ctx, "POST", "/analyze/batch", nil, req)
+	if err != nil {
+		return done, err
+	}
+	defer resp.Body.Close()
+
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var line json.RawMessage
+		if err := dec.Decode(&line); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return done, err
+		}
+		if err := json.Unmarshal(line, &done); err != nil {
+			return done, err
+		}
+		if done.Done {
+			return done, nil
+		}
+		var res BatchResult
+		if err := json.Unmarshal(line, &res); err != nil {
+			return done, err
+		}
+		if err := fn(res); err != nil {
+			return done, err
+		}
+	}
+}
+
+// SubmitBatch queues a batch for asynchronous analysis; poll it with Batch.
+func (c *Client) SubmitBatch(ctx context.Context, req BatchRequest) (id string, total int, err error) {
+	body := struct {
+		BatchRequest
+		Async bool `json:"async"`
+	}{req, true}
+	var res struct {
+		ID    string `json:"id"`
+		Total int    `json:"total"`
+	}
+	err = c.do(ctx, "POST", "/analyze/batch", nil, body, &res)
+	return res.ID, res.Total, err
+}
+
+func (c *Client) Batch(ctx context.Context, id string) (Batch, error) {
+	var res Batch
+	err := c.do(ctx, "GET", "/analyze/batch/"+url.PathEscape(id), nil, nil, &res)
+	return res, err
+}
+
+func (c *Client) CancelBatch(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/analyze/batch/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// Review classifies every move of a game and computes each player's accuracy.
+func (c *Client) Review(ctx context.Context, req ReviewRequest) (Review, error) {
+	var res Review
+	err := c.do(ctx, "POST", "/review", nil, req, &res)
+	return res, err
+}
+
+func (c *Client) Healthz(ctx context.Context) (Health, error) {
+	var res Health
+	err := c.do(ctx, "GET", "/healthz", nil, nil, &res)
+	return res, err
+}
+
+// Readyz returns an *Error with status 503 when the server is not ready.
+func (c *Client) Readyz(ctx context.Context) (Health, error) {
+	var res Health
+	err := c.do(ctx, "GET", "/readyz", nil, nil, &res)
+	return res, err
+}
+
+// Metrics returns the server's metrics in the Prometheus text format.
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	resp, err := c.send(ctx, "GET", "/metrics", nil, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+// OpenAPI returns the server's OpenAPI document.
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var res json.RawMessage
+	err := c.do(ctx, "GET", "/openapi.json", nil, nil, &res)
+	return res, err
+}
+
+// do sends a request and decodes the JSON response into out, if not nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send sends a request, turning a non-2xx response into an *Error.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	return nil, responseError(resp)
+}
+
+func responseError(resp *http.Response) error {
+	e := &Error{StatusCode: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(secs) * time.Second
+	}
+	var body struct {
+		Error  string `json:"error"`
+		Status string `json:"status"` // /readyz
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(data, &body) == nil {
+		e.Message = body.Error
+		if e.Message == "" {
+			e.Message = body.Status
+		}
+	}
+	return e
+}
+
+// IsRetryable reports whether err is a rate limit or a temporary unavailability, worth
+// retrying after its RetryAfter.
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && (e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusServiceUnavailable)
+}
+
+func setNonEmpty(q url.Values, key, value string) {
+	if value != "" {
+		q.Set(key, value)
+	}
+}
+
+func setPositive(q url.Values, key string, value int) {
+	if value > 0 {
+		q.Set(key, strconv.Itoa(value))
+	}
+}
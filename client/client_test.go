@@ -0,0 +1,323 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorded is what the test server saw of a request.
+type recorded struct {
+	method, path, query, apiKey, contentType, body string
+}
+
+func newTestServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request)) (*Client, *recorded) {
+	t.Helper()
+	rec := &recorded{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*rec = recorded{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("X-API-Key"), r.Header.Get("Content-Type"), string(body)}
+		handler(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return New(srv.URL+"/", WithAPIKey("secret")), rec
+}
+
+func TestRequests(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		call     func(c *Client) error
+		response string
+		want     recorded
+	}{
+		{
+			name: "best move",
+			call: func(c *Client) error {
+				res, err := c.BestMove(ctx, BestMoveRequest{FEN: "8/8 w", Turn: "black", NoBook: true})
+				if err == nil && res.BestMove.UCI() != "e2e4" {
+					err = fmt.Errorf("best move %s", res.BestMove)
+				}
+				return err
+			},
+			response: `{"best_move":{"From":{"Line":2,"Column":5},"To":{"Line":4,"Column":5}},"depth":4,"score":30}`,
+			want:     recorded{method: "GET", path: "/best-move", query: "book=false&fen=8%2F8+w&turn=black"},
+		},
+		{
+			name: "image",
+			call: func(c *Client) error {
+				_, _, err := c.Image(ctx, ImageRequest{FEN: "x", Format: "svg", Size: 256, Coordinates: true, Arrows: []string{"e2e4", "d2d4"}})
+				return err
+			},
+			want: recorded{method: "GET", path: "/image", query: "arrows=e2e4%2Cd2d4&coords=true&fen=x&format=svg&size=256"},
+		},
+		{
+			name:     "profile without a name",
+			call:     func(c *Client) error { _, err := c.Profile(ctx, ""); return err },
+			response: `{"name":"default","params":{}}`,
+			want:     recorded{method: "GET", path: "/profile"},
+		},
+		{
+			name: "jobs",
+			call: func(c *Client) error {
+				jobs, err := c.Jobs(ctx)
+				if err == nil && (len(jobs) != 1 || jobs[0].ID != 3) {
+					err = fmt.Errorf("jobs %+v", jobs)
+				}
+				return err
+			},
+			response: `{"jobs":[{"id":3,"key":"k","priority":2,"state":"queued","queued_at":"2024-01-01T00:00:00Z"}]}`,
+			want:     recorded{method: "GET", path: "/jobs"},
+		},
+		{
+			name: "cancel job",
+			call: func(c *Client) error { return c.CancelJob(ctx, 42) },
+			want: recorded{method: "DELETE", path: "/jobs/42"},
+		},
+		{
+			name:     "create game",
+			call:     func(c *Client) error { _, err := c.CreateGame(ctx, NewGame{EngineColor: "black"}); return err },
+			response: `{"id":"g1"}`,
+			want:     recorded{method: "POST", path: "/games", contentType: "application/json", body: `{"engine_color":"black"}`},
+		},
+		{
+			name:     "play move",
+			call:     func(c *Client) error { _, err := c.PlayMove(ctx, "a/b", "e4", false); return err },
+			response: `{"move":{"san":"e4"},"game":{"id":"a/b"}}`,
+			want:     recorded{method: "POST", path: "/games/a/b/moves", contentType: "application/json", body: `{"move":"e4"}`},
+		},
+		{
+			name:     "undo",
+			call:     func(c *Client) error { _, err := c.Undo(ctx, "g1", 2); return err },
+			response: `{"id":"g1"}`,
+			want:     recorded{method: "POST", path: "/games/g1/undo", query: "plies=2"},
+		},
+		{
+			name: "submit batch",
+			call: func(c *Client) error {
+				id, total, err := c.SubmitBatch(ctx, BatchRequest{FENs: []string{"x"}, Depth: 3})
+				if err == nil && (id != "b1" || total != 1) {
+					err = fmt.Errorf("batch %s of %d", id, total)
+				}
+				return err
+			},
+			response: `{"id":"b1","total":1}`,
+			want:     recorded{method: "POST", path: "/analyze/batch", contentType: "application/json", body: `{"fens":["x"],"depth":3,"async":true}`},
+		},
+		{
+			name: "review",
+			call: func(c *Client) error {
+				_, err := c.Review(ctx, ReviewRequest{Moves: []string{"e4"}, Depth: 2})
+				return err
+			},
+			response: `{"depth":2,"moves":[]}`,
+			want:     recorded{method: "POST", path: "/review", contentType: "application/json", body: `{"moves":["e4"],"depth":2}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.response == "" {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				io.WriteString(w, tt.response)
+			})
+			if err := tt.call(c); err != nil {
+				t.Fatal(err)
+			}
+			tt.want.apiKey = "secret"
+			if *rec != tt.want {
+				t.Errorf("request = %+v\nwant      %+v", *rec, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       int
+		retryAfter string
+		body       string
+		want       Error
+		retryable  bool
+	}{
+		{"bad request", 400, "", `{"error":"fen is required"}`, Error{StatusCode: 400, Message: "fen is required"}, false},
+		{"rate limited", 429, "3", `{"error":"rate limit exceeded"}`, Error{StatusCode: 429, Message: "rate limit exceeded", RetryAfter: 3 * time.Second}, true},
+		{"not ready", 503, "", `{"status":"shutting down"}`, Error{StatusCode: 503, Message: "shutting down"}, true},
+		{"not JSON", 502, "", `<html>bad gateway</html>`, Error{StatusCode: 502}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.code)
+				io.WriteString(w, tt.body)
+			})
+			_, err := c.Readyz(context.Background())
+			var e *Error
+			if !errors.As(err, &e) || *e != tt.want {
+				t.Fatalf("err = %#v, want %#v", err, tt.want)
+			}
+			if IsRetryable(err) != tt.retryable {
+				t.Errorf("IsRetryable = %v", !tt.retryable)
+			}
+		})
+	}
+	if IsRetryable(io.EOF) {
+		t.Error("a transport error is retryable")
+	}
+	if got := (&Error{StatusCode: 404}).Error(); got != "chess engine: 404 Not Found" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestBestMoveStream(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		stopAt  int // fn fails at this depth when positive
+		depths  []int
+		want    StreamDone
+		wantErr error
+	}{
+		{
+			name: "to depth",
+			body: "event: depth\ndata: {\"depth\":1,\"best_move\":{\"From\":{\"Line\":2,\"Column\":5},\"To\":{\"Line\":4,\"Column\":5}}}\n\n" +
+				": keep-alive\n\n" +
+				"event: depth\ndata: {\"depth\":2}\n\n" +
+				"event: done\ndata: {\"reason\":\"depth\",\"depth\":2}\n\n",
+			depths: []int{1, 2},
+			want:   StreamDone{Reason: "depth", Depth: 2},
+		},
+		{
+			name:    "cut short",
+			body:    "event: depth\ndata: {\"depth\":1}\n\n",
+			depths:  []int{1},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+		{
+			name:    "stopped by fn",
+			body:    "event: depth\ndata: {\"depth\":1}\n\nevent: depth\ndata: {\"depth\":2}\n\nevent: done\ndata: {}\n\n",
+			stopAt:  1,
+			depths:  []int{1},
+			wantErr: errStop,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/event-stream")
+				io.WriteString(w, tt.body)
+			})
+			var depths []int
+			done, err := c.BestMoveStream(context.Background(), StreamRequest{FEN: "x", Depth: 2, MoveTimeMS: 500}, func(res SearchResult) error {
+				depths = append(depths, res.Depth)
+				if res.Depth == tt.stopAt {
+					return errStop
+				}
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || done != tt.want {
+				t.Errorf("BestMoveStream = %+v, %v; want %+v, %v", done, err, tt.want, tt.wantErr)
+			}
+			if !reflect.DeepEqual(depths, tt.depths) {
+				t.Errorf("depths %v, want %v", depths, tt.depths)
+			}
+			if rec.path != "/best-move/stream" || rec.query != "depth=2&fen=x&movetime=500" {
+				t.Errorf("request %s?%s", rec.path, rec.query)
+			}
+		})
+	}
+}
+
+var errStop = errors.New("stop")
+
+func TestAnalyzeBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		indexes []int
+		want    BatchDone
+		wantErr error
+	}{
+		{
+			name: "complete",
+			body: `{"index":1,"fen":"b","best_move":"e2e4","score":20,"depth":3}
+{"index":0,"fen":"a","error":"invalid fen"}
+{"done":true,"total":2,"completed":2}
+`,
+			indexes: []int{1, 0},
+			want:    BatchDone{Done: true, Total: 2, Completed: 2},
+		},
+		{
+			name:    "cut short",
+			body:    `{"index":0,"fen":"a"}` + "\n",
+			indexes: []int{0},
+			wantErr: io.ErrUnexpectedEOF,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, tt.body)
+			})
+			var indexes []int
+			done, err := c.AnalyzeBatch(context.Background(), BatchRequest{FENs: []string{"a", "b"}}, func(res BatchResult) error {
+				indexes = append(indexes, res.Index)
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) || done != tt.want {
+				t.Errorf("AnalyzeBatch = %+v, %v; want %+v, %v", done, err, tt.want, tt.wantErr)
+			}
+			if !reflect.DeepEqual(indexes, tt.indexes) {
+				t.Errorf("results %v, want %v", indexes, tt.indexes)
+			}
+			if rec.method != "POST" || !strings.Contains(rec.body, `"fens":["a","b"]`) {
+				t.Errorf("request %s %s", rec.method, rec.body)
+			}
+		})
+	}
+}
+
+func TestParseMove(t *testing.T) {
+	tests := []struct {
+		uci     string
+		want    Move
+		wantErr bool
+	}{
+		{"e2e4", Move{From: Square{Line: 2, Column: 5}, To: Square{Line: 4, Column: 5}}, false},
+		{"a7a8q", Move{From: Square{Line: 7, Column: 1}, To: Square{Line: 8, Column: 1}}, false},
+		{"h1a8", Move{From: Square{Line: 1, Column: 8}, To: Square{Line: 8, Column: 1}}, false},
+		{"e2", Move{}, true},
+		{"i2i4", Move{}, true},
+		{"e0e4", Move{}, true},
+	}
+	for _, tt := range tests {
+		m, err := ParseMove(tt.uci)
+		if (err != nil) != tt.wantErr || m != tt.want {
+			t.Errorf("ParseMove(%q) = %+v, %v", tt.uci, m, err)
+			continue
+		}
+		if err == nil && m.UCI() != tt.uci[:4] {
+			t.Errorf("%q round-trips to %q", tt.uci, m.UCI())
+		}
+	}
+
+	// the JSON shape is the server's Move
+	data, err := json.Marshal(Move{From: Square{Line: 2, Column: 5}, To: Square{Line: 4, Column: 5}})
+	if err != nil || string(data) != `{"From":{"Line":2,"Column":5},"To":{"Line":4,"Column":5}}` {
+		t.Errorf("Move encodes as %s, %v", data, err)
+	}
+}
@@ -0,0 +1,279 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Square is a board square. Line is the rank and Column the file, both 1 to 8 with a1 at
+// {1, 1}: e4 is {Line: 4, Column: 5}.
+type Square struct {
+	Line   int `json:"Line"`
+	Column int `json:"Column"`
+}
+
+func (s Square) String() string {
+	return fmt.Sprintf("%c%d", 'a'+s.Column-1, s.Line)
+}
+
+// Move is how the server encodes a move, as its from and to squares. Promotion is always
+// to a queen and is not part of the move.
+type Move struct {
+	From Square `json:"From"`
+	To   Square `json:"To"`
+}
+
+// UCI returns the move in coordinate notation, e.g. e2e4.
+func (m Move) UCI() string {
+	return m.From.String() + m.To.String()
+}
+
+func (m Move) String() string {
+	return m.UCI()
+}
+
+// ParseMove reads a move in coordinate notation. A promotion suffix is accepted and ignored.
+func ParseMove(uci string) (Move, error) {
+	if len(uci) != 4 && len(uci) != 5 {
+		return Move{}, fmt.Errorf("invalid move %q", uci)
+	}
+	var m Move
+	for i, sq := range []*Square{&m.From, &m.To} {
+		file, rank := uci[2*i], uci[2*i+1]
+		if file < 'a' || file > 'h' || rank < '1' || rank > '8' {
+			return Move{}, fmt.Errorf("invalid move %q", uci)
+		}
+		*sq = Square{Line: int(rank - '0'), Column: int(file-'a') + 1}
+	}
+	return m, nil
+}
+
+type BestMove struct {
+	BestMove Move `json:"best_move"`
+	Depth    int  `json:"depth"` // 0 for book moves
+	Score    int  `json:"score"` // centipawns for the side to move; mates are near ±100000
+	Book     bool `json:"book,omitempty"`
+}
+
+// SearchResult is one completed iteration of a streamed search.
+type SearchResult struct {
+	Depth    int    `json:"depth"`
+	BestMove Move   `json:"best_move"`
+	Score    int    `json:"score"`
+	PV       []Move `json:"pv"`
+	Nodes    int64  `json:"nodes"`
+}
+
+// StreamDone ends a streamed search. Reason is depth, movetime or shutdown.
+type StreamDone struct {
+	Reason string `json:"reason"`
+	Depth  int    `json:"depth"`
+}
+
+type Status struct {
+	State  string `json:"state"`            // ongoing, checkmate, stalemate, draw or resigned
+	Reason string `json:"reason,omitempty"` // why a game is drawn
+	Winner string `json:"winner,omitempty"`
+}
+
+type PieceCounts struct {
+	Pawn   int `json:"pawn"`
+	Knight int `json:"knight"`
+	Bishop int `json:"bishop"`
+	Rook   int `json:"rook"`
+	Queen  int `json:"queen"`
+}
+
+type SideMaterial struct {
+	Pieces PieceCounts `json:"pieces"`
+	Points int         `json:"points"`
+}
+
+type LegalMove struct {
+	UCI     string `json:"uci"`
+	SAN     string `json:"san"`
+	Capture bool   `json:"capture"`
+}
+
+type Position struct {
+	FEN        string      `json:"fen"`
+	Turn       string      `json:"turn"`
+	InCheck    bool        `json:"in_check"`
+	Status     Status      `json:"status"`
+	LegalMoves []LegalMove `json:"legal_moves"`
+	Material   struct {
+		White   SideMaterial `json:"white"`
+		Black   SideMaterial `json:"black"`
+		Balance int          `json:"balance"` // white minus black, in pawns
+	} `json:"material"`
+}
+
+type PhaseScore struct {
+	MG int `json:"mg"`
+	EG int `json:"eg"`
+}
+
+type EvalTerm struct {
+	Name  string     `json:"name"`
+	White PhaseScore `json:"white"`
+	Black PhaseScore `json:"black"`
+	Total int        `json:"total"`
+}
+
+type EvalTrace struct {
+	FEN      string     `json:"fen"`
+	Phase    int        `json:"phase"`
+	MaxPhase int        `json:"max_phase"`
+	Terms    []EvalTerm `json:"terms"`
+	Terminal string     `json:"terminal,omitempty"`
+	Score    int        `json:"score"` // white-relative
+}
+
+type Profile struct {
+	Name   string          `json:"name"`
+	Params json.RawMessage `json:"params"`
+}
+
+type Job struct {
+	ID       int64           `json:"id"`
+	Key      string          `json:"key"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Priority int             `json:"priority"`
+	State    string          `json:"state"` // queued or running
+	Queued   time.Time       `json:"queued_at"`
+	Started  *time.Time      `json:"started_at,omitempty"`
+}
+
+type NewGame struct {
+	FEN         string `json:"fen,omitempty"` // the standard start position when empty
+	Depth       int    `json:"depth,omitempty"`
+	Profile     string `json:"profile,omitempty"`
+	EngineColor string `json:"engine_color,omitempty"` // side the engine plays automatically
+}
+
+type GameSettings struct {
+	Depth       int    `json:"depth"`
+	Profile     string `json:"profile,omitempty"`
+	EngineColor string `json:"engine_color,omitempty"`
+}
+
+type Ply struct {
+	Number int    `json:"number"`
+	Color  string `json:"color"`
+	UCI    string `json:"uci"`
+	SAN    string `json:"san"`
+	FEN    string `json:"fen"` // position after the move
+	Engine bool   `json:"engine"`
+}
+
+type Game struct {
+	ID         string       `json:"id"`
+	Settings   GameSettings `json:"settings"`
+	StartFEN   string       `json:"start_fen"`
+	FEN        string       `json:"fen"`
+	Turn       string       `json:"turn"`
+	Moves      []Ply        `json:"moves"`
+	Status     Status       `json:"status"`
+	Result     string       `json:"result"`
+	Resigned   string       `json:"resigned,omitempty"`
+	LegalMoves []string     `json:"legal_moves"`
+	PGN        string       `json:"pgn"`
+	Created    time.Time    `json:"created_at"`
+}
+
+type GameMoveResult struct {
+	Move  Ply  `json:"move"`
+	Reply *Ply `json:"reply,omitempty"` // the engine's answer, if it moved
+	Game  Game `json:"game"`
+}
+
+type BatchRequest struct {
+	FENs    []string `json:"fens,omitempty"`
+	PGN     string   `json:"pgn,omitempty"` // every position before each move of every game
+	Depth   int      `json:"depth,omitempty"`
+	Profile string   `json:"profile,omitempty"`
+}
+
+type BatchResult struct {
+	Index    int    `json:"index"`
+	FEN      string `json:"fen"`
+	Source   string `json:"source,omitempty"`
+	BestMove string `json:"best_move,omitempty"` // UCI
+	SAN      string `json:"san,omitempty"`
+	Score    int    `json:"score"`
+	Depth    int    `json:"depth"`
+	Cached   bool   `json:"cached"`
+	Error    string `json:"error,omitempty"`
+}
+
+type BatchDone struct {
+	Done      bool `json:"done"`
+	Total     int  `json:"total"`
+	Completed int  `json:"completed"`
+}
+
+type Batch struct {
+	ID        string        `json:"id"`
+	Total     int           `json:"total"`
+	Completed int           `json:"completed"`
+	Done      bool          `json:"done"`
+	Results   []BatchResult `json:"results"`
+}
+
+type ReviewRequest struct {
+	PGN     string   `json:"pgn,omitempty"`
+	Game    int      `json:"game,omitempty"` // 1-based game in a multi-game PGN
+	FEN     string   `json:"fen,omitempty"`  // start position for Moves
+	Moves   []string `json:"moves,omitempty"`
+	Depth   int      `json:"depth,omitempty"`
+	Profile string   `json:"profile,omitempty"`
+}
+
+type ReviewAlternative struct {
+	UCI   string `json:"uci"`
+	SAN   string `json:"san"`
+	Score int    `json:"score"`
+}
+
+type ReviewedMove struct {
+	Ply            int                `json:"ply"`
+	Number         int                `json:"number"`
+	Color          string             `json:"color"`
+	UCI            string             `json:"uci"`
+	SAN            string             `json:"san"`
+	FEN            string             `json:"fen"`         // position before the move
+	EvalBefore     int                `json:"eval_before"` // white-relative, with best play
+	EvalAfter      int                `json:"eval_after"`  // white-relative, after the played move
+	CentipawnLoss  int                `json:"cp_loss"`
+	Classification string             `json:"classification"`
+	Alternative    *ReviewAlternative `json:"alternative,omitempty"`
+}
+
+type PlayerReview struct {
+	Accuracy      float64        `json:"accuracy"`
+	AverageCPLoss int            `json:"acpl"`
+	Moves         int            `json:"moves"`
+	Counts        map[string]int `json:"counts"`
+}
+
+type Review struct {
+	Depth   int               `json:"depth"`
+	Profile string            `json:"profile,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	White   PlayerReview      `json:"white"`
+	Black   PlayerReview      `json:"black"`
+	Moves   []ReviewedMove    `json:"moves"`
+}
+
+type JobStats struct {
+	Queued   int  `json:"queued"`
+	Running  int  `json:"running"`
+	MaxQueue int  `json:"max_queue"`
+	Closed   bool `json:"closed"`
+}
+
+type Health struct {
+	Status string    `json:"status"`
+	Jobs   *JobStats `json:"jobs,omitempty"`
+}
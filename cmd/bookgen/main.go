@@ -0,0 +1,322 @@
+// Command bookgen builds a Polyglot opening book from PGN game collections. Every game that
+// passes the result and rating filters is replayed on the engine's board, up to -plies
+// halfmoves, and each move is counted against the position it was played in, with the
+// wins, draws and losses of the side that played it. Moves played in at least -min-count
+// games go into the book, weighted 2 per win and 1 per draw as Polyglot's own builder does;
+// moves that never scored are left out since Polyglot readers skip zero weights. -stats
+// also dumps the counts of every position as JSON.
+//
+// The engine cannot castle, so a game stops counting at its first castling move, as it
+// does at an illegal move. Games with a FEN tag are skipped, as they do not start from the
+// standard position, and so are games that cannot be read; the rest of their file is used.
+//
+// Usage: bookgen [flags] file.pgn|dir ...
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/g0g05arui/chess-engine/book"
+	engine "github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/pgn"
+)
+
+// moveStats are the games a move was played in, from the point of view of its side.
+type moveStats struct {
+	uci, san                   string
+	games, wins, draws, losses int
+}
+
+type position struct {
+	fen   string
+	games int
+	moves map[uint16]*moveStats // by Polyglot move
+}
+
+// counts tallies why games were left out, for the summary.
+type counts struct {
+	games, used, unreadable, result, rating, setup, illegal, castled int
+}
+
+func main() {
+	outFile := flag.String("out", "book.bin", "where to write the book")
+	statsFile := flag.String("stats", "", "where to write the move statistics as JSON; none when empty")
+	results := flag.String("results", "1-0,0-1,1/2-1/2", "comma-separated game results to count")
+	minElo := flag.Int("min-elo", 0, "skip games unless both WhiteElo and BlackElo are at least this; 0 also counts unrated games")
+	minCount := flag.Int("min-count", 3, "leave out moves played in fewer games")
+	plies := flag.Int("plies", 20, "count moves up to this many halfmoves into each game")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] file.pgn|dir ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	accepted := map[string]bool{}
+	for _, r := range strings.Split(*results, ",") {
+		r = strings.TrimSpace(r)
+		switch r {
+		case "1-0", "0-1", "1/2-1/2":
+			accepted[r] = true
+		default:
+			log.Fatalf("Invalid result %q: must be 1-0, 0-1 or 1/2-1/2", r)
+		}
+	}
+	if *minCount < 1 || *plies < 1 {
+		log.Fatal("-min-count and -plies must be at least 1")
+	}
+
+	files, err := pgnFiles(flag.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	positions := map[uint64]*position{}
+	var n counts
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatalf("Error reading %s: %v", path, err)
+		}
+		games, errs := pgn.ParseEach(string(data))
+		for _, err := range errs {
+			fmt.Printf("Skipping a game in %s: %v\n", path, err)
+		}
+		n.unreadable += len(errs)
+		for _, g := range games {
+			n.games++
+			switch {
+			case !accepted[g.Result]:
+				n.result++
+				continue
+			case !rated(g, *minElo):
+				n.rating++
+				continue
+			case g.Tags["FEN"] != "":
+				n.setup++
+				continue
+			}
+
+			g.Moves = g.Moves[:min(len(g.Moves), *plies)]
+			// the moves before castling or an illegal move still count
+			steps, err := g.Replay()
+			switch {
+			case errors.Is(err, pgn.ErrCastling):
+				n.castled++
+			case err != nil:
+				n.illegal++
+			}
+			n.used++
+			count(positions, steps, g.Result)
+		}
+	}
+	fmt.Printf("Read %d games from %d files, counted %d\n", n.games, len(files), n.used)
+	fmt.Printf("Skipped %d unreadable, %d by result, %d by rating and %d from a set-up position; %d stopped at castling and %d at an illegal move\n",
+		n.unreadable, n.result, n.rating, n.setup, n.castled, n.illegal)
+
+	entries := bookEntries(positions, *minCount)
+	f, err := os.Create(*outFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := book.Write(f, entries); err != nil {
+		log.Fatalf("Error writing %s: %v", *outFile, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Error writing %s: %v", *outFile, err)
+	}
+	fmt.Printf("Book with %d entries written to %s\n", len(entries), *outFile)
+
+	if *statsFile != "" {
+		if err := writeStats(*statsFile, positions, *minCount); err != nil {
+			log.Fatalf("Error writing %s: %v", *statsFile, err)
+		}
+		fmt.Printf("Statistics written to %s\n", *statsFile)
+	}
+}
+
+// pgnFiles expands directories in args to the .pgn files under them.
+func pgnFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, arg)
+			continue
+		}
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && strings.EqualFold(filepath.Ext(path), ".pgn") {
+				files = append(files, path)
+			}
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// rated reports whether both players are rated at least minElo.
+func rated(g pgn.Game, minElo int) bool {
+	if minElo <= 0 {
+		return true
+	}
+	for _, tag := range []string{"WhiteElo", "BlackElo"} {
+		elo, err := strconv.Atoi(g.Tags[tag])
+		if err != nil || elo < minElo {
+			return false
+		}
+	}
+	return true
+}
+
+// count adds the moves of a replayed game to positions, keyed like the positions the book
+// is looked up with.
+func count(positions map[uint64]*position, steps []pgn.Step, result string) {
+	var last *engine.Move
+	for i, s := range steps {
+		key := book.Key(s.Before, last)
+		last = &steps[i].Move
+
+		pos := positions[key]
+		if pos == nil {
+			pos = &position{fen: engine.BoardToFEN(s.Before), moves: map[uint16]*moveStats{}}
+			positions[key] = pos
+		}
+		pos.games++
+
+		promotion := 0
+		if s.Before.PiecesMatrix[s.Move.From.Line][s.Move.From.Column].Type == engine.Pawn && (s.Move.To.Line == 1 || s.Move.To.Line == 8) {
+			promotion = book.PromoteQueen
+		}
+		code := book.EncodeMove(s.Move, promotion)
+		ms := pos.moves[code]
+		if ms == nil {
+			ms = &moveStats{uci: engine.MoveToUCI(s.Before, s.Move), san: s.SAN}
+			pos.moves[code] = ms
+		}
+		ms.games++
+		switch {
+		case result == "1/2-1/2":
+			ms.draws++
+		case (result == "1-0") == s.Before.WhiteTurn:
+			ms.wins++
+		default:
+			ms.losses++
+		}
+	}
+}
+
+func (ms *moveStats) rawWeight() int {
+	return 2*ms.wins + ms.draws
+}
+
+// bookEntries are the moves played in at least minCount games, heaviest first within a
+// position, with weights scaled down to fit when the largest would overflow.
+func bookEntries(positions map[uint64]*position, minCount int) []book.Entry {
+	type raw struct {
+		key    uint64
+		move   uint16
+		weight int
+	}
+	var moves []raw
+	heaviest := 0
+	for key, pos := range positions {
+		for code, ms := range pos.moves {
+			if ms.games < minCount || ms.rawWeight() == 0 {
+				continue
+			}
+			moves = append(moves, raw{key, code, ms.rawWeight()})
+			heaviest = max(heaviest, ms.rawWeight())
+		}
+	}
+	slices.SortFunc(moves, func(a, b raw) int {
+		return cmp.Or(cmp.Compare(a.key, b.key), cmp.Compare(b.weight, a.weight), cmp.Compare(a.move, b.move))
+	})
+
+	entries := make([]book.Entry, len(moves))
+	for i, m := range moves {
+		w := m.weight
+		if heaviest > 0xffff {
+			w = max(1, w*0xffff/heaviest)
+		}
+		entries[i] = book.Entry{Key: m.key, Move: m.move, Weight: uint16(w)}
+	}
+	return entries
+}
+
+type statsPosition struct {
+	Key   string      `json:"key"` // Polyglot key, in hex
+	FEN   string      `json:"fen"`
+	Games int         `json:"games"`
+	Moves []statsMove `json:"moves"`
+}
+
+type statsMove struct {
+	Move   string  `json:"move"`
+	SAN    string  `json:"san"`
+	Games  int     `json:"games"`
+	Wins   int     `json:"wins"`
+	Draws  int     `json:"draws"`
+	Losses int     `json:"losses"`
+	Score  float64 `json:"score"` // points per game for the side that played the move
+	InBook bool    `json:"in_book"`
+}
+
+// writeStats dumps every position with a move played in at least minCount games, the most
+// played first.
+func writeStats(path string, positions map[uint64]*position, minCount int) error {
+	var out []statsPosition
+	for key, pos := range positions {
+		sp := statsPosition{Key: fmt.Sprintf("%016x", key), FEN: pos.fen, Games: pos.games}
+		for _, ms := range pos.moves {
+			if ms.games < minCount {
+				continue
+			}
+			sp.Moves = append(sp.Moves, statsMove{
+				Move:   ms.uci,
+				SAN:    ms.san,
+				Games:  ms.games,
+				Wins:   ms.wins,
+				Draws:  ms.draws,
+				Losses: ms.losses,
+				Score:  (float64(ms.wins) + float64(ms.draws)/2) / float64(ms.games),
+				InBook: ms.rawWeight() > 0,
+			})
+		}
+		if len(sp.Moves) == 0 {
+			continue
+		}
+		slices.SortFunc(sp.Moves, func(a, b statsMove) int {
+			return cmp.Or(cmp.Compare(b.Games, a.Games), cmp.Compare(a.Move, b.Move))
+		})
+		out = append(out, sp)
+	}
+	slices.SortFunc(out, func(a, b statsPosition) int {
+		return cmp.Or(cmp.Compare(b.Games, a.Games), cmp.Compare(a.Key, b.Key))
+	})
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/book"
+	engine "github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/pgn"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+
+// countGames replays each game, given as space-separated SAN and a result, into positions.
+func countGames(t *testing.T, games [][2]string) map[uint64]*position {
+	t.Helper()
+	positions := map[uint64]*position{}
+	for _, g := range games {
+		steps, err := pgn.Game{Tags: map[string]string{}, Moves: strings.Fields(g[0]), Result: g[1]}.Replay()
+		if err != nil {
+			t.Fatalf("%s: %v", g[0], err)
+		}
+		count(positions, steps, g[1])
+	}
+	return positions
+}
+
+// positionAfter is the stats entry of the position reached by the SAN moves.
+func positionAfter(t *testing.T, positions map[uint64]*position, moves string) *position {
+	t.Helper()
+	board, err := engine.ParseFEN(startFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var last *engine.Move
+	for _, san := range strings.Fields(moves) {
+		m, err := engine.ParseMove(board, san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		board, last = engine.BoardAfterMove(m, board), &m
+	}
+	pos := positions[book.Key(board, last)]
+	if pos == nil {
+		t.Fatalf("no statistics after %q", moves)
+	}
+	return pos
+}
+
+func moveNamed(pos *position, uci string) *moveStats {
+	for _, ms := range pos.moves {
+		if ms.uci == uci {
+			return ms
+		}
+	}
+	return nil
+}
+
+func TestCount(t *testing.T) {
+	positions := countGames(t, [][2]string{
+		{"e4 e5 Nf3", "1-0"},
+		{"e4 c5", "0-1"},
+		{"d4", "1/2-1/2"},
+		{"Nf3 Nf6 Nc3 d5", "1-0"},
+		{"Nc3 Nf6 Nf3 d5", "0-1"},
+	})
+	tests := []struct {
+		after, uci, san            string
+		posGames                   int
+		games, wins, draws, losses int
+	}{
+		{"", "e2e4", "e4", 5, 2, 1, 0, 1},
+		{"", "d2d4", "d4", 5, 1, 0, 1, 0},
+		{"e4", "e7e5", "e5", 2, 1, 0, 0, 1}, // from black's side
+		{"e4", "c7c5", "c5", 2, 1, 1, 0, 0},
+		{"e4 e5", "g1f3", "Nf3", 1, 1, 1, 0, 0},
+		{"Nf3 Nf6 Nc3", "d7d5", "d5", 2, 2, 1, 0, 1}, // reached by transposition too
+	}
+	for _, tt := range tests {
+		pos := positionAfter(t, positions, tt.after)
+		ms := moveNamed(pos, tt.uci)
+		if pos.games != tt.posGames || ms == nil {
+			t.Errorf("after %q: %d games, %s %v; want %d games", tt.after, pos.games, tt.uci, ms, tt.posGames)
+			continue
+		}
+		got := moveStats{tt.uci, ms.san, ms.games, ms.wins, ms.draws, ms.losses}
+		if want := (moveStats{tt.uci, tt.san, tt.games, tt.wins, tt.draws, tt.losses}); got != want {
+			t.Errorf("after %q: %+v, want %+v", tt.after, got, want)
+		}
+	}
+}
+
+func TestBookEntries(t *testing.T) {
+	positions := map[uint64]*position{
+		2: {moves: map[uint16]*moveStats{
+			10: {games: 5, wins: 1, draws: 3}, // weight 5
+			11: {games: 4, wins: 3},           // weight 6
+			12: {games: 2, wins: 2},           // too rare
+			13: {games: 6, losses: 6},         // never scored
+		}},
+		1: {moves: map[uint16]*moveStats{
+			20: {games: 3, draws: 3},
+		}},
+	}
+	want := []book.Entry{
+		{Key: 1, Move: 20, Weight: 3},
+		{Key: 2, Move: 11, Weight: 6},
+		{Key: 2, Move: 10, Weight: 5},
+	}
+	if got := bookEntries(positions, 3); !slices.Equal(got, want) {
+		t.Errorf("bookEntries = %v, want %v", got, want)
+	}
+
+	// weights are scaled to fit in 16 bits, keeping at least 1
+	heavy := map[uint64]*position{1: {moves: map[uint16]*moveStats{
+		1: {games: 100000, wins: 100000},
+		2: {games: 50000, wins: 50000},
+		3: {games: 1, wins: 1},
+	}}}
+	got := bookEntries(heavy, 1)
+	if len(got) != 3 || got[0].Weight != 0xffff || got[1].Weight != 0xffff/2 || got[2].Weight != 1 {
+		t.Errorf("scaled entries = %v", got)
+	}
+}
+
+func TestRated(t *testing.T) {
+	tests := []struct {
+		name   string
+		tags   map[string]string
+		minElo int
+		want   bool
+	}{
+		{"no filter", map[string]string{}, 0, true},
+		{"both rated", map[string]string{"WhiteElo": "2100", "BlackElo": "2000"}, 2000, true},
+		{"one below", map[string]string{"WhiteElo": "2100", "BlackElo": "1999"}, 2000, false},
+		{"unrated", map[string]string{"WhiteElo": "2100", "BlackElo": "-"}, 2000, false},
+		{"missing", map[string]string{"WhiteElo": "2100"}, 2000, false},
+	}
+	for _, tt := range tests {
+		if got := rated(pgn.Game{Tags: tt.tags}, tt.minElo); got != tt.want {
+			t.Errorf("%s: rated = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPGNFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.pgn", "b.PGN", "notes.txt", "sub/c.pgn"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	single := filepath.Join(dir, "notes.txt")
+
+	files, err := pgnFiles([]string{dir, single})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.pgn"), filepath.Join(dir, "b.PGN"), filepath.Join(dir, "sub/c.pgn"), single}
+	if !slices.Equal(files, want) {
+		t.Errorf("pgnFiles = %v, want %v", files, want)
+	}
+	if _, err := pgnFiles([]string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("a missing path was accepted")
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	positions := countGames(t, [][2]string{
+		{"e4 e5", "1-0"},
+		{"e4 e5", "1/2-1/2"},
+		{"e4 c5", "0-1"},
+		{"d4", "0-1"},
+	})
+	path := filepath.Join(t.TempDir(), "stats.json")
+	if err := writeStats(path, positions, 2); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []statsPosition
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	// d4, c5 and the single games after e5 are below the minimum
+	want := []statsPosition{
+		{FEN: startFEN, Games: 4, Moves: []statsMove{{Move: "e2e4", SAN: "e4", Games: 3, Wins: 1, Draws: 1, Losses: 1, Score: 0.5, InBook: true}}},
+		{Games: 3, Moves: []statsMove{{Move: "e7e5", SAN: "e5", Games: 2, Draws: 1, Losses: 1, Score: 0.25, InBook: true}}},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("stats = %+v, want %d positions", got, len(want))
+	}
+	for i := range want {
+		want[i].Key = got[i].Key
+		if i > 0 {
+			want[i].FEN = got[i].FEN
+		}
+		if len(got[i].Key) != 16 || !slices.Equal(got[i].Moves, want[i].Moves) || got[i].FEN != want[i].FEN || got[i].Games != want[i].Games {
+			t.Errorf("position %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBuiltBookPlaysTheMoves(t *testing.T) {
+	positions := countGames(t, [][2]string{
+		{"e4 e5", "1-0"},
+		{"e4 e5", "1-0"},
+		{"e4 c5", "1/2-1/2"},
+		{"d4 d5", "1/2-1/2"},
+	})
+	b, err := book.New(bookEntries(positions, 1), book.Options{Selection: book.Best})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start, err := engine.ParseFEN(startFEN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var moves []string
+	for _, c := range b.Candidates(start, book.Key(start, nil)) {
+		moves = append(moves, engine.MoveToUCI(start, c.Move))
+	}
+	if !slices.Equal(moves, []string{"e2e4", "d2d4"}) {
+		t.Errorf("book moves at the start = %v, want [e2e4 d2d4]", moves)
+	}
+
+	e4, err := engine.ParseMove(start, "e4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	after := engine.BoardAfterMove(e4, start)
+	reply, ok := b.Pick(after, book.Key(after, &e4), 1)
+	if !ok || engine.MoveToUCI(after, reply) != "c7c5" {
+		t.Errorf("book reply to e4 = %v, %v; want c7c5, the only one that scored for black", reply, ok)
+	}
+}
@@ -0,0 +1,325 @@
+// Command tune fits the handcrafted evaluation weights to game results with Texel's method.
+// It reads quiet positions labelled with the result of the game they come from and runs a
+// local search over every weight, minimising the mean squared error between the result and
+// sigmoid(eval). The tuned weights are written as JSON and can be loaded with
+// game_state.LoadEvalParams.
+//
+// Accepted lines: EPD with a c9 opcode (`... c9 "1-0";`), or a FEN followed by a result
+// either in brackets (`[1.0]`, `[0.5]`, `[1-0]`) or as the last field (`1-0`, `0-1`, `1/2-1/2`).
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+type sample struct {
+	board  engine.Board
+	result float64 // 1 white win, 0.5 draw, 0 black win
+}
+
+// param is a single tunable weight, addressed by its JSON path.
+type param struct {
+	name  string
+	value reflect.Value
+	step  float64
+}
+
+func (p param) get() float64 {
+	if p.value.Kind() == reflect.Float32 {
+		return p.value.Float()
+	}
+	return float64(p.value.Int())
+}
+
+func (p param) set(v float64) {
+	if p.value.Kind() == reflect.Float32 {
+		p.value.SetFloat(v)
+		return
+	}
+	p.value.SetInt(int64(math.Round(v)))
+}
+
+func main() {
+	dataFile := flag.String("data", "", "file of quiet positions labelled with game results (required)")
+	outFile := flag.String("out", "eval_params.json", "where to write the tuned weights")
+	initFile := flag.String("init", "", "weights to start from; the built-in weights when empty")
+	k := flag.Float64("k", 0, "sigmoid scale; fitted to the data when 0")
+	step := flag.Int("step", 1, "step for integer weights")
+	passes := flag.Int("passes", 50, "maximum number of passes over all weights")
+	limit := flag.Int("limit", 0, "use at most this many positions; 0 for all")
+	freeze := flag.String("freeze", "piece_values.pawn,king_attack_weight.pawn,safe_check.pawn",
+		"comma-separated weights to leave untouched")
+	flag.Parse()
+
+	if *dataFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	params := engine.DefaultEvalParams()
+	if *initFile != "" {
+		var err error
+		params, err = engine.LoadEvalParams(*initFile)
+		if err != nil {
+			log.Fatalf("Error loading %s: %v", *initFile, err)
+		}
+	}
+
+	samples, err := loadSamples(*dataFile, *limit)
+	if err != nil {
+		log.Fatalf("Error reading %s: %v", *dataFile, err)
+	}
+	if len(samples) == 0 {
+		log.Fatal("No usable positions")
+	}
+	fmt.Printf("Loaded %d positions\n", len(samples))
+
+	if *k == 0 {
+		*k = fitK(samples, params)
+	}
+	fmt.Printf("Using K = %.3f\n", *k)
+
+	frozen := map[string]bool{}
+	for _, name := range strings.Split(*freeze, ",") {
+		frozen[strings.TrimSpace(name)] = true
+	}
+
+	var tunables []param
+	collectParams(reflect.ValueOf(params).Elem(), "", float64(*step), &tunables)
+
+	best := meanError(samples, params, *k)
+	fmt.Printf("Initial error %.6f over %d weights\n", best, len(tunables))
+
+	// try sets a weight and returns the error, or false when the weights would no longer
+	// load, so the tuned file is always one the server accepts
+	try := func(p param, value float64) (float64, bool) {
+		p.set(value)
+		if params.Validate() != nil {
+			return 0, false
+		}
+		return meanError(samples, params, *k), true
+	}
+
+	// weights that never change the error are skipped after the first pass
+	inert := map[string]bool{}
+	for pass := 1; pass <= *passes; pass++ {
+		improved := 0
+		for _, p := range tunables {
+			if frozen[p.name] || inert[p.name] {
+				continue
+			}
+			orig := p.get()
+
+			up, upOK := try(p, orig+p.step)
+			if upOK && up < best {
+				best = up
+				improved++
+				continue
+			}
+
+			down, downOK := try(p, orig-p.step)
+			if downOK && down < best {
+				best = down
+				improved++
+				continue
+			}
+
+			p.set(orig)
+			if pass == 1 && upOK && downOK && up == down {
+				inert[p.name] = true
+			}
+		}
+
+		fmt.Printf("Pass %d: error %.6f, %d weights changed\n", pass, best, improved)
+		if err := params.Validate(); err != nil {
+			log.Fatalf("Tuned weights are invalid: %v", err)
+		}
+		if err := params.Save(*outFile); err != nil {
+			log.Fatalf("Error writing %s: %v", *outFile, err)
+		}
+		if improved == 0 {
+			break
+		}
+	}
+
+	fmt.Printf("Tuned weights written to %s\n", *outFile)
+}
+
+// collectParams walks the weights struct and records every int and float32 leaf.
+func collectParams(v reflect.Value, name string, step float64, out *[]param) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			key := strings.Split(f.Tag.Get("json"), ",")[0]
+			if key == "" {
+				key = f.Name
+			}
+			if name != "" {
+				key = name + "." + key
+			}
+			collectParams(v.Field(i), key, step, out)
+		}
+	case reflect.Array, reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			collectParams(v.Index(i), fmt.Sprintf("%s[%d]", name, i), step, out)
+		}
+	case reflect.Int:
+		*out = append(*out, param{name: name, value: v, step: step})
+	case reflect.Float32:
+		*out = append(*out, param{name: name, value: v, step: 0.005})
+	}
+}
+
+func loadSamples(path string, limit int) ([]sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var samples []sample
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, err := parseSample(line)
+		if err != nil {
+			fmt.Printf("Skipping line %d: %v\n", lineNo, err)
+			continue
+		}
+		if !isQuiet(s.board) {
+			continue
+		}
+		samples = append(samples, s)
+		if limit > 0 && len(samples) >= limit {
+			break
+		}
+	}
+	return samples, scanner.Err()
+}
+
+func parseSample(line string) (s sample, err error) {
+	var fen, result string
+
+	switch {
+	case strings.Contains(line, "c9 \""):
+		i := strings.Index(line, "c9 \"")
+		fen = line[:i]
+		result = strings.SplitN(line[i+4:], "\"", 2)[0]
+	case strings.Contains(line, "["):
+		i := strings.LastIndex(line, "[")
+		fen = line[:i]
+		result = strings.TrimSuffix(strings.TrimSpace(line[i+1:]), "]")
+	default:
+		fields := strings.Fields(strings.NewReplacer(";", " ", "|", " ").Replace(line))
+		if len(fields) < 3 {
+			return s, fmt.Errorf("no result")
+		}
+		fen = strings.Join(fields[:len(fields)-1], " ")
+		result = fields[len(fields)-1]
+	}
+
+	switch strings.TrimSpace(result) {
+	case "1-0":
+		s.result = 1
+	case "0-1":
+		s.result = 0
+	case "1/2-1/2":
+		s.result = 0.5
+	default:
+		s.result, err = strconv.ParseFloat(strings.TrimSpace(result), 64)
+		if err != nil || s.result < 0 || s.result > 1 {
+			return s, fmt.Errorf("invalid result %q", result)
+		}
+	}
+
+	fields := strings.Fields(fen)
+	if len(fields) < 2 {
+		return s, fmt.Errorf("invalid FEN %q", fen)
+	}
+
+	// FENToBoard panics on malformed placement
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid FEN %q: %v", fen, r)
+		}
+	}()
+	s.board = engine.FENToBoard(fields[0] + " " + fields[1])
+	return s, nil
+}
+
+// isQuiet drops positions where the static evaluation is meaningless: mates, stalemates and checks.
+func isQuiet(board engine.Board) bool {
+	side := engine.WhiteColor
+	if !board.WhiteTurn {
+		side = engine.BlackColor
+	}
+	return !engine.IsKingInCheck(board, side) && engine.HasLegalMoves(board, side)
+}
+
+func sigmoid(score, k float64) float64 {
+	return 1 / (1 + math.Pow(10, -k*score/400))
+}
+
+// meanError evaluates every sample in parallel.
+func meanError(samples []sample, params *engine.EvalParams, k float64) float64 {
+	workers := runtime.NumCPU()
+	sums := make([]float64, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(samples); i += workers {
+				score := float64(engine.StaticEvaluate(samples[i].board, params))
+				diff := samples[i].result - sigmoid(score, k)
+				sums[w] += diff * diff
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	total := 0.0
+	for _, s := range sums {
+		total += s
+	}
+	return total / float64(len(samples))
+}
+
+// fitK finds the sigmoid scale that best matches the current weights to the results,
+// with a coarse scan followed by a finer one around the best value.
+func fitK(samples []sample, params *engine.EvalParams) float64 {
+	best, bestErr := 1.0, math.Inf(1)
+	scan := func(lo, hi, step float64) {
+		for k := lo; k <= hi; k += step {
+			if e := meanError(samples, params, k); e < bestErr {
+				best, bestErr = k, e
+			}
+		}
+	}
+	scan(0.1, 3.0, 0.1)
+	scan(math.Max(best-0.1, 0.01), best+0.1, 0.01)
+	return best
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+func TestParseSample(t *testing.T) {
+	const fen = "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b"
+	tests := []struct {
+		name    string
+		line    string
+		want    float64
+		wantErr bool
+	}{
+		{"EPD c9", fen + ` - - c9 "1-0";`, 1, false},
+		{"bracketed float", fen + " - - 0 1 [0.5]", 0.5, false},
+		{"bracketed result", fen + " - - 0 1 [0-1]", 0, false},
+		{"trailing result", fen + " - - 0 1 1/2-1/2", 0.5, false},
+		{"semicolon separated", fen + " - - 0 1; 1-0", 1, false},
+		{"result out of range", fen + " - - 0 1 [1.5]", 0, true},
+		{"no result", "8/8/8", 0, true},
+		{"bad placement", "rnbqkbnr/ppp/8 w [1.0]", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := parseSample(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSample err = %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if s.result != tt.want {
+				t.Errorf("result = %v, want %v", s.result, tt.want)
+			}
+			if s.board.WhiteTurn {
+				t.Error("side to move not read from the FEN")
+			}
+		})
+	}
+}
+
+func TestCollectParams(t *testing.T) {
+	params := engine.DefaultEvalParams()
+	var all []param
+	collectParams(reflect.ValueOf(params).Elem(), "", 2, &all)
+
+	byName := map[string]param{}
+	for _, p := range all {
+		byName[p.name] = p
+	}
+	for name, step := range map[string]float64{
+		"piece_values.knight":      2,
+		"center_value_multiplier":  0.005,
+		"mobility.rook[3].eg":      2,
+		"passed_pawn[6].mg":        2,
+		"king_attacker_percent[2]": 2,
+	} {
+		p, ok := byName[name]
+		if !ok {
+			t.Errorf("%s not collected", name)
+			continue
+		}
+		if p.step != step {
+			t.Errorf("%s step = %v, want %v", name, p.step, step)
+		}
+	}
+
+	// weights are set in place
+	knight := byName["piece_values.knight"]
+	knight.set(knight.get() + 2.4)
+	if params.PieceValues.Knight != engine.DefaultEvalParams().PieceValues.Knight+2 {
+		t.Errorf("knight = %d after a rounded step of 2.4", params.PieceValues.Knight)
+	}
+	center := byName["center_value_multiplier"]
+	center.set(0.25)
+	if params.CenterValueMultiplier != 0.25 {
+		t.Errorf("center_value_multiplier = %v, want 0.25", params.CenterValueMultiplier)
+	}
+}
+
+func TestSigmoid(t *testing.T) {
+	tests := []struct {
+		score, k, want float64
+	}{
+		{0, 1, 0.5},
+		{400, 1, 10.0 / 11},
+		{-400, 1, 1.0 / 11},
+		{400, 2, 100.0 / 101},
+	}
+	for _, tt := range tests {
+		if got := sigmoid(tt.score, tt.k); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("sigmoid(%v, %v) = %v, want %v", tt.score, tt.k, got, tt.want)
+		}
+	}
+}
+
+func TestIsQuiet(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want bool
+	}{
+		{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1", true},
+		{"in check", "4k3/8/8/8/8/8/4r3/4K3 w - - 0 1", false},
+		{"stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := engine.ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := isQuiet(board); got != tt.want {
+				t.Errorf("isQuiet = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,32 +1,32 @@
 package computed
 
 import (
-	"sync"
-
 	engine "github.com/g0g05arui/chess-engine/game_state"
 )
 
 type CacheKey = struct {
 	Fen       string
 	WhiteTurn bool
+	Profile   string // evaluation profile, empty for the default
+	Eval      string // fingerprint of the profile's weights, which a reload may change
 }
 
 type CacheValue = struct {
 	BestMove engine.Move
 	Depth    int
+	Score    int // from the side to move's point of view
 }
 
 type DeepCacheKey struct {
 	Fen       string
 	WhiteTurn bool
+	Profile   string
+	Eval      string
 	Depth     int
 }
 
-type DeepCacheValue struct {
-	BestMove engine.Move
-	Depth    int
-}
+type DeepCacheValue = CacheValue
 
-var DeepCache = make(map[DeepCacheKey]DeepCacheValue)
-var Cache = make(map[CacheKey]engine.Move)
-var InProgress sync.Map // key = DeepCacheKey, value = struct{}{}
+// Both caches are shared by request handlers and background searches.
+var DeepCache = NewLRU[DeepCacheKey](DefaultCacheCapacity)
+var Cache = NewLRU[CacheKey](DefaultCacheCapacity)
@@ -0,0 +1,141 @@
+package computed
+
+import (
+	"container/list"
+	"sync"
+)
+
+const DefaultCacheCapacity = 100_000
+
+// evictionWindow is how many of the least recently used entries are considered when
+// making room; the shallowest of them is evicted, so deep results outlive shallow ones.
+const evictionWindow = 4
+
+// LRU is a bounded, concurrency-safe cache of search results.
+type LRU[K comparable] struct {
+	mu        sync.Mutex
+	capacity  int
+	items     map[K]*list.Element
+	order     *list.List // front is the most recently used
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+type lruEntry[K comparable] struct {
+	key   K
+	value CacheValue
+}
+
+// CacheStats is a snapshot of a cache's size and counters.
+type CacheStats struct {
+	Len       int    `json:"len"`
+	Capacity  int    `json:"capacity"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+func NewLRU[K comparable](capacity int) *LRU[K] {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &LRU[K]{
+		capacity: capacity,
+		items:    make(map[K]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get returns the cached value for key and marks it as recently used.
+func (c *LRU[K]) Get(key K) (CacheValue, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		c.misses++
+		return CacheValue{}, false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry[K]).value, true
+}
+
+// Contains reports whether key is cached without touching recency or counters.
+func (c *LRU[K]) Contains(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.items[key]
+	return ok
+}
+
+// Put stores value under key. A cached result from a deeper search is kept in place of a shallower one.
+func (c *LRU[K]) Put(key K, value CacheValue) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry[K])
+		if value.Depth >= entry.value.Depth {
+			entry.value = value
+		}
+		c.order.MoveToFront(el)
+		return
+	}
+
+	for len(c.items) >= c.capacity {
+		c.evict()
+	}
+	c.items[key] = c.order.PushFront(&lruEntry[K]{key: key, value: value})
+}
+
+// evict removes the shallowest of the least recently used entries. Callers hold c.mu.
+func (c *LRU[K]) evict() {
+	victim := c.order.Back()
+	el := victim
+	for i := 0; i < evictionWindow && el != nil; i++ {
+		if el.Value.(*lruEntry[K]).value.Depth < victim.Value.(*lruEntry[K]).value.Depth {
+			victim = el
+		}
+		el = el.Prev()
+	}
+	if victim == nil {
+		return
+	}
+	c.order.Remove(victim)
+	delete(c.items, victim.Value.(*lruEntry[K]).key)
+	c.evictions++
+}
+
+// SetCapacity changes the maximum number of entries, evicting if the cache is over the new size.
+func (c *LRU[K]) SetCapacity(capacity int) {
+	if capacity < 1 {
+		capacity = 1
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.capacity = capacity
+	for len(c.items) > c.capacity {
+		c.evict()
+	}
+}
+
+func (c *LRU[K]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+func (c *LRU[K]) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{
+		Len:       len(c.items),
+		Capacity:  c.capacity,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+	}
+}
@@ -0,0 +1,134 @@
+package computed
+
+import "testing"
+
+func TestLRUPut(t *testing.T) {
+	tests := []struct {
+		name      string
+		depths    []int // depth of each key put in order, keys are 0, 1, 2, ...
+		capacity  int
+		touch     []int // keys read after the puts, before the last put
+		evicted   []int
+		remaining []int
+	}{
+		{
+			name:      "evicts the least recently used when depths are equal",
+			depths:    []int{3, 3, 3},
+			capacity:  2,
+			evicted:   []int{0},
+			remaining: []int{1, 2},
+		},
+		{
+			name:      "prefers evicting a shallow entry within the window",
+			depths:    []int{6, 2, 6, 6},
+			capacity:  3,
+			evicted:   []int{1},
+			remaining: []int{0, 2, 3},
+		},
+		{
+			name:      "a read keeps an entry out of the window",
+			depths:    []int{2, 6, 6, 6, 6, 6},
+			capacity:  5,
+			touch:     []int{0},
+			evicted:   []int{1},
+			remaining: []int{0, 2, 3, 4, 5},
+		},
+		{
+			name:      "shallow entries past the window are not preferred",
+			depths:    []int{6, 6, 6, 6, 1, 6},
+			capacity:  5,
+			evicted:   []int{0},
+			remaining: []int{1, 2, 3, 4, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRU[int](tt.capacity)
+			last := len(tt.depths) - 1
+			for key, depth := range tt.depths[:last] {
+				c.Put(key, CacheValue{Depth: depth})
+			}
+			for _, key := range tt.touch {
+				c.Get(key)
+			}
+			c.Put(last, CacheValue{Depth: tt.depths[last]})
+
+			for _, key := range tt.evicted {
+				if c.Contains(key) {
+					t.Errorf("key %d still cached", key)
+				}
+			}
+			for _, key := range tt.remaining {
+				if !c.Contains(key) {
+					t.Errorf("key %d was evicted", key)
+				}
+			}
+			if got := c.Stats().Evictions; got != uint64(len(tt.evicted)) {
+				t.Errorf("Evictions = %d, want %d", got, len(tt.evicted))
+			}
+		})
+	}
+}
+
+func TestLRUPutKeepsDeeperResult(t *testing.T) {
+	tests := []struct {
+		name      string
+		old, new  CacheValue
+		wantScore int
+	}{
+		{"deeper replaces", CacheValue{Depth: 2, Score: 10}, CacheValue{Depth: 4, Score: 20}, 20},
+		{"equal depth replaces", CacheValue{Depth: 4, Score: 10}, CacheValue{Depth: 4, Score: 20}, 20},
+		{"shallower is dropped", CacheValue{Depth: 4, Score: 10}, CacheValue{Depth: 2, Score: 20}, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRU[string](4)
+			c.Put("k", tt.old)
+			c.Put("k", tt.new)
+			got, ok := c.Get("k")
+			if !ok {
+				t.Fatal("key missing")
+			}
+			if got.Score != tt.wantScore {
+				t.Errorf("Score = %d, want %d", got.Score, tt.wantScore)
+			}
+			if c.Len() != 1 {
+				t.Errorf("Len = %d, want 1", c.Len())
+			}
+		})
+	}
+}
+
+func TestLRUSetCapacity(t *testing.T) {
+	c := NewLRU[int](8)
+	for key := 0; key < 8; key++ {
+		c.Put(key, CacheValue{Depth: 5})
+	}
+	c.Put(0, CacheValue{Depth: 5}) // most recently used
+	c.SetCapacity(3)
+
+	if c.Len() != 3 {
+		t.Fatalf("Len = %d, want 3", c.Len())
+	}
+	for _, key := range []int{0, 6, 7} {
+		if !c.Contains(key) {
+			t.Errorf("key %d was evicted", key)
+		}
+	}
+	stats := c.Stats()
+	if stats.Capacity != 3 || stats.Evictions != 5 {
+		t.Errorf("Stats = %+v, want capacity 3 and 5 evictions", stats)
+	}
+}
+
+func TestLRUCountsHitsAndMisses(t *testing.T) {
+	c := NewLRU[int](2)
+	c.Put(1, CacheValue{})
+	c.Get(1)
+	c.Get(1)
+	c.Get(2)
+	c.Contains(2)
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Stats = %+v, want 2 hits and 1 miss", stats)
+	}
+}
@@ -0,0 +1,280 @@
+package computed
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+const storeFileName = "analysis.log"
+
+// storeVersion is the record format. Records of other versions, including the unversioned
+// ones from before scores and weight fingerprints were stored, are ignored on load and
+// dropped by the next Compact.
+const storeVersion = 1
+
+var ErrStoreClosed = errors.New("analysis store is closed")
+
+// StoreKey identifies a stored result by Zobrist hash (which includes the side to move),
+// evaluation profile, the weights behind that profile and search depth.
+type StoreKey struct {
+	Hash    uint64
+	Profile string
+	Eval    string // fingerprint of the evaluator's weights, so changed weights miss
+	Depth   int
+}
+
+// storeRecord is one line of the log.
+type storeRecord struct {
+	Version int         `json:"v"`
+	Hash    uint64      `json:"h"`
+	Profile string      `json:"p,omitempty"`
+	Eval    string      `json:"e,omitempty"`
+	Depth   int         `json:"d"`
+	Move    engine.Move `json:"m"`
+	Score   int         `json:"s"`
+}
+
+// Store persists search results in an append-only log under a directory so deep analysis
+// survives restarts. The whole log is indexed in memory on open; a later record for the
+// same key replaces an earlier one, and Compact rewrites the log with one record per key.
+// A nil *Store is valid and stores nothing.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	w       *bufio.Writer
+	index   map[StoreKey]CacheValue
+	records int // lines in the log, including superseded ones
+}
+
+// OpenStore loads the log in dir, creating the directory if needed. A partial last line,
+// left by a crash mid-write, is cut off.
+func OpenStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		path:  filepath.Join(dir, storeFileName),
+		index: make(map[StoreKey]CacheValue),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.openForAppend(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var complete int64 // bytes up to the last newline
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				// drop the partial record so the next append starts on a fresh line
+				return os.Truncate(s.path, complete)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.path, err)
+		}
+		complete += int64(len(line))
+
+		var rec storeRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			continue
+		}
+		s.records++
+		if rec.Version != storeVersion {
+			continue
+		}
+		s.index[StoreKey{Hash: rec.Hash, Profile: rec.Profile, Eval: rec.Eval, Depth: rec.Depth}] = CacheValue{BestMove: rec.Move, Depth: rec.Depth, Score: rec.Score}
+	}
+}
+
+func (s *Store) openForAppend() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.file = f
+	s.w = bufio.NewWriter(f)
+	return nil
+}
+
+func (s *Store) Get(key StoreKey) (CacheValue, bool) {
+	if s == nil {
+		return CacheValue{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.index[key]
+	return value, ok
+}
+
+// Put records a result. Writes are flushed to the OS immediately but not fsynced.
+func (s *Store) Put(key StoreKey, value CacheValue) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	value.Depth = key.Depth
+	if old, ok := s.index[key]; ok && old == value {
+		return nil
+	}
+	data, err := json.Marshal(storeRecord{Version: storeVersion, Hash: key.Hash, Profile: key.Profile, Eval: key.Eval, Depth: key.Depth, Move: value.BestMove, Score: value.Score})
+	if err != nil {
+		return err
+	}
+	s.w.Write(data)
+	s.w.WriteByte('\n')
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	s.index[key] = value
+	s.records++
+	return nil
+}
+
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.index)
+}
+
+// Compact rewrites the log with a single record per key and atomically replaces the old file.
+func (s *Store) Compact() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return ErrStoreClosed
+	}
+	tmp := s.path + ".tmp"
+	if err := s.writeSnapshot(tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Everything in the old log is in the snapshot too, so its flush errors only matter if
+	// the snapshot cannot replace it. Until the log is reopened writes fail with ErrStoreClosed.
+	err := errors.Join(s.w.Flush(), s.file.Close())
+	s.file = nil
+	if renameErr := os.Rename(tmp, s.path); renameErr != nil {
+		os.Remove(tmp)
+		return errors.Join(err, renameErr, s.openForAppend())
+	}
+	s.records = len(s.index)
+	return s.openForAppend()
+}
+
+// writeSnapshot writes a record for each key in the index to a new file at path and syncs it.
+func (s *Store) writeSnapshot(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for key, value := range s.index {
+		if err := enc.Encode(storeRecord{Version: storeVersion, Hash: key.Hash, Profile: key.Profile, Eval: key.Eval, Depth: key.Depth, Move: value.BestMove, Score: value.Score}); err != nil {
+			return errors.Join(err, f.Close())
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	if err := f.Sync(); err != nil {
+		return errors.Join(err, f.Close())
+	}
+	return f.Close()
+}
+
+// CompactEvery compacts the log every interval once superseded records make up at least
+// half of it, logging failures to logger. It returns a function that stops the background loop.
+func (s *Store) CompactEvery(interval time.Duration, logger *slog.Logger) (stop func()) {
+	if s == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				s.mu.Lock()
+				wasted := s.records - len(s.index)
+				s.mu.Unlock()
+				if wasted == 0 || wasted*2 < s.records {
+					continue
+				}
+				if err := s.Compact(); err != nil {
+					logger.Error("Error compacting analysis store", "err", err)
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// Close flushes pending writes to disk and closes the log. Later writes fail with
+// ErrStoreClosed, while reads keep working from the in-memory index.
+func (s *Store) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	f := s.file
+	s.file = nil
+	if err := s.w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
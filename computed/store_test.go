@@ -0,0 +1,286 @@
+package computed
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+func move(fromLine, fromColumn, toLine, toColumn int8) engine.Move {
+	return engine.Move{From: engine.Position{Line: fromLine, Column: fromColumn}, To: engine.Position{Line: toLine, Column: toColumn}}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	entries := []struct {
+		key   StoreKey
+		value CacheValue
+	}{
+		{StoreKey{Hash: 1, Depth: 4}, CacheValue{BestMove: move(2, 5, 4, 5), Score: 30}},
+		{StoreKey{Hash: 1, Depth: 6}, CacheValue{BestMove: move(2, 4, 4, 4), Score: 25}},
+		{StoreKey{Hash: 1, Profile: "aggressive", Depth: 4}, CacheValue{BestMove: move(1, 7, 3, 6), Score: 40}},
+		{StoreKey{Hash: 1, Profile: "aggressive", Eval: "ab12", Depth: 4}, CacheValue{BestMove: move(1, 2, 3, 3), Score: -15}},
+		{StoreKey{Hash: 2, Depth: 4}, CacheValue{BestMove: move(7, 5, 5, 5), Score: -30}},
+	}
+
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if err := s.Put(e.key, e.value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != len(entries) {
+		t.Errorf("Len = %d, want %d", s.Len(), len(entries))
+	}
+	for _, e := range entries {
+		want := e.value
+		want.Depth = e.key.Depth
+		got, ok := s.Get(e.key)
+		if !ok {
+			t.Errorf("%+v missing after reopen", e.key)
+		} else if got != want {
+			t.Errorf("%+v = %+v, want %+v", e.key, got, want)
+		}
+	}
+	if _, ok := s.Get(StoreKey{Hash: 1, Eval: "other", Depth: 4}); ok {
+		t.Error("found a result under different weights")
+	}
+}
+
+func TestStoreLaterRecordWins(t *testing.T) {
+	dir := t.TempDir()
+	key := StoreKey{Hash: 7, Depth: 5}
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Put(key, CacheValue{Score: 1})
+	s.Put(key, CacheValue{Score: 2})
+	s.Close()
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, _ := s.Get(key); got.Score != 2 {
+		t.Errorf("Score = %d, want 2", got.Score)
+	}
+}
+
+func TestStoreCompact(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for score := 0; score < 5; score++ {
+		s.Put(StoreKey{Hash: 3, Depth: 4}, CacheValue{Score: score})
+	}
+	s.Put(StoreKey{Hash: 4, Depth: 4}, CacheValue{Score: 9})
+	if err := s.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	s.Put(StoreKey{Hash: 5, Depth: 4}, CacheValue{Score: 11})
+	s.Close()
+
+	if n := countLines(t, filepath.Join(dir, storeFileName)); n != 3 {
+		t.Errorf("log has %d records after compaction, want 3", n)
+	}
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for hash, want := range map[uint64]int{3: 4, 4: 9, 5: 11} {
+		if got, _ := s.Get(StoreKey{Hash: hash, Depth: 4}); got.Score != want {
+			t.Errorf("hash %d: Score = %d, want %d", hash, got.Score, want)
+		}
+	}
+}
+
+func TestStoreCompactFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    func(t *testing.T, path string) // makes the compaction fail
+		writable bool                            // the store still takes writes afterwards
+	}{
+		{"snapshot cannot be created", func(t *testing.T, path string) {
+			if err := os.Mkdir(path+".tmp", 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}, true},
+		{"log replaced by a directory", func(t *testing.T, path string) {
+			// the open log survives its removal; the rename and the reopen both fail
+			if err := os.Remove(path); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.MkdirAll(filepath.Join(path, "sub"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			s, err := OpenStore(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			s.Put(StoreKey{Hash: 1, Depth: 2}, CacheValue{Score: 3})
+			tt.block(t, filepath.Join(dir, storeFileName))
+
+			if err := s.Compact(); err == nil {
+				t.Fatal("Compact succeeded")
+			}
+			err = s.Put(StoreKey{Hash: 2, Depth: 2}, CacheValue{Score: 4})
+			if tt.writable && err != nil {
+				t.Errorf("Put after a failed snapshot = %v", err)
+			}
+			if !tt.writable && err != ErrStoreClosed {
+				t.Errorf("Put without a log = %v, want ErrStoreClosed", err)
+			}
+			if _, ok := s.Get(StoreKey{Hash: 1, Depth: 2}); !ok {
+				t.Error("the failed compaction lost the in-memory index")
+			}
+		})
+	}
+}
+
+func TestStoreCompactEveryLogsFailures(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for score := 0; score < 3; score++ {
+		s.Put(StoreKey{Hash: 1, Depth: 2}, CacheValue{Score: score})
+	}
+	if err := os.Mkdir(filepath.Join(dir, storeFileName+".tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	logged := make(chan string, 1)
+	logger := slog.New(slog.NewTextHandler(writerFunc(func(p []byte) {
+		select {
+		case logged <- string(p):
+		default:
+		}
+	}), nil))
+	stop := s.CompactEvery(time.Millisecond, logger)
+	defer stop()
+	select {
+	case line := <-logged:
+		if !strings.Contains(line, "Error compacting analysis store") {
+			t.Errorf("logged %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the failed compaction was not logged")
+	}
+}
+
+// writerFunc is an io.Writer that hands each write to a function.
+type writerFunc func(p []byte)
+
+func (f writerFunc) Write(p []byte) (int, error) {
+	f(p)
+	return len(p), nil
+}
+
+func TestStoreDropsPartialRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Put(StoreKey{Hash: 1, Depth: 3}, CacheValue{Score: 5})
+	s.Close()
+
+	path := filepath.Join(dir, storeFileName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"v":1,"h":2,"d":`)
+	f.Close()
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(StoreKey{Hash: 3, Depth: 3}, CacheValue{Score: 6}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = OpenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 2 {
+		t.Errorf("Len = %d, want 2", s.Len())
+	}
+	if got, ok := s.Get(StoreKey{Hash: 3, Depth: 3}); !ok || got.Score != 6 {
+		t.Errorf("record appended after the partial line = %+v, %v", got, ok)
+	}
+}
+
+func TestStoreClosed(t *testing.T) {
+	s, err := OpenStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := StoreKey{Hash: 1, Depth: 2}
+	s.Put(key, CacheValue{Score: 3})
+	s.Close()
+
+	if err := s.Put(StoreKey{Hash: 2, Depth: 2}, CacheValue{}); err != ErrStoreClosed {
+		t.Errorf("Put after Close = %v, want ErrStoreClosed", err)
+	}
+	if _, ok := s.Get(key); !ok {
+		t.Error("Get after Close lost the in-memory index")
+	}
+
+	var nilStore *Store
+	if err := nilStore.Put(key, CacheValue{}); err != nil {
+		t.Errorf("nil Put = %v", err)
+	}
+	if _, ok := nilStore.Get(key); ok {
+		t.Error("nil Get found a result")
+	}
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, b := range data {
+		if b == '\n' {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/g0g05arui/chess-engine/book"
+	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/joho/godotenv"
+)
+
+// Config is the server's configuration. Each setting comes from, in increasing order of
+// precedence: its default, the JSON config file, the environment (including an optional
+// .env file) and the command line.
+type Config struct {
+	Port int    `json:"port"`
+	Bind string `json:"bind"` // address to listen on; all interfaces when empty
+
+	GRPCPort int `json:"grpc_port"` // port of the gRPC service on the same address; none when 0
+
+	ReadTimeoutSeconds     int `json:"read_timeout_seconds"`
+	WriteTimeoutSeconds    int `json:"write_timeout_seconds"` // streaming endpoints are exempt
+	IdleTimeoutSeconds     int `json:"idle_timeout_seconds"`
+	SearchTimeoutSeconds   int `json:"search_timeout_seconds"` // deadline of each search a request runs
+	ShutdownTimeoutSeconds int `json:"shutdown_timeout_seconds"`
+
+	DefaultDepth  int `json:"default_depth"`    // depth /best-move answers with straight away
+	MaxDepth      int `json:"max_depth"`        // deepest search any request or background job may run
+	MaxMoveTimeMS int `json:"max_move_time_ms"` // longest /best-move/stream search; other searches stop at the lower of this and search_timeout_seconds
+	Workers       int `json:"workers"`          // goroutines per request search
+
+	CacheCapacity     int    `json:"cache_capacity"`
+	DeepCacheCapacity int    `json:"deep_cache_capacity"`
+	AnalysisStoreDir  string `json:"analysis_store_dir"`
+
+	JobWorkers        int `json:"job_workers"`
+	JobQueueLimit     int `json:"job_queue_limit"`
+	JobTimeoutSeconds int `json:"job_timeout_seconds"`
+	JobSearchWorkers  int `json:"job_search_workers"` // goroutines per background or batch search
+
+	MaxGames         int `json:"max_games"`
+	BatchConcurrency int `json:"batch_concurrency"`
+
+	NNUEFile       string `json:"nnue_file"`
+	EvalProfile    string `json:"eval_profile"`
+	EvalProfileDir string `json:"eval_profile_dir"`
+
+	BookFile      string `json:"book_file"`      // Polyglot opening book; none when empty
+	BookDepth     int    `json:"book_depth"`     // plies from the start the book is used for; no limit when 0
+	BookSelection string `json:"book_selection"` // weighted or best
+
+	APIKeysFile    string   `json:"api_keys_file"`   // see package access; no authentication when empty
+	CORSOrigins    []string `json:"cors_origins"`    // "*" allows any origin
+	TrustedProxies []string `json:"trusted_proxies"` // whose X-Forwarded-For is believed; none when empty
+	LogLevel       string   `json:"log_level"`       // debug, info, warn or error
+	LogFormat      string   `json:"log_format"`      // text or json
+}
+
+func defaultConfig() Config {
+	return Config{
+		Port:                   8080,
+		ReadTimeoutSeconds:     30,
+		WriteTimeoutSeconds:    60,
+		IdleTimeoutSeconds:     120,
+		SearchTimeoutSeconds:   30,
+		ShutdownTimeoutSeconds: 30,
+		DefaultDepth:           4,
+		MaxDepth:               8,
+		MaxMoveTimeMS:          5 * 60 * 1000,
+		Workers:                11,
+		CacheCapacity:          computed.DefaultCacheCapacity,
+		DeepCacheCapacity:      computed.DefaultCacheCapacity,
+		JobWorkers:             2,
+		JobQueueLimit:          256,
+		JobTimeoutSeconds:      300,
+		JobSearchWorkers:       4,
+		MaxGames:               1000,
+		BatchConcurrency:       4,
+		BookDepth:              20,
+		BookSelection:          string(book.Weighted),
+		LogLevel:               "info",
+		LogFormat:              "text",
+	}
+}
+
+// setting ties a Config field to its environment variable and flag. The flag name is the
+// JSON key with dashes.
+type setting struct {
+	key   string
+	env   string
+	usage string
+	field func(*Config) any // pointer to the field
+}
+
+var settings = []setting{
+	{"port", "PORT", "HTTP port", func(c *Config) any { return &c.Port }},
+	{"bind", "BIND_ADDRESS", "address to listen on, all interfaces when empty", func(c *Config) any { return &c.Bind }},
+	{"grpc_port", "GRPC_PORT", "gRPC port, no gRPC service when 0", func(c *Config) any { return &c.GRPCPort }},
+	{"read_timeout_seconds", "READ_TIMEOUT_SECONDS", "time to read a request", func(c *Config) any { return &c.ReadTimeoutSeconds }},
+	{"write_timeout_seconds", "WRITE_TIMEOUT_SECONDS", "time to answer a request, streams excepted", func(c *Config) any { return &c.WriteTimeoutSeconds }},
+	{"idle_timeout_seconds", "IDLE_TIMEOUT_SECONDS", "time a keep-alive connection may sit idle", func(c *Config) any { return &c.IdleTimeoutSeconds }},
+	{"search_timeout_seconds", "SEARCH_TIMEOUT_SECONDS", "deadline of each search a request runs", func(c *Config) any { return &c.SearchTimeoutSeconds }},
+	{"shutdown_timeout_seconds", "SHUTDOWN_TIMEOUT_SECONDS", "time in-flight requests get to finish on SIGTERM", func(c *Config) any { return &c.ShutdownTimeoutSeconds }},
+	{"default_depth", "DEFAULT_DEPTH", "depth /best-move answers with straight away", func(c *Config) any { return &c.DefaultDepth }},
+	{"max_depth", "MAX_DEPTH", "deepest search a request or background job may run", func(c *Config) any { return &c.MaxDepth }},
+	{"max_move_time_ms", "MAX_MOVE_TIME_MS", "longest streamed search in milliseconds, also capping other searches", func(c *Config) any { return &c.MaxMoveTimeMS }},
+	{"workers", "SEARCH_WORKERS", "goroutines per request search", func(c *Config) any { return &c.Workers }},
+	{"cache_capacity", "CACHE_CAPACITY", "entries in the default-depth result cache", func(c *Config) any { return &c.CacheCapacity }},
+	{"deep_cache_capacity", "DEEP_CACHE_CAPACITY", "entries in the deeper result cache", func(c *Config) any { return &c.DeepCacheCapacity }},
+	{"analysis_store_dir", "ANALYSIS_STORE_DIR", "directory of the on-disk result store, none when empty", func(c *Config) any { return &c.AnalysisStoreDir }},
+	{"job_workers", "JOB_WORKERS", "background searches run at once", func(c *Config) any { return &c.JobWorkers }},
+	{"job_queue_limit", "JOB_QUEUE_LIMIT", "background searches waiting to run", func(c *Config) any { return &c.JobQueueLimit }},
+	{"job_timeout_seconds", "JOB_TIMEOUT_SECONDS", "time limit per background search, none when 0", func(c *Config) any { return &c.JobTimeoutSeconds }},
+	{"job_search_workers", "JOB_SEARCH_WORKERS", "goroutines per background or batch search", func(c *Config) any { return &c.JobSearchWorkers }},
+	{"max_games", "MAX_GAMES", "game sessions kept in memory", func(c *Config) any { return &c.MaxGames }},
+	{"batch_concurrency", "BATCH_CONCURRENCY", "positions searched at once across all batches and reviews", func(c *Config) any { return &c.BatchConcurrency }},
+	{"nnue_file", "NNUE_FILE", "NNUE weights, handcrafted evaluation when empty", func(c *Config) any { return &c.NNUEFile }},
+	{"eval_profile", "EVAL_PROFILE", "evaluation profile JSON to use by default", func(c *Config) any { return &c.EvalProfile }},
+	{"eval_profile_dir", "EVAL_PROFILE_DIR", "directory of named evaluation profiles", func(c *Config) any { return &c.EvalProfileDir }},
+	{"book_file", "BOOK_FILE", "Polyglot opening book (.bin), none when empty", func(c *Config) any { return &c.BookFile }},
+	{"book_depth", "BOOK_DEPTH", "plies from the start of a game the book is used for, no limit when 0", func(c *Config) any { return &c.BookDepth }},
+	{"book_selection", "BOOK_SELECTION", "how book moves are chosen: weighted (at random by weight) or best", func(c *Config) any { return &c.BookSelection }},
+	{"api_keys_file", "API_KEYS_FILE", "JSON file of API keys and their limits, open access when empty", func(c *Config) any { return &c.APIKeysFile }},
+	{"cors_origins", "CORS_ORIGINS", "comma-separated origins allowed by CORS, * for any", func(c *Config) any { return &c.CORSOrigins }},
+	{"trusted_proxies", "TRUSTED_PROXIES", "comma-separated proxy addresses or CIDRs whose X-Forwarded-For is trusted, none when empty", func(c *Config) any { return &c.TrustedProxies }},
+	{"log_level", "LOG_LEVEL", "debug, info, warn or error", func(c *Config) any { return &c.LogLevel }},
+	{"log_format", "LOG_FORMAT", "text or json", func(c *Config) any { return &c.LogFormat }},
+}
+
+// loadConfig builds the configuration from args (without the program name) and the
+// environment. printOnly is set when --print-config asks for a dump instead of a server.
+func loadConfig(args []string) (cfg Config, printOnly bool, err error) {
+	// a missing .env is fine; variables already in the environment win over it
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return cfg, false, fmt.Errorf(".env: %w", err)
+	}
+
+	// flags are parsed into a scratch Config and copied over only where given
+	var fromFlags Config
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON config file (env CONFIG_FILE)")
+	fs.BoolVar(&printOnly, "print-config", false, "print the resolved configuration as JSON and exit")
+	for _, s := range settings {
+		name := strings.ReplaceAll(s.key, "_", "-")
+		usage := fmt.Sprintf("%s (env %s)", s.usage, s.env)
+		switch p := s.field(&fromFlags).(type) {
+		case *int:
+			fs.IntVar(p, name, 0, usage)
+		case *string:
+			fs.StringVar(p, name, "", usage)
+		case *[]string:
+			fs.Func(name, usage, func(v string) error {
+				*p = splitList(v)
+				return nil
+			})
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return cfg, false, err
+	}
+
+	cfg = defaultConfig()
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return cfg, false, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return cfg, false, err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if strings.ReplaceAll(s.key, "_", "-") != f.Name {
+				continue
+			}
+			switch p := s.field(&cfg).(type) {
+			case *int:
+				*p = *s.field(&fromFlags).(*int)
+			case *string:
+				*p = *s.field(&fromFlags).(*string)
+			case *[]string:
+				*p = *s.field(&fromFlags).(*[]string)
+			}
+		}
+	})
+	return cfg, printOnly, cfg.validate()
+}
+
+// loadFile overlays the settings present in a JSON file; unknown keys are an error.
+func (cfg *Config) loadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+func (cfg *Config) loadEnv() error {
+	for _, s := range settings {
+		v, ok := os.LookupEnv(s.env)
+		if !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch p := s.field(cfg).(type) {
+		case *int:
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", s.env, v)
+			}
+			*p = n
+		case *string:
+			*p = v
+		case *[]string:
+			*p = splitList(v)
+		}
+	}
+	return nil
+}
+
+func (cfg Config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	check(cfg.Port >= 1 && cfg.Port <= 65535, "port must be between 1 and 65535")
+	check(cfg.GRPCPort >= 0 && cfg.GRPCPort <= 65535 && cfg.GRPCPort != cfg.Port, "grpc_port must be between 1 and 65535 and differ from port, or 0")
+	check(cfg.ReadTimeoutSeconds >= 1, "read_timeout_seconds must be at least 1")
+	check(cfg.SearchTimeoutSeconds >= 1, "search_timeout_seconds must be at least 1")
+	check(cfg.WriteTimeoutSeconds > cfg.SearchTimeoutSeconds, "write_timeout_seconds must be longer than search_timeout_seconds")
+	check(cfg.IdleTimeoutSeconds >= 1, "idle_timeout_seconds must be at least 1")
+	check(cfg.ShutdownTimeoutSeconds >= 0, "shutdown_timeout_seconds must not be negative")
+	check(cfg.DefaultDepth >= 1, "default_depth must be at least 1")
+	check(cfg.MaxDepth >= cfg.DefaultDepth && cfg.MaxDepth <= 20, "max_depth must be between default_depth and 20")
+	check(cfg.MaxMoveTimeMS >= 1, "max_move_time_ms must be positive")
+	check(cfg.Workers >= 1, "workers must be at least 1")
+	check(cfg.CacheCapacity >= 1, "cache_capacity must be at least 1")
+	check(cfg.DeepCacheCapacity >= 1, "deep_cache_capacity must be at least 1")
+	check(cfg.JobWorkers >= 1, "job_workers must be at least 1")
+	check(cfg.JobQueueLimit >= 1, "job_queue_limit must be at least 1")
+	check(cfg.JobTimeoutSeconds >= 0, "job_timeout_seconds must not be negative")
+	check(cfg.JobSearchWorkers >= 1, "job_search_workers must be at least 1")
+	check(cfg.MaxGames >= 1, "max_games must be at least 1")
+	check(cfg.BatchConcurrency >= 1, "batch_concurrency must be at least 1")
+	check(cfg.BookDepth >= 0, "book_depth must not be negative")
+	check(cfg.BookSelection == string(book.Weighted) || cfg.BookSelection == string(book.Best), "book_selection must be weighted or best")
+	for _, p := range cfg.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(p)
+		check(cidrErr == nil || net.ParseIP(p) != nil, "trusted_proxies: %q is not an IP address or CIDR", p)
+	}
+	_, levelErr := parseLogLevel(cfg.LogLevel)
+	check(levelErr == nil, "log_level must be debug, info, warn or error")
+	check(cfg.LogFormat == "text" || cfg.LogFormat == "json", "log_format must be text or json")
+	return errors.Join(errs...)
+}
+
+// Addr is the listen address for net/http.
+func (cfg Config) Addr() string {
+	return net.JoinHostPort(cfg.Bind, strconv.Itoa(cfg.Port))
+}
+
+// GRPCAddr is the listen address of the gRPC service.
+func (cfg Config) GRPCAddr() string {
+	return net.JoinHostPort(cfg.Bind, strconv.Itoa(cfg.GRPCPort))
+}
+
+func (cfg Config) print(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
+
+// newLogger builds the server's slog logger from the log settings.
+func (cfg Config) newLogger(w io.Writer) *slog.Logger {
+	level, _ := parseLogLevel(cfg.LogLevel)
+	opts := &slog.HandlerOptions{Level: level}
+	if cfg.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// isolateConfig runs the test in an empty directory, so no .env is picked up, with every
+// setting's environment variable unset.
+func isolateConfig(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	for _, env := range append([]string{"CONFIG_FILE"}, settingEnvs()...) {
+		if old, ok := os.LookupEnv(env); ok {
+			os.Unsetenv(env)
+			t.Cleanup(func() { os.Setenv(env, old) })
+		}
+	}
+}
+
+func settingEnvs() []string {
+	var envs []string
+	for _, s := range settings {
+		envs = append(envs, s.env)
+	}
+	return envs
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string // config file content, none when empty
+		dotenv   string // .env content, none when empty
+		env      map[string]string
+		args     []string
+		wantPort int
+		wantBind string
+	}{
+		{name: "default", wantPort: 8080},
+		{name: "file over default", file: `{"port": 9001, "bind": "127.0.0.1"}`, wantPort: 9001, wantBind: "127.0.0.1"},
+		{name: "env over file", file: `{"port": 9001, "bind": "127.0.0.1"}`, env: map[string]string{"PORT": "9002"}, wantPort: 9002, wantBind: "127.0.0.1"},
+		{name: "dotenv over file", file: `{"port": 9001}`, dotenv: "PORT=9003\n", wantPort: 9003},
+		{name: "environment over dotenv", dotenv: "PORT=9003\n", env: map[string]string{"PORT": "9002"}, wantPort: 9002},
+		{name: "flag over env", file: `{"port": 9001}`, env: map[string]string{"PORT": "9002"}, args: []string{"-port", "9004"}, wantPort: 9004},
+		{name: "flag set to its zero value", env: map[string]string{"BIND_ADDRESS": "127.0.0.1"}, args: []string{"-bind", ""}, wantPort: 8080},
+		{name: "unset flag leaves env", env: map[string]string{"PORT": "9002"}, args: []string{"-bind", "::1"}, wantPort: 9002, wantBind: "::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateConfig(t)
+			if tt.dotenv != "" {
+				if err := os.WriteFile(".env", []byte(tt.dotenv), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { os.Unsetenv("PORT") })
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, "config.json", tt.file)}, args...)
+			}
+
+			cfg, _, err := loadConfig(args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Port != tt.wantPort || cfg.Bind != tt.wantBind {
+				t.Errorf("port %d, bind %q; want %d, %q", cfg.Port, cfg.Bind, tt.wantPort, tt.wantBind)
+			}
+		})
+	}
+}
+
+func TestLoadConfigSources(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "config.json", `{"cors_origins": ["https://a.example"], "max_depth": 10}`))
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.0/8 , 192.168.1.1 ")
+
+	cfg, printOnly, err := loadConfig([]string{"-cors-origins", "https://b.example,https://c.example", "-print-config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !printOnly {
+		t.Error("-print-config was not reported")
+	}
+	if cfg.MaxDepth != 10 {
+		t.Errorf("max_depth from CONFIG_FILE = %d, want 10", cfg.MaxDepth)
+	}
+	if want := []string{"10.0.0.0/8", "192.168.1.1"}; !slices.Equal(cfg.TrustedProxies, want) {
+		t.Errorf("trusted_proxies = %q, want %q", cfg.TrustedProxies, want)
+	}
+	if want := []string{"https://b.example", "https://c.example"}; !slices.Equal(cfg.CORSOrigins, want) {
+		t.Errorf("cors_origins = %q, want %q", cfg.CORSOrigins, want)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{name: "unknown file key", file: `{"prot": 80}`, wantErr: "unknown field"},
+		{name: "env not a number", env: map[string]string{"MAX_DEPTH": "deep"}, wantErr: "MAX_DEPTH"},
+		{name: "unknown flag", args: []string{"-nope"}, wantErr: "nope"},
+		{name: "invalid value", args: []string{"-port", "70000"}, wantErr: "port must be between"},
+		{name: "depth below default", env: map[string]string{"DEFAULT_DEPTH": "6", "MAX_DEPTH": "5"}, wantErr: "max_depth"},
+		{name: "bad proxy", args: []string{"-trusted-proxies", "proxy.local"}, wantErr: "proxy.local"},
+		{name: "every problem reported", args: []string{"-workers", "0", "-log-format", "xml"}, wantErr: "workers must be at least 1\nlog_format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateConfig(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeConfigFile(t, "config.json", tt.file)}, args...)
+			}
+			_, _, err := loadConfig(args)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("loadConfig = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,5 @@
+// Package enginepb holds the protobuf messages and gRPC service of the engine, generated
+// from engine.proto. The server implements the service when GRPC_PORT is set.
+package enginepb
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative enginepb/engine.proto
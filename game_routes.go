@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/access"
+	"github.com/g0g05arui/chess-engine/book"
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/games"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultGameDepth = 4
+	maxGameDepth     = 6
+)
+
+type searchOptsFunc func(profile string) ([]game_state.SearchOption, error)
+
+type newGameRequest struct {
+	FEN         string `json:"fen"`
+	Depth       int    `json:"depth"`
+	Profile     string `json:"profile"`
+	EngineColor string `json:"engine_color"`
+}
+
+type moveRequest struct {
+	Move  string `json:"move"`
+	Reply bool   `json:"reply"` // have the engine answer even if it does not play a side
+}
+
+type resignRequest struct {
+	Color string `json:"color"`
+}
+
+// registerGameRoutes serves the /games resource, with engine depths up to maxDepth and each
+// engine move taken from openingBook, which may be nil, or else searched within searchTimeout
+// and the client's share of maxMoveTimeMS.
+func registerGameRoutes(r *gin.Engine, store *games.Store, optsFor searchOptsFunc, maxDepth int, searchTimeout time.Duration, maxMoveTimeMS int, openingBook *book.Book) {
+	engineMove := func(ctx context.Context, g *games.Game, client *access.Client) (games.Ply, error) {
+		opts, err := optsFor(g.Settings.Profile)
+		if err != nil {
+			return games.Ply{}, err
+		}
+		ctx, cancel := context.WithTimeout(ctx, searchTimeoutFor(client, searchTimeout, maxMoveTimeMS))
+		defer cancel()
+		opts = append(slices.Clip(opts), game_state.WithContext(ctx))
+		return g.PlayEngine(func(t games.Turn) (game_state.Move, error) {
+			if move, ok := openingBook.Pick(t.Board, book.Key(t.Board, t.LastMove), book.Ply(t.Fullmove, t.Color)); ok {
+				return move, nil
+			}
+			move, _ := game_state.BestMove(t.Board, g.Settings.Depth, t.Color, opts...)
+			if ctx.Err() != nil {
+				return game_state.Move{}, searchErr(ctx)
+			}
+			return move, nil
+		})
+	}
+
+	// getGame finds the game named in the path. Other keys' games are not found, so ids
+	// cannot be probed.
+	getGame := func(c *gin.Context) (*games.Game, error) {
+		g, err := store.Get(c.Param("id"))
+		if err != nil {
+			return nil, err
+		}
+		if g.Owner != clientFrom(c).ID() {
+			return nil, games.ErrNotFound
+		}
+		return g, nil
+	}
+
+	r.POST("/games", func(c *gin.Context) {
+		var req newGameRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.Depth == 0 {
+			req.Depth = min(defaultGameDepth, clientFrom(c).MaxDepth(maxDepth))
+		}
+		if maxDepth := clientFrom(c).MaxDepth(maxDepth); req.Depth < 1 || req.Depth > maxDepth {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("depth must be between 1 and %d", maxDepth)})
+			return
+		}
+		if req.EngineColor != "" && req.EngineColor != "white" && req.EngineColor != "black" {
+			c.JSON(400, gin.H{"error": "engine_color must be white, black or empty"})
+			return
+		}
+		if _, err := optsFor(req.Profile); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		g, err := store.Create(req.FEN, games.Settings{Depth: req.Depth, Profile: req.Profile, EngineColor: req.EngineColor}, clientFrom(c).ID())
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if _, ongoing := g.Board(); ongoing && g.EngineToMove() {
+			if _, err := engineMove(c.Request.Context(), g, clientFrom(c)); err != nil {
+				// the client never learns the id, so the game would only take up room
+				store.Delete(g.ID)
+				gameError(c, err)
+				return
+			}
+		}
+		c.JSON(201, g.Snapshot())
+	})
+
+	r.GET("/games/:id", func(c *gin.Context) {
+		g, err := getGame(c)
+		if err != nil {
+			gameError(c, err)
+			return
+		}
+		c.JSON(200, g.Snapshot())
+	})
+
+	r.DELETE("/games/:id", func(c *gin.Context) {
+		g, err := getGame(c)
+		if err != nil || !store.Delete(g.ID) {
+			gameError(c, games.ErrNotFound)
+			return
+		}
+		c.Status(204)
+	})
+
+	// Plays a human move; the engine answers when it plays the side now to move or when asked to
+	r.POST("/games/:id/moves", func(c *gin.Context) {
+		g, err := getGame(c)
+		if err != nil {
+			gameError(c, err)
+			return
+		}
+		var req moveRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Move == "" {
+			c.JSON(400, gin.H{"error": "expected {\"move\": \"e2e4\"} in UCI or SAN"})
+			return
+		}
+
+		played, err := g.Play(req.Move)
+		if err != nil {
+			gameError(c, err)
+			return
+		}
+		resp := gin.H{"move": played}
+
+		if _, ongoing := g.Board(); ongoing && (req.Reply || g.EngineToMove()) {
+			reply, err := engineMove(c.Request.Context(), g, clientFrom(c))
+			if err != nil {
+				// take the move back, or the game would be left waiting on an engine
+				// move nothing asks for again
+				g.Retract(played)
+				gameError(c, err)
+				return
+			}
+			resp["reply"] = reply
+		}
+		resp["game"] = g.Snapshot()
+		c.JSON(200, resp)
+	})
+
+	// Takes back ?plies=n moves, or back to the human's turn by default
+	r.POST("/games/:id/undo", func(c *gin.Context) {
+		g, err := getGame(c)
+		if err != nil {
+			gameError(c, err)
+			return
+		}
+		if plies := c.Query("plies"); plies != "" {
+			n, convErr := strconv.Atoi(plies)
+			if convErr != nil {
+				c.JSON(400, gin.H{"error": "plies must be a number"})
+				return
+			}
+			err = g.Undo(n)
+		} else {
+			err = g.UndoToHuman()
+		}
+		if err != nil {
+			gameError(c, err)
+			return
+		}
+		c.JSON(200, g.Snapshot())
+	})
+
+	// The human resigns; the color is required when the engine plays neither side
+	r.POST("/games/:id/resign", func(c *gin.Context) {
+		g, err := getGame(c)
+		if err != nil {
+			gameError(c, err)
+			return
+		}
+		var req resignRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		if req.Color == "" {
+			switch g.Settings.EngineColor {
+			case "white":
+				req.Color = "black"
+			case "black":
+				req.Color = "white"
+			}
+		}
+		if err := g.Resign(req.Color); err != nil {
+			gameError(c, err)
+			return
+		}
+		c.JSON(200, g.Snapshot())
+	})
+}
+
+func gameError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, games.ErrNotFound):
+		c.JSON(404, gin.H{"error": err.Error()})
+	case errors.Is(err, games.ErrGameOver), errors.Is(err, games.ErrEngineTurn), errors.Is(err, games.ErrUndoEngine):
+		c.JSON(409, gin.H{"error": err.Error()})
+	case errors.Is(err, errSearchTimeout):
+		c.JSON(503, gin.H{"error": err.Error()})
+	default:
+		c.JSON(400, gin.H{"error": err.Error()})
+	}
+}
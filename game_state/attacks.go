@@ -0,0 +1,97 @@
+package game_state
+
+var knightOffsets = []struct{ dc, dl int8 }{
+	{1, 2}, {2, 1}, {2, -1}, {1, -2},
+	{-1, -2}, {-2, -1}, {-2, 1}, {-1, 2},
+}
+
+var kingOffsets = []struct{ dc, dl int8 }{
+	{1, 0}, {-1, 0}, {0, 1}, {0, -1},
+	{1, 1}, {-1, -1}, {-1, 1}, {1, -1},
+}
+
+var rookDirections = []struct{ dc, dl int8 }{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+var bishopDirections = []struct{ dc, dl int8 }{{1, 1}, {-1, -1}, {-1, 1}, {1, -1}}
+
+func onBoard(l, c int8) bool {
+	return l >= 1 && l <= 8 && c >= 1 && c <= 8
+}
+
+// pieceAttacks returns every square the piece attacks. Unlike GenerateAllVisiblePositions
+// it includes squares held by friendly pieces and excludes quiet pawn pushes.
+func pieceAttacks(piece Piece, board Board) []Position {
+	var attacks []Position
+	l, c := piece.Pos.Line, piece.Pos.Column
+
+	addOffsets := func(offsets []struct{ dc, dl int8 }) {
+		for _, o := range offsets {
+			if onBoard(l+o.dl, c+o.dc) {
+				attacks = append(attacks, Position{Line: l + o.dl, Column: c + o.dc})
+			}
+		}
+	}
+	addRays := func(directions []struct{ dc, dl int8 }) {
+		for _, d := range directions {
+			for nl, nc := l+d.dl, c+d.dc; onBoard(nl, nc); nl, nc = nl+d.dl, nc+d.dc {
+				attacks = append(attacks, Position{Line: nl, Column: nc})
+				if board.PiecesMatrix[nl][nc].Type != 0 {
+					break
+				}
+			}
+		}
+	}
+
+	switch piece.Type {
+	case Pawn:
+		dir := pawnDirection(piece.Color)
+		for _, dc := range []int8{-1, 1} {
+			if onBoard(l+dir, c+dc) {
+				attacks = append(attacks, Position{Line: l + dir, Column: c + dc})
+			}
+		}
+	case Knight:
+		addOffsets(knightOffsets)
+	case King:
+		addOffsets(kingOffsets)
+	case Bishop:
+		addRays(bishopDirections)
+	case Rook:
+		addRays(rookDirections)
+	case Queen:
+		addRays(rookDirections)
+		addRays(bishopDirections)
+	}
+
+	return attacks
+}
+
+// attackInfo counts, per square, how many pieces of one color attack it.
+type attackInfo struct {
+	all   [9][9]int8
+	pawns [9][9]bool
+}
+
+func computeAttacks(board Board, color PieceColor) attackInfo {
+	var info attackInfo
+	for _, p := range board.PiecesSlice {
+		if p.Color != color {
+			continue
+		}
+		for _, pos := range pieceAttacks(p, board) {
+			info.all[pos.Line][pos.Column]++
+			if p.Type == Pawn {
+				info.pawns[pos.Line][pos.Column] = true
+			}
+		}
+	}
+	return info
+}
+
+func findKing(board Board, color PieceColor) (Piece, bool) {
+	for _, p := range board.PiecesSlice {
+		if p.Type == King && p.Color == color {
+			return p, true
+		}
+	}
+	return Piece{}, false
+}
@@ -7,6 +7,7 @@ type Board = struct {
 	PiecesMatrix [9][9]Piece
 	WhiteTurn    bool
 	Played       map[string]int //fen counter
+	nnue         *nnueAccumulator
 }
 
 func CreateBoard() Board {
@@ -109,14 +110,14 @@ func BoardToFEN(board Board) string {
 				emptyCount++
 			} else {
 				if emptyCount > 0 {
-					sb.WriteString(string('0' + emptyCount))
+					sb.WriteString(string(rune('0' + emptyCount)))
 					emptyCount = 0
 				}
 				sb.WriteString(PieceToFENChar(p))
 			}
 		}
 		if emptyCount > 0 {
-			sb.WriteString(string('0' + emptyCount))
+			sb.WriteString(string(rune('0' + emptyCount)))
 		}
 		if rank > 1 {
 			sb.WriteString("/")
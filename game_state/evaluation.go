@@ -1,72 +1,173 @@
 package game_state
 
-var pieceValue = map[PieceType]int{
-	Pawn:   100,
-	Knight: 320,
-	Bishop: 330,
-	Rook:   500,
-	Queen:  900,
-	King:   0,
+const (
+	INF = 100000 // High value for checkmate, but not so high it causes overflow
+)
+
+// Game phase weights: 24 with all minor and major pieces on the board, 0 in a pawn endgame.
+var phaseWeight = map[PieceType]int{
+	Knight: 1,
+	Bishop: 1,
+	Rook:   2,
+	Queen:  4,
+}
+
+const maxPhase = 24
+
+// PhaseScore is a term's middlegame and endgame value in centipawns.
+type PhaseScore struct {
+	MG int `json:"mg"`
+	EG int `json:"eg"`
+}
+
+func (s PhaseScore) add(o PhaseScore) PhaseScore {
+	return PhaseScore{s.MG + o.MG, s.EG + o.EG}
+}
+
+func (s PhaseScore) scale(k int) PhaseScore {
+	return PhaseScore{s.MG * k, s.EG * k}
+}
+
+func (s PhaseScore) percent(p int) PhaseScore {
+	return PhaseScore{s.MG * p / 100, s.EG * p / 100}
 }
 
+// taper interpolates between the middlegame and endgame values by phase.
+func (s PhaseScore) taper(phase int) int {
+	return (s.MG*phase + s.EG*(maxPhase-phase)) / maxPhase
+}
+
+func gamePhase(board Board) int {
+	phase := 0
+	for _, p := range board.PiecesSlice {
+		phase += phaseWeight[p.Type]
+	}
+	if phase > maxPhase {
+		phase = maxPhase
+	}
+	return phase
+}
+
+// Evaluation terms, in the order they are reported by EvaluateTrace.
 const (
-	INF = 100000 // High value for checkmate, but not so high it causes overflow
+	termMaterial = iota
+	termCenter
+	termThreats
+	termMobility
+	termPieces
+	termPawns
+	termPassedPawns
+	termKingSafety
+	numEvalTerms
 )
 
+var evalTermNames = [numEvalTerms]string{
+	"material", "center", "threats", "mobility", "pieces", "pawns", "passed_pawns", "king_safety",
+}
+
+// evalBreakdown holds every term for each color, from that color's point of view.
+type evalBreakdown [numEvalTerms][2]PhaseScore
+
+// total returns the white-relative score tapered by phase.
+func (b *evalBreakdown) total(phase int) int {
+	var sum PhaseScore
+	for _, t := range b {
+		sum = sum.add(t[WhiteColor]).add(t[BlackColor].scale(-1))
+	}
+	return sum.taper(phase)
+}
+
+func untapered(v int) PhaseScore {
+	return PhaseScore{v, v}
+}
+
 func Evaluate(board Board, sideToMove PieceColor) int {
+	return EvaluateWithParams(board, sideToMove, ActiveEvalParams())
+}
+
+// EvaluateWithParams is Evaluate with explicit weights instead of the active ones.
+func EvaluateWithParams(board Board, sideToMove PieceColor, params *EvalParams) int {
+	if score, over := terminalScore(board, sideToMove); over {
+		return score
+	}
+
+	terms := evaluateTerms(board, params)
+	score := terms.total(gamePhase(board))
+
+	if sideToMove == BlackColor {
+		score = -score
+	}
+	return score
+}
+
+// terminalScore scores checkmate, stalemate and threefold repetition.
+func terminalScore(board Board, sideToMove PieceColor) (int, bool) {
 	// Check for checkmate or stalemate
 	hasLegalMoves := HasLegalMoves(board, sideToMove)
 	if !hasLegalMoves {
 		if IsKingInCheck(board, sideToMove) {
-			// Checkmate - return INF or -INF depending on who is mated
-			if sideToMove == WhiteColor {
-				return -INF
-			}
-			return INF
+			// Checkmate - the side to move has lost
+			return -INF, true
 		}
-		return 0 // Stalemate
+		return 0, true // Stalemate
 	}
 
 	// Check for threefold repetition (position played twice already)
 	fen := BoardToFEN(board)
 	if count, exists := board.Played[fen]; exists && count >= 2 {
-		return 0
+		return 0, true
 	}
+	return 0, false
+}
 
-	score := 0
-	const CENTER_VALUE_MULTIPLIER float32 = 0.05
-	const ATTACK_VISIBILITY_MULTIPLIER int = 15 // percent-based scaling
+// StaticEvaluate returns the white-relative evaluation with the given weights, without
+// checking for mate or repetition. It is meant for tuning on quiet positions.
+func StaticEvaluate(board Board, params *EvalParams) int {
+	terms := evaluateTerms(board, params)
+	return terms.total(gamePhase(board))
+}
+
+func evaluateTerms(board Board, params *EvalParams) evalBreakdown {
+	var terms evalBreakdown
+
+	attacks := [2]attackInfo{computeAttacks(board, WhiteColor), computeAttacks(board, BlackColor)}
 
 	for _, p := range board.PiecesSlice {
-		v := pieceValue[p.Type]
+		v := params.PieceValues.of(p.Type)
 
 		// Central control bonus for pawns and knights
 		if p.Pos.Line >= 4 && p.Pos.Line <= 5 && p.Pos.Column >= 3 && p.Pos.Column <= 6 &&
 			(p.Type == Pawn || p.Type == Knight) {
-			score += int(CENTER_VALUE_MULTIPLIER*float32(v)) * getSign(p.Color)
+			terms[termCenter][p.Color] = terms[termCenter][p.Color].add(untapered(int(params.CenterValueMultiplier * float32(v))))
 		}
 
 		// Base material score
-		score += v * getSign(p.Color)
+		terms[termMaterial][p.Color] = terms[termMaterial][p.Color].add(untapered(v))
 
-		// Attack value bonus for Rook, Bishop, Knight
-		if p.Type == Rook || p.Type == Bishop || p.Type == Knight {
+		if p.Type == Rook || p.Type == Bishop || p.Type == Knight || p.Type == Queen {
 			visible := GenerateAllVisiblePositions(p, board)
+			terms[termMobility][p.Color] = terms[termMobility][p.Color].add(pieceMobility(p, visible, attacks[opposite(p.Color)], params))
+
+			// Attack value bonus for Rook, Bishop, Knight
+			if p.Type == Queen {
+				continue
+			}
 			for _, pos := range visible {
 				target, found := _Find_Piece_By_Pos(pos, board)
 				if found && target.Color != p.Color {
-					targetValue := pieceValue[target.Type]
-					bonus := (targetValue * ATTACK_VISIBILITY_MULTIPLIER) / 100
-					score += bonus * getSign(p.Color)
+					targetValue := params.PieceValues.of(target.Type)
+					bonus := (targetValue * params.AttackVisibilityMultiplier) / 100
+					terms[termThreats][p.Color] = terms[termThreats][p.Color].add(untapered(bonus))
 				}
 			}
 		}
 	}
 
-	if sideToMove == BlackColor {
-		score = -score
-	}
-	return score
+	terms[termPieces] = evaluatePieceActivity(board, params)
+	terms[termPawns], terms[termPassedPawns] = evaluatePawns(board, params)
+	terms[termKingSafety] = evaluateKingSafety(board, attacks, params)
+
+	return terms
 }
 
 func getSign(color PieceColor) int {
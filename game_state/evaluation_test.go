@@ -0,0 +1,71 @@
+package game_state
+
+import "testing"
+
+func TestEvaluateTerminalFromSideToMove(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int
+	}{
+		{"white mated", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w - - 1 3", -INF},
+		{"black mated", "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1", -INF},
+		{"stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Evaluate(board, SideToMove(board)); got != tt.want {
+				t.Errorf("Evaluate = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestMoveFindsMateInOne(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want Move
+	}{
+		{"white mates", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", Move{From: Position{Line: 1, Column: 1}, To: Position{Line: 8, Column: 1}}},
+		{"black mates", "r5k1/8/8/8/8/8/5PPP/6K1 b - - 0 1", Move{From: Position{Line: 8, Column: 1}, To: Position{Line: 1, Column: 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			move, score := BestMove(board, 2, SideToMove(board))
+			if move != tt.want {
+				t.Errorf("BestMove = %v, want %v", move, tt.want)
+			}
+			if !IsMateScore(score) || score < 0 {
+				t.Errorf("score = %d, want a winning mate score", score)
+			}
+		})
+	}
+}
+
+func TestSearchPrefersNearerMates(t *testing.T) {
+	score := func(fen string) int {
+		board, err := ParseFEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, score := BestMove(board, 4, SideToMove(board))
+		if !IsMateScore(score) || score < 0 {
+			t.Fatalf("%s: score = %d, want a winning mate score", fen, score)
+		}
+		return score
+	}
+	mateInOne := score("7k/8/6K1/8/8/8/8/R7 w - - 0 1")
+	mateInTwo := score("7k/8/8/6K1/8/8/8/R7 w - - 0 1")
+	if mateInOne <= mateInTwo {
+		t.Errorf("mate in one scores %d, mate in two %d; want the nearer mate higher", mateInOne, mateInTwo)
+	}
+}
@@ -0,0 +1,58 @@
+package game_state
+
+// Evaluator scores a position in centipawns from the point of view of sideToMove.
+// Implementations must be safe for concurrent use, since BestMove evaluates from several workers.
+type Evaluator interface {
+	Evaluate(board Board, sideToMove PieceColor) int
+}
+
+// boardPreparer is implemented by evaluators that keep incremental state on the board,
+// which the search attaches to the root position before expanding it.
+type boardPreparer interface {
+	prepare(board Board) Board
+}
+
+// leafEvaluator is implemented by evaluators with a cheaper path for positions inside a
+// search, which finds checkmate and stalemate itself.
+type leafEvaluator interface {
+	evaluateLeaf(board Board, sideToMove PieceColor) int
+}
+
+// EvaluatorFunc adapts an ordinary function to the Evaluator interface.
+type EvaluatorFunc func(board Board, sideToMove PieceColor) int
+
+func (f EvaluatorFunc) Evaluate(board Board, sideToMove PieceColor) int {
+	return f(board, sideToMove)
+}
+
+// HandcraftedEvaluator is the default evaluator, backed by Evaluate. It uses Params
+// when set and the active weights otherwise.
+type HandcraftedEvaluator struct {
+	Params *EvalParams
+}
+
+func (e HandcraftedEvaluator) Evaluate(board Board, sideToMove PieceColor) int {
+	if e.Params == nil {
+		return Evaluate(board, sideToMove)
+	}
+	return EvaluateWithParams(board, sideToMove, e.Params)
+}
+
+// MaterialEvaluator only counts material, which makes for a fast and weak opponent.
+type MaterialEvaluator struct{}
+
+func (MaterialEvaluator) Evaluate(board Board, sideToMove PieceColor) int {
+	if score, over := terminalScore(board, sideToMove); over {
+		return score
+	}
+
+	params := ActiveEvalParams()
+	score := 0
+	for _, p := range board.PiecesSlice {
+		score += params.PieceValues.of(p.Type) * getSign(p.Color)
+	}
+	if sideToMove == BlackColor {
+		score = -score
+	}
+	return score
+}
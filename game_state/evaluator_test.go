@@ -0,0 +1,65 @@
+package game_state
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestEvaluators(t *testing.T) {
+	params := DefaultEvalParams()
+	params.PieceValues.Knight = 500
+
+	tests := []struct {
+		name string
+		fen  string
+		eval Evaluator
+		want func(board Board, side PieceColor) int
+	}{
+		{"handcrafted uses the active weights", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b - - 2 2", HandcraftedEvaluator{}, Evaluate},
+		{"handcrafted with its own weights", "4k3/8/8/8/8/8/8/1N2K3 w - - 0 1", HandcraftedEvaluator{Params: params}, func(b Board, side PieceColor) int { return EvaluateWithParams(b, side, params) }},
+		{"material for white", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", MaterialEvaluator{}, func(Board, PieceColor) int { return 500 }},
+		{"material for black", "4k3/8/8/8/8/8/8/R3K3 b - - 0 1", MaterialEvaluator{}, func(Board, PieceColor) int { return -500 }},
+		{"material sees mate", "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1", MaterialEvaluator{}, func(Board, PieceColor) int { return -INF }},
+		{"func adapter", "4k3/8/8/8/8/8/8/R3K3 w - - 0 1", EvaluatorFunc(func(Board, PieceColor) int { return 42 }), func(Board, PieceColor) int { return 42 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			side := SideToMove(board)
+			if got, want := tt.eval.Evaluate(board, side), tt.want(board, side); got != want {
+				t.Errorf("Evaluate = %d, want %d", got, want)
+			}
+		})
+	}
+
+	board, err := ParseFEN("4k3/8/8/8/8/8/8/1N2K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (HandcraftedEvaluator{Params: params}).Evaluate(board, WhiteColor) == Evaluate(board, WhiteColor) {
+		t.Error("a heavier knight did not change the handcrafted evaluation")
+	}
+}
+
+func TestSearchUsesEvaluator(t *testing.T) {
+	board, err := ParseFEN("4k3/8/8/3q4/8/8/3R4/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int64
+	counting := EvaluatorFunc(func(b Board, side PieceColor) int {
+		calls.Add(1)
+		return MaterialEvaluator{}.Evaluate(b, side)
+	})
+
+	best, _ := BestMove(board, 2, WhiteColor, WithEvaluator(counting), WithWorkers(2))
+	if want := (Move{From: Position{Line: 2, Column: 4}, To: Position{Line: 5, Column: 4}}); best != want {
+		t.Errorf("BestMove = %v, want the rook taking the queen", best)
+	}
+	if calls.Load() == 0 {
+		t.Error("the search never called the evaluator")
+	}
+}
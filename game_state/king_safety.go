@@ -0,0 +1,125 @@
+package game_state
+
+// evaluateKingSafety returns the king safety score per color.
+func evaluateKingSafety(board Board, attacks [2]attackInfo, params *EvalParams) [2]PhaseScore {
+	var total [2]PhaseScore
+	for color := WhiteColor; color <= BlackColor; color++ {
+		king, found := findKing(board, color)
+		if !found {
+			continue
+		}
+		total[color] = kingShelter(board, king, params).add(kingDanger(board, king, attacks[color], params))
+	}
+	return total
+}
+
+// kingShelter scores the pawn shield, enemy pawn storms and open files on the
+// king's file and the two files next to it.
+func kingShelter(board Board, king Piece, params *EvalParams) PhaseScore {
+	var s PhaseScore
+	us, them := king.Color, opposite(king.Color)
+	dir := pawnDirection(us)
+	onHomeRanks := relativeRank(us, king.Pos.Line) <= 2
+
+	for col := king.Pos.Column - 1; col <= king.Pos.Column+1; col++ {
+		if col < 1 || col > 8 {
+			continue
+		}
+
+		ownPawns := countPawnsAhead(board, us, col, 0, 1)
+		enemyPawns := countPawnsAhead(board, them, col, 0, 1)
+		if ownPawns == 0 && enemyPawns == 0 {
+			s = s.add(params.OpenFileNearKing)
+		} else if ownPawns == 0 {
+			s = s.add(params.SemiOpenFileNearKing)
+		}
+
+		if !onHomeRanks {
+			continue
+		}
+
+		for d := int8(1); d <= 2; d++ {
+			if isPawnAt(board, us, king.Pos.Line+d*dir, col) {
+				s = s.add(params.PawnShield[d])
+				break
+			}
+		}
+
+		// nearest enemy pawn advancing on this file
+		for d := int8(1); d <= 3; d++ {
+			line := king.Pos.Line + d*dir
+			if isPawnAt(board, them, line, col) {
+				penalty := params.PawnStorm[d]
+				if isPawnAt(board, us, line-dir, col) {
+					penalty = penalty.percent(50) // the storm is held up by our own pawn
+				}
+				s = s.add(penalty)
+				break
+			}
+		}
+	}
+	return s
+}
+
+// kingDanger scores enemy pieces bearing on the squares around the king and the
+// checks the opponent can give from squares we do not defend.
+func kingDanger(board Board, king Piece, ours attackInfo, params *EvalParams) PhaseScore {
+	them := opposite(king.Color)
+
+	zone := map[Position]bool{king.Pos: true}
+	for _, o := range kingOffsets {
+		if onBoard(king.Pos.Line+o.dl, king.Pos.Column+o.dc) {
+			zone[Position{Line: king.Pos.Line + o.dl, Column: king.Pos.Column + o.dc}] = true
+		}
+	}
+
+	// squares from which a piece of each type would give check
+	checkSquares := map[PieceType]map[Position]bool{}
+	for _, t := range []PieceType{Knight, Bishop, Rook} {
+		checkSquares[t] = map[Position]bool{}
+		probe := Piece{Type: t, Color: king.Color, Pos: king.Pos}
+		for _, pos := range pieceAttacks(probe, board) {
+			checkSquares[t][pos] = true
+		}
+	}
+	checkSquares[Queen] = map[Position]bool{}
+	for pos := range checkSquares[Bishop] {
+		checkSquares[Queen][pos] = true
+	}
+	for pos := range checkSquares[Rook] {
+		checkSquares[Queen][pos] = true
+	}
+
+	attackers, weight := 0, 0
+	safeChecks := map[PieceType]bool{}
+	for _, p := range board.PiecesSlice {
+		if p.Color != them || params.KingAttackWeight.of(p.Type) == 0 {
+			continue
+		}
+		hitsZone := false
+		for _, pos := range pieceAttacks(p, board) {
+			if zone[pos] {
+				hitsZone = true
+			}
+			if target := board.PiecesMatrix[pos.Line][pos.Column]; target.Type != 0 && target.Color == them {
+				continue
+			}
+			if checkSquares[p.Type][pos] && ours.all[pos.Line][pos.Column] == 0 {
+				safeChecks[p.Type] = true
+			}
+		}
+		if hitsZone {
+			attackers++
+			weight += params.KingAttackWeight.of(p.Type)
+		}
+	}
+
+	if attackers >= len(params.KingAttackerPercent) {
+		attackers = len(params.KingAttackerPercent) - 1
+	}
+	s := PhaseScore{-weight * params.KingAttackerPercent[attackers] / 100, 0}
+	for t := range safeChecks {
+		s = s.add(params.SafeCheck.of(t))
+	}
+	return s
+}
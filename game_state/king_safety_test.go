@@ -0,0 +1,71 @@
+package game_state
+
+import "testing"
+
+func kingTestParams() *EvalParams {
+	return &EvalParams{
+		PawnShield:           [3]PhaseScore{{}, {MG: 10}, {MG: 5}},
+		PawnStorm:            [4]PhaseScore{{}, {MG: -40}, {MG: -20}, {MG: -10}},
+		OpenFileNearKing:     PhaseScore{MG: -100},
+		SemiOpenFileNearKing: PhaseScore{MG: -50},
+		KingAttackWeight:     PieceTable[int]{Knight: 20, Bishop: 20, Rook: 40, Queen: 80},
+		KingAttackerPercent:  [8]int{0, 50, 75, 100, 100, 100, 100, 100},
+		SafeCheck:            PieceTable[PhaseScore]{Knight: PhaseScore{MG: -1}, Bishop: PhaseScore{MG: -2}, Rook: PhaseScore{MG: -30}, Queen: PhaseScore{MG: -4}},
+	}
+}
+
+func TestKingShelter(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int // white's middlegame shelter
+	}{
+		{"full shield", "6k1/8/8/8/8/8/5PPP/6K1 w - - 0 1", 3 * 10},
+		{"advanced shield pawn", "6k1/8/8/8/8/6P1/5P1P/6K1 w - - 0 1", 2*10 + 5},
+		{"open file", "6k1/8/8/8/8/8/5P1P/6K1 w - - 0 1", 2*10 - 100},
+		{"semi-open file", "6k1/8/8/6p1/8/8/5P1P/6K1 w - - 0 1", 2*10 - 50},
+		{"storm held up by a shield pawn", "6k1/8/8/8/8/6p1/5PPP/6K1 w - - 0 1", 3*10 - 20/2},
+		{"storm on a file without a shield pawn", "6k1/8/8/8/8/6p1/5P1P/6K1 w - - 0 1", 2*10 - 20 - 50},
+		{"king off its home ranks", "6k1/8/8/8/8/6K1/5PPP/8 w - - 0 1", 0},
+	}
+	params := kingTestParams()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			king, _ := findKing(board, WhiteColor)
+			if got := kingShelter(board, king, params); got.MG != tt.want {
+				t.Errorf("shelter = %d, want %d", got.MG, tt.want)
+			}
+		})
+	}
+}
+
+func TestKingDanger(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want int // white's middlegame danger
+	}{
+		{"no attackers", "6k1/8/8/8/8/8/5PPP/6K1 w - - 0 1", 0},
+		{"one attacker", "6k1/8/8/8/7q/8/5PPP/6K1 w - - 0 1", -80 * 50 / 100},
+		{"two attackers", "6k1/8/8/8/6nq/8/5PPP/6K1 w - - 0 1", -(80 + 20) * 75 / 100},
+		{"safe back-rank check", "r5k1/8/8/8/8/8/5PPP/6K1 w - - 0 1", -30},
+		{"defended check square", "r5k1/8/8/8/8/8/2N2PPP/6K1 w - - 0 1", 0},
+	}
+	params := kingTestParams()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			king, _ := findKing(board, WhiteColor)
+			if got := kingDanger(board, king, computeAttacks(board, WhiteColor), params); got.MG != tt.want {
+				t.Errorf("danger = %d, want %d", got.MG, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package game_state
+
+// pieceMobility scores the visible squares of a piece that are not attacked by enemy pawns.
+func pieceMobility(p Piece, visible []Position, enemy attackInfo, params *EvalParams) PhaseScore {
+	table := params.Mobility.of(p.Type)
+	if len(table) == 0 {
+		return PhaseScore{}
+	}
+	safe := 0
+	for _, pos := range visible {
+		// GenerateAllVisiblePositions does not bounds-check knight jumps or slider rays
+		if onBoard(pos.Line, pos.Column) && !enemy.pawns[pos.Line][pos.Column] {
+			safe++
+		}
+	}
+	if safe >= len(table) {
+		safe = len(table) - 1
+	}
+	return table[safe]
+}
+
+// evaluatePieceActivity returns the score per color for rook files, the bishop
+// pair and knight outposts.
+func evaluatePieceActivity(board Board, params *EvalParams) [2]PhaseScore {
+	var total [2]PhaseScore
+	bishops := [2]int{}
+
+	for _, p := range board.PiecesSlice {
+		us, them := p.Color, opposite(p.Color)
+		var s PhaseScore
+
+		switch p.Type {
+		case Rook:
+			ownPawns := countPawnsAhead(board, us, p.Pos.Column, 0, 1)
+			enemyPawns := countPawnsAhead(board, them, p.Pos.Column, 0, 1)
+			if ownPawns == 0 && enemyPawns == 0 {
+				s = params.RookOpenFile
+			} else if ownPawns == 0 {
+				s = params.RookSemiOpenFile
+			}
+		case Bishop:
+			bishops[us]++
+		case Knight:
+			// an outpost is defended by a pawn and can never be chased away by one
+			dir := pawnDirection(us)
+			rank := relativeRank(us, p.Pos.Line)
+			if rank >= 4 && rank <= 6 && isPawnDefended(board, p) &&
+				countPawnsAhead(board, them, p.Pos.Column-1, p.Pos.Line, dir) == 0 &&
+				countPawnsAhead(board, them, p.Pos.Column+1, p.Pos.Line, dir) == 0 {
+				s = params.KnightOutpost
+			}
+		}
+
+		total[us] = total[us].add(s)
+	}
+
+	for color := WhiteColor; color <= BlackColor; color++ {
+		if bishops[color] >= 2 {
+			total[color] = total[color].add(params.BishopPair)
+		}
+	}
+	return total
+}
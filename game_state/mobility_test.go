@@ -0,0 +1,82 @@
+package game_state
+
+import "testing"
+
+// mobilityTable scores n safe squares as n, up to size-1.
+func mobilityTable(size int) []PhaseScore {
+	table := make([]PhaseScore, size)
+	for i := range table {
+		table[i] = PhaseScore{MG: i}
+	}
+	return table
+}
+
+func TestPieceMobility(t *testing.T) {
+	tests := []struct {
+		name   string
+		fen    string
+		square Position
+		table  int // length of the piece's mobility table
+		want   int
+	}{
+		{"knight in the corner", "4k3/8/8/8/8/8/8/N3K3 w - - 0 1", Position{Line: 1, Column: 1}, 9, 2},
+		{"knight in the center", "4k3/8/8/8/4N3/8/8/4K3 w - - 0 1", Position{Line: 4, Column: 5}, 9, 8},
+		{"squares covered by pawns", "4k3/4p3/8/8/4N3/8/8/4K3 w - - 0 1", Position{Line: 4, Column: 5}, 9, 6},
+		{"capped at the table", "4k3/8/8/8/4N3/8/8/4K3 w - - 0 1", Position{Line: 4, Column: 5}, 5, 4},
+		{"no table", "4k3/8/8/8/4N3/8/8/4K3 w - - 0 1", Position{Line: 4, Column: 5}, 0, 0},
+		{"black rook on an open board", "r3k3/8/8/8/8/8/8/4K3 b - - 0 1", Position{Line: 8, Column: 1}, 15, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := board.PiecesMatrix[tt.square.Line][tt.square.Column]
+			params := &EvalParams{}
+			params.Mobility.Knight = mobilityTable(tt.table)
+			params.Mobility.Rook = mobilityTable(tt.table)
+
+			enemy := computeAttacks(board, opposite(p.Color))
+			got := pieceMobility(p, GenerateAllVisiblePositions(p, board), enemy, params)
+			if got.MG != tt.want {
+				t.Errorf("mobility = %d, want %d", got.MG, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluatePieceActivity(t *testing.T) {
+	params := &EvalParams{
+		RookOpenFile:     PhaseScore{MG: 1},
+		RookSemiOpenFile: PhaseScore{MG: 10},
+		BishopPair:       PhaseScore{MG: 100},
+		KnightOutpost:    PhaseScore{MG: 1000},
+	}
+	tests := []struct {
+		name         string
+		fen          string
+		white, black int
+	}{
+		{"rook on an open file", "4k3/p7/8/8/8/8/P7/3RK3 w - - 0 1", 1, 0},
+		{"rook on a semi-open file", "3rk3/p7/8/3P4/8/8/P7/4K3 w - - 0 1", 0, 10},
+		{"rook behind its own pawn", "4k3/8/8/8/8/8/3P4/3RK3 w - - 0 1", 0, 0},
+		{"bishop pair", "2b1kb2/8/8/8/8/8/8/2B1K3 w - - 0 1", 0, 100},
+		{"knight outpost", "4k3/8/8/3N4/4P3/8/8/4K3 w - - 0 1", 1000, 0},
+		{"knight that a pawn can chase", "4k3/4p3/8/3N4/4P3/8/8/4K3 w - - 0 1", 0, 0},
+		{"undefended knight", "4k3/8/8/3N4/8/8/8/4K3 w - - 0 1", 0, 0},
+		{"black outpost", "4k3/8/8/4p3/3n4/8/8/4K3 b - - 0 1", 0, 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := evaluatePieceActivity(board, params)
+			if got[WhiteColor].MG != tt.white || got[BlackColor].MG != tt.black {
+				t.Errorf("activity white %d, black %d; want %d, %d", got[WhiteColor].MG, got[BlackColor].MG, tt.white, tt.black)
+			}
+		})
+	}
+}
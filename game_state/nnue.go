@@ -0,0 +1,358 @@
+package game_state
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+// NNUE network with a HalfKP feature set: for each perspective, every non-king piece is
+// indexed by the square of that side's own king, the piece kind and the piece square.
+// Squares are mirrored vertically for Black so both perspectives share one weight matrix.
+//
+// Weights file layout, all little-endian:
+//
+//	magic          [8]byte  "CENNUE01"
+//	hidden         uint32   accumulator size per perspective
+//	outputScale    int32    final scale to centipawns
+//	featureBias    [hidden]int16
+//	featureWeights [nnueInputs][hidden]int16
+//	outputWeights  [2*hidden]int16, side to move first
+//	outputBias     int32
+const (
+	nnueMagic      = "CENNUE01"
+	nnuePieceKinds = 10 // pawn, knight, bishop, rook, queen for each side
+	nnueInputs     = 64 * nnuePieceKinds * 64
+	nnueQA         = 255 // clipped ReLU ceiling on accumulator values
+	nnueQB         = 64  // output weight quantization
+	nnueMaxHidden  = 4096
+	nnueNoFeature  = -1 // kings are implied by the feature index, not inputs themselves
+	nnueMaxActive  = 30 // features of a legal position: every piece but the two kings
+)
+
+// nnueKind maps a piece type to its kind index within one side.
+var nnueKind = map[PieceType]int{
+	Pawn:   0,
+	Knight: 1,
+	Bishop: 2,
+	Rook:   3,
+	Queen:  4,
+	King:   nnueNoFeature,
+}
+
+// Network is a quantized NNUE network loaded with LoadNetwork.
+type Network struct {
+	hidden         int
+	outputScale    int32
+	featureBias    []int16
+	featureWeights []int16 // row-major: the hidden weights of a feature are contiguous
+	outputWeights  []int16
+	outputBias     int32
+}
+
+// LoadNetwork reads network weights from a local file.
+func LoadNetwork(path string) (*Network, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	magic := make([]byte, len(nnueMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, fmt.Errorf("nnue: reading header: %w", err)
+	}
+	if string(magic) != nnueMagic {
+		return nil, errors.New("nnue: not a network file")
+	}
+
+	var header struct {
+		Hidden      uint32
+		OutputScale int32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("nnue: reading header: %w", err)
+	}
+	if header.Hidden == 0 || header.Hidden > nnueMaxHidden {
+		return nil, fmt.Errorf("nnue: invalid hidden size %d", header.Hidden)
+	}
+
+	n := &Network{
+		hidden:         int(header.Hidden),
+		outputScale:    header.OutputScale,
+		featureBias:    make([]int16, header.Hidden),
+		featureWeights: make([]int16, nnueInputs*int(header.Hidden)),
+		outputWeights:  make([]int16, 2*header.Hidden),
+	}
+	for _, dst := range []any{n.featureBias, n.featureWeights, n.outputWeights, &n.outputBias} {
+		if err := binary.Read(r, binary.LittleEndian, dst); err != nil {
+			return nil, fmt.Errorf("nnue: reading weights: %w", err)
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return nil, errors.New("nnue: trailing data after weights")
+	}
+	if err := n.checkRange(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// checkRange rejects weights that could overflow the int16 accumulator: the bias of a
+// hidden unit plus nnueMaxActive of its largest feature weights must fit.
+func (n *Network) checkRange() error {
+	largest := make([]int32, n.hidden)
+	for f := 0; f < nnueInputs; f++ {
+		for i, w := range n.weights(f) {
+			largest[i] = max(largest[i], abs32(int32(w)))
+		}
+	}
+	for i, l := range largest {
+		if abs32(int32(n.featureBias[i]))+nnueMaxActive*l > math.MaxInt16 {
+			return fmt.Errorf("nnue: weights of hidden unit %d can overflow the accumulator", i)
+		}
+	}
+	return nil
+}
+
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// nnueAccumulator holds the first layer output for both perspectives. It is carried on
+// the Board and updated by makeMove, so the search only pays for changed features.
+type nnueAccumulator struct {
+	net    *Network
+	values [2][]int16
+	stack  *nnueStack
+	ply    int // index in stack
+}
+
+// nnueStack holds one accumulator per ply of the line a search thread is in. Making a move
+// overwrites the child ply, which only the sibling searched before it was using, so a
+// thread stops allocating once its line has been as deep as the search goes.
+type nnueStack struct {
+	plies []*nnueAccumulator
+}
+
+// child returns the accumulator for the ply after a, reusing the one already there.
+func (a *nnueAccumulator) child() *nnueAccumulator {
+	s := a.stack
+	if a.ply+1 == len(s.plies) {
+		next := &nnueAccumulator{net: a.net, stack: s, ply: a.ply + 1}
+		for c := range next.values {
+			next.values[c] = make([]int16, a.net.hidden)
+		}
+		s.plies = append(s.plies, next)
+	}
+	return s.plies[a.ply+1]
+}
+
+// fork copies a into a stack of its own, for another search thread to make moves from.
+func (a *nnueAccumulator) fork() *nnueAccumulator {
+	acc := &nnueAccumulator{net: a.net, stack: &nnueStack{}}
+	for c := range acc.values {
+		acc.values[c] = slices.Clone(a.values[c])
+	}
+	acc.stack.plies = []*nnueAccumulator{acc}
+	return acc
+}
+
+func nnueSquare(pos Position, perspective PieceColor) int {
+	line := pos.Line
+	if perspective == BlackColor {
+		line = 9 - line
+	}
+	return int(line-1)*8 + int(pos.Column-1)
+}
+
+// featureIndex returns the input index of piece seen from perspective, or -1 for kings.
+func featureIndex(piece Piece, king Position, perspective PieceColor) int {
+	kind := nnueKind[piece.Type]
+	if kind == nnueNoFeature {
+		return -1
+	}
+	if piece.Color != perspective {
+		kind += nnuePieceKinds / 2
+	}
+	return (nnueSquare(king, perspective)*nnuePieceKinds+kind)*64 + nnueSquare(piece.Pos, perspective)
+}
+
+func (n *Network) weights(feature int) []int16 {
+	return n.featureWeights[feature*n.hidden : (feature+1)*n.hidden]
+}
+
+// addWeights and subWeights are the hot loops of the accumulator. Both operands are
+// contiguous int16 slices of equal length, which keeps bounds checks out of the loop body.
+func addWeights(acc, w []int16) {
+	w = w[:len(acc)]
+	for i := range acc {
+		acc[i] += w[i]
+	}
+}
+
+func subWeights(acc, w []int16) {
+	w = w[:len(acc)]
+	for i := range acc {
+		acc[i] -= w[i]
+	}
+}
+
+// refreshPerspective rebuilds one side of the accumulator from scratch. It fails without
+// a king, or with more pieces than checkRange allowed for.
+func (n *Network) refreshPerspective(acc []int16, board Board, perspective PieceColor) bool {
+	king, found := findKing(board, perspective)
+	if !found {
+		return false
+	}
+	copy(acc, n.featureBias)
+	active := 0
+	for _, p := range board.PiecesSlice {
+		if f := featureIndex(p, king.Pos, perspective); f >= 0 {
+			addWeights(acc, n.weights(f))
+			active++
+		}
+	}
+	return active <= nnueMaxActive
+}
+
+func (n *Network) newAccumulator(board Board) *nnueAccumulator {
+	acc := &nnueAccumulator{net: n, stack: &nnueStack{}}
+	for c := WhiteColor; c <= BlackColor; c++ {
+		acc.values[c] = make([]int16, n.hidden)
+		if !n.refreshPerspective(acc.values[c], board, c) {
+			return nil
+		}
+	}
+	acc.stack.plies = []*nnueAccumulator{acc}
+	return acc
+}
+
+// afterMove returns the accumulator for next, the position reached by playing m on before,
+// in the ply after a. A perspective whose own king moved is rebuilt; otherwise only the
+// changed features are applied.
+func (a *nnueAccumulator) afterMove(m Move, before Board, next Board) *nnueAccumulator {
+	n := a.net
+	moved := before.PiecesMatrix[m.From.Line][m.From.Column]
+	captured, isCapture := _Find_Piece_By_Pos(m.To, before)
+	landed := next.PiecesMatrix[m.To.Line][m.To.Column] // differs from moved on promotion
+
+	acc := a.child()
+	for c := WhiteColor; c <= BlackColor; c++ {
+		values := acc.values[c]
+		if moved.Type == King && moved.Color == c {
+			if !n.refreshPerspective(values, next, c) {
+				return nil
+			}
+			continue
+		}
+
+		king, found := findKing(next, c)
+		if !found {
+			return nil
+		}
+		copy(values, a.values[c])
+		if f := featureIndex(moved, king.Pos, c); f >= 0 {
+			subWeights(values, n.weights(f))
+		}
+		if f := featureIndex(landed, king.Pos, c); f >= 0 {
+			addWeights(values, n.weights(f))
+		}
+		if isCapture {
+			if f := featureIndex(captured, king.Pos, c); f >= 0 {
+				subWeights(values, n.weights(f))
+			}
+		}
+	}
+	return acc
+}
+
+// makeMove is BoardAfterMove for the search, carrying the accumulator over to the new
+// position. BoardAfterMove leaves it behind so legality checks do not pay for it. The
+// accumulator of the returned board is only valid until the next makeMove from board.
+func makeMove(m Move, board Board) Board {
+	next := BoardAfterMove(m, board)
+	if board.nnue != nil {
+		next.nnue = board.nnue.afterMove(m, board, next)
+	}
+	return next
+}
+
+// forkSearch gives board an accumulator stack of its own, for a search thread starting
+// from it.
+func forkSearch(board Board) Board {
+	if board.nnue != nil {
+		board.nnue = board.nnue.fork()
+	}
+	return board
+}
+
+func clippedDot(acc, w []int16) int64 {
+	w = w[:len(acc)]
+	var sum int64
+	for i, v := range acc {
+		if v < 0 {
+			v = 0
+		} else if v > nnueQA {
+			v = nnueQA
+		}
+		sum += int64(v) * int64(w[i])
+	}
+	return sum
+}
+
+func (n *Network) output(acc *nnueAccumulator, sideToMove PieceColor) int {
+	sum := clippedDot(acc.values[sideToMove], n.outputWeights[:n.hidden])
+	sum += clippedDot(acc.values[opposite(sideToMove)], n.outputWeights[n.hidden:])
+	sum += int64(n.outputBias)
+	return int(sum * int64(n.outputScale) / (nnueQA * nnueQB))
+}
+
+// NNUEEvaluator scores positions with a network, falling back to Evaluate when Net is nil
+// or the position has no king to anchor the features on.
+type NNUEEvaluator struct {
+	Net *Network
+}
+
+func (e NNUEEvaluator) Evaluate(board Board, sideToMove PieceColor) int {
+	if e.Net == nil {
+		return Evaluate(board, sideToMove)
+	}
+	if score, over := terminalScore(board, sideToMove); over {
+		return score
+	}
+	return e.evaluateLeaf(board, sideToMove)
+}
+
+// evaluateLeaf is Evaluate without looking for mate, stalemate or repetition, which cost
+// more than the network itself. The search finds mates at nodes without legal moves.
+func (e NNUEEvaluator) evaluateLeaf(board Board, sideToMove PieceColor) int {
+	if e.Net == nil {
+		return Evaluate(board, sideToMove)
+	}
+	acc := board.nnue
+	if acc == nil || acc.net != e.Net {
+		acc = e.Net.newAccumulator(board)
+	}
+	if acc == nil {
+		return Evaluate(board, sideToMove)
+	}
+	return e.Net.output(acc, sideToMove)
+}
+
+// prepare attaches a fresh accumulator to the search root.
+func (e NNUEEvaluator) prepare(board Board) Board {
+	if e.Net != nil {
+		board.nnue = e.Net.newAccumulator(board)
+	}
+	return board
+}
@@ -0,0 +1,102 @@
+package game_state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testHidden = 8
+
+// writeTestNetwork writes a network with weights from weight and returns its path.
+func writeTestNetwork(t *testing.T, weight func(r *rand.Rand) int16) string {
+	t.Helper()
+	r := rand.New(rand.NewSource(1))
+	var buf bytes.Buffer
+	buf.WriteString(nnueMagic)
+	values := []any{uint32(testHidden), int32(400)}
+	for _, n := range []int{testHidden, nnueInputs * testHidden, 2 * testHidden} {
+		w := make([]int16, n)
+		for i := range w {
+			w[i] = weight(r)
+		}
+		values = append(values, w)
+	}
+	values = append(values, int32(0))
+	for _, v := range values {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	path := filepath.Join(t.TempDir(), "net.nnue")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestNNUEIncrementalMatchesRefresh(t *testing.T) {
+	net, err := LoadNetwork(writeTestNetwork(t, func(r *rand.Rand) int16 { return int16(r.Intn(201) - 100) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		fen   string
+		moves []string
+	}{
+		{"opening", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1", []string{"e2e4", "d7d5", "e4d5", "d8d5", "b1c3", "d5a5"}},
+		{"king moves", "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", []string{"e1d2", "e8d7", "e2e4", "d7e6"}},
+		{"promotion", "4k3/1P6/8/8/8/8/8/4K3 w - - 0 1", []string{"b7b8q", "e8d7", "b8b5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			board = NNUEEvaluator{Net: net}.prepare(board)
+			for _, s := range tt.moves {
+				m, err := ParseUCIMove(s)
+				if err != nil {
+					t.Fatal(err)
+				}
+				board = makeMove(m, board)
+
+				fresh := net.newAccumulator(board)
+				for c := range fresh.values {
+					if !slices.Equal(board.nnue.values[c], fresh.values[c]) {
+						t.Fatalf("after %s, perspective %d: incremental %v, refreshed %v", s, c, board.nnue.values[c], fresh.values[c])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestLoadNetworkRejectsOverflow(t *testing.T) {
+	tests := []struct {
+		name    string
+		weight  int16
+		wantErr bool
+	}{
+		{"fits", 1000, false},
+		{"overflows", 1200, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadNetwork(writeTestNetwork(t, func(*rand.Rand) int16 { return tt.weight }))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadNetwork error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "overflow") {
+				t.Errorf("error %q does not mention the overflow", err)
+			}
+		})
+	}
+}
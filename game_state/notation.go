@@ -0,0 +1,240 @@
+package game_state
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// SquareName returns the algebraic name of a square, e.g. "e4".
+func SquareName(p Position) string {
+	return string(rune('a'+p.Column-1)) + string(rune('0'+p.Line))
+}
+
+// ParseSquare parses an algebraic square name such as "e4".
+func ParseSquare(s string) (Position, error) {
+	if len(s) != 2 || s[0] < 'a' || s[0] > 'h' || s[1] < '1' || s[1] > '8' {
+		return Position{}, fmt.Errorf("invalid square %q", s)
+	}
+	return Position{Line: int8(s[1] - '0'), Column: int8(s[0]-'a') + 1}, nil
+}
+
+var ErrUnderpromotion = errors.New("the engine only promotes to a queen")
+
+// MoveToUCI returns a move on board in long algebraic notation, e.g. "e2e4", or "e7e8q"
+// for a promotion, which is always to a queen.
+func MoveToUCI(board Board, m Move) string {
+	uci := SquareName(m.From) + SquareName(m.To)
+	if board.PiecesMatrix[m.From.Line][m.From.Column].Type == Pawn && (m.To.Line == 1 || m.To.Line == 8) {
+		uci += "q"
+	}
+	return uci
+}
+
+// ParseUCIMove parses long algebraic notation. The engine only promotes to a queen, so the
+// only promotion suffix accepted is "q", which may also be left out.
+func ParseUCIMove(s string) (Move, error) {
+	if len(s) == 5 && strings.ContainsRune("rbn", rune(s[4])) {
+		return Move{}, fmt.Errorf("invalid move %q: %w", s, ErrUnderpromotion)
+	}
+	if len(s) != 4 && (len(s) != 5 || s[4] != 'q') {
+		return Move{}, fmt.Errorf("invalid move %q", s)
+	}
+	from, err := ParseSquare(s[:2])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid move %q", s)
+	}
+	to, err := ParseSquare(s[2:4])
+	if err != nil {
+		return Move{}, fmt.Errorf("invalid move %q", s)
+	}
+	return Move{From: from, To: to}, nil
+}
+
+// ParseFEN is FENToBoard with validation: it returns an error instead of panicking and
+// rejects positions without exactly one king per side. Castling, en passant and the move
+// counters are not part of the board and are ignored.
+func ParseFEN(fen string) (Board, error) {
+	fields := strings.Fields(fen)
+	if len(fields) < 2 {
+		return Board{}, fmt.Errorf("invalid FEN %q: expected placement and side to move", fen)
+	}
+	if fields[1] != "w" && fields[1] != "b" {
+		return Board{}, fmt.Errorf("invalid FEN %q: side to move must be w or b", fen)
+	}
+
+	ranks := strings.Split(fields[0], "/")
+	if len(ranks) != 8 {
+		return Board{}, fmt.Errorf("invalid FEN %q: expected 8 ranks", fen)
+	}
+	kings := map[byte]int{}
+	for _, rank := range ranks {
+		squares := 0
+		for i := 0; i < len(rank); i++ {
+			ch := rank[i]
+			switch {
+			case ch >= '1' && ch <= '8':
+				squares += int(ch - '0')
+			case strings.IndexByte("pnbrqkPNBRQK", ch) >= 0:
+				squares++
+				if ch == 'k' || ch == 'K' {
+					kings[ch]++
+				}
+			default:
+				return Board{}, fmt.Errorf("invalid FEN %q: unexpected %q", fen, ch)
+			}
+		}
+		if squares != 8 {
+			return Board{}, fmt.Errorf("invalid FEN %q: rank %q does not have 8 squares", fen, rank)
+		}
+	}
+	if kings['K'] != 1 || kings['k'] != 1 {
+		return Board{}, fmt.Errorf("invalid FEN %q: each side needs exactly one king", fen)
+	}
+
+	return FENToBoard(fields[0] + " " + fields[1]), nil
+}
+
+// FENWithCounters is BoardToFEN with real halfmove and fullmove counters, which the board
+// itself does not track.
+func FENWithCounters(board Board, halfmove, fullmove int) string {
+	fields := strings.Fields(BoardToFEN(board))
+	return fmt.Sprintf("%s %s - - %d %d", fields[0], fields[1], halfmove, fullmove)
+}
+
+// FENCounters reads the halfmove clock and fullmove number of a FEN, defaulting to 0 and 1.
+func FENCounters(fen string) (halfmove, fullmove int) {
+	halfmove, fullmove = 0, 1
+	fields := strings.Fields(fen)
+	if len(fields) >= 5 {
+		if n, err := strconv.Atoi(fields[4]); err == nil && n >= 0 {
+			halfmove = n
+		}
+	}
+	if len(fields) >= 6 {
+		if n, err := strconv.Atoi(fields[5]); err == nil && n >= 1 {
+			fullmove = n
+		}
+	}
+	return halfmove, fullmove
+}
+
+// SideToMove returns the color whose turn it is.
+func SideToMove(board Board) PieceColor {
+	if board.WhiteTurn {
+		return WhiteColor
+	}
+	return BlackColor
+}
+
+// LegalMoves returns every legal move for the side to move.
+func LegalMoves(board Board) []Move {
+	color := SideToMove(board)
+	var moves []Move
+	for _, piece := range board.PiecesSlice {
+		if piece.Color != color {
+			continue
+		}
+		for _, to := range GenerateAllLegalMoves(piece, board) {
+			moves = append(moves, Move{From: piece.Pos, To: to})
+		}
+	}
+	return moves
+}
+
+// MoveToSAN returns a legal move in standard algebraic notation, e.g. "Nbd7", "exd5",
+// "e8=Q+" or "Qh4#". board is the position before the move.
+func MoveToSAN(board Board, m Move) string {
+	piece := board.PiecesMatrix[m.From.Line][m.From.Column]
+	capture := board.PiecesMatrix[m.To.Line][m.To.Column].Type != 0
+
+	var sb strings.Builder
+	if piece.Type == Pawn {
+		if capture {
+			sb.WriteString(SquareName(m.From)[:1])
+			sb.WriteByte('x')
+		}
+		sb.WriteString(SquareName(m.To))
+		if m.To.Line == 8 || m.To.Line == 1 {
+			sb.WriteString("=Q")
+		}
+	} else {
+		sb.WriteString(_uncolored_PieceToString(piece))
+
+		// disambiguate against other pieces of the same kind reaching the same square
+		sameFile, sameLine, ambiguous := false, false, false
+		for _, other := range board.PiecesSlice {
+			if other.Type != piece.Type || other.Color != piece.Color || other.Pos == m.From {
+				continue
+			}
+			if !slices.Contains(GenerateAllLegalMoves(other, board), m.To) {
+				continue
+			}
+			ambiguous = true
+			sameFile = sameFile || other.Pos.Column == m.From.Column
+			sameLine = sameLine || other.Pos.Line == m.From.Line
+		}
+		from := SquareName(m.From)
+		switch {
+		case ambiguous && !sameFile:
+			sb.WriteString(from[:1])
+		case ambiguous && !sameLine:
+			sb.WriteString(from[1:])
+		case ambiguous:
+			sb.WriteString(from)
+		}
+
+		if capture {
+			sb.WriteByte('x')
+		}
+		sb.WriteString(SquareName(m.To))
+	}
+
+	next := BoardAfterMove(m, board)
+	opponent := SideToMove(next)
+	if IsKingInCheck(next, opponent) {
+		if HasLegalMoves(next, opponent) {
+			sb.WriteByte('+')
+		} else {
+			sb.WriteByte('#')
+		}
+	}
+	return sb.String()
+}
+
+// ParseMove reads a move for the side to move in UCI ("g1f3") or SAN ("Nf3") notation
+// and checks that it is legal.
+func ParseMove(board Board, s string) (Move, error) {
+	s = strings.TrimSpace(s)
+	legal := LegalMoves(board)
+
+	m, err := ParseUCIMove(s)
+	if err == nil {
+		for _, l := range legal {
+			// a "q" suffix must promote, while a promotion may leave it out
+			if l == m && (len(s) == 4 || MoveToUCI(board, l) == s) {
+				return m, nil
+			}
+		}
+		return Move{}, fmt.Errorf("illegal move %q", s)
+	}
+	if errors.Is(err, ErrUnderpromotion) {
+		return Move{}, err
+	}
+
+	want := normalizeSAN(s)
+	for _, m := range legal {
+		if normalizeSAN(MoveToSAN(board, m)) == want {
+			return m, nil
+		}
+	}
+	return Move{}, fmt.Errorf("illegal or unrecognised move %q", s)
+}
+
+// normalizeSAN drops check marks, annotations and the promotion '=' so "e8Q" matches "e8=Q+".
+func normalizeSAN(s string) string {
+	s = strings.TrimRight(s, "+#!?")
+	return strings.ReplaceAll(s, "=", "")
+}
@@ -0,0 +1,247 @@
+package game_state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sync/atomic"
+)
+
+// PieceTable holds one value per piece type. Kings are never weighted.
+type PieceTable[T any] struct {
+	Pawn   T `json:"pawn"`
+	Knight T `json:"knight"`
+	Bishop T `json:"bishop"`
+	Rook   T `json:"rook"`
+	Queen  T `json:"queen"`
+}
+
+func (t *PieceTable[T]) of(p PieceType) T {
+	switch p {
+	case Pawn:
+		return t.Pawn
+	case Knight:
+		return t.Knight
+	case Bishop:
+		return t.Bishop
+	case Rook:
+		return t.Rook
+	case Queen:
+		return t.Queen
+	}
+	var zero T
+	return zero
+}
+
+// EvalParams holds every tunable weight of the handcrafted evaluation, in centipawns
+// unless noted. Paired weights are middlegame/endgame values. A params value must not be
+// modified once it is in use by a search.
+type EvalParams struct {
+	PieceValues                PieceTable[int] `json:"piece_values"`
+	CenterValueMultiplier      float32         `json:"center_value_multiplier"`      // share of a piece's value
+	AttackVisibilityMultiplier int             `json:"attack_visibility_multiplier"` // percent of the attacked piece's value
+
+	// Mobility by number of safe squares. The tables are non-linear: a trapped piece
+	// loses a lot, while extra squares on an open board add little.
+	Mobility         PieceTable[[]PhaseScore] `json:"mobility"`
+	RookOpenFile     PhaseScore               `json:"rook_open_file"`
+	RookSemiOpenFile PhaseScore               `json:"rook_semi_open_file"`
+	BishopPair       PhaseScore               `json:"bishop_pair"`
+	KnightOutpost    PhaseScore               `json:"knight_outpost"`
+
+	// Pawn structure, indexed by relative rank where needed
+	PassedPawn             [9]PhaseScore `json:"passed_pawn"`
+	CandidatePasser        [9]PhaseScore `json:"candidate_passer"`
+	IsolatedPawn           PhaseScore    `json:"isolated_pawn"`
+	DoubledPawn            PhaseScore    `json:"doubled_pawn"`
+	BackwardPawn           PhaseScore    `json:"backward_pawn"`
+	PawnChain              PhaseScore    `json:"pawn_chain"`
+	PassedBlockedPercent   int           `json:"passed_blocked_percent"`   // share kept by a blocked passer
+	PassedSupportedPercent int           `json:"passed_supported_percent"` // scale for a passer defended by a pawn
+
+	// King safety. Endgame values are zero by default so the terms fade out as material comes off.
+	PawnShield           [3]PhaseScore          `json:"pawn_shield"` // indexed by distance from the king
+	PawnStorm            [4]PhaseScore          `json:"pawn_storm"`
+	OpenFileNearKing     PhaseScore             `json:"open_file_near_king"`
+	SemiOpenFileNearKing PhaseScore             `json:"semi_open_file_near_king"`
+	KingAttackWeight     PieceTable[int]        `json:"king_attack_weight"`
+	KingAttackerPercent  [8]int                 `json:"king_attacker_percent"` // share of the summed weight by attacker count
+	SafeCheck            PieceTable[PhaseScore] `json:"safe_check"`
+}
+
+// DefaultEvalParams returns a fresh copy of the built-in weights.
+func DefaultEvalParams() *EvalParams {
+	return &EvalParams{
+		PieceValues:                PieceTable[int]{Pawn: 100, Knight: 320, Bishop: 330, Rook: 500, Queen: 900},
+		CenterValueMultiplier:      0.05,
+		AttackVisibilityMultiplier: 15,
+
+		Mobility: PieceTable[[]PhaseScore]{
+			Knight: []PhaseScore{
+				{-31, -40}, {-26, -28}, {-6, -15}, {-2, -8}, {2, 2}, {6, 5}, {11, 8}, {14, 10}, {16, 12},
+			},
+			Bishop: []PhaseScore{
+				{-24, -29}, {-10, -11}, {8, -1}, {13, 6}, {19, 12}, {25, 21}, {27, 27},
+				{31, 28}, {31, 32}, {34, 36}, {40, 39}, {40, 43}, {45, 44}, {49, 48},
+			},
+			Rook: []PhaseScore{
+				{-30, -39}, {-10, -8}, {1, 11}, {1, 19}, {1, 35}, {5, 49}, {11, 51}, {15, 60},
+				{20, 67}, {20, 69}, {20, 79}, {24, 82}, {28, 84}, {28, 84}, {31, 86},
+			},
+			Queen: []PhaseScore{
+				{-15, -24}, {-6, -15}, {-4, -3}, {-4, 9}, {10, 20}, {11, 27}, {11, 29},
+				{17, 37}, {19, 39}, {26, 48}, {32, 48}, {32, 50}, {32, 60}, {33, 63},
+				{33, 65}, {33, 66}, {36, 68}, {36, 70}, {38, 73}, {39, 75}, {46, 75},
+				{54, 84}, {54, 84}, {54, 85}, {55, 91}, {57, 91}, {57, 96}, {58, 109},
+			},
+		},
+		RookOpenFile:     PhaseScore{20, 10},
+		RookSemiOpenFile: PhaseScore{10, 5},
+		BishopPair:       PhaseScore{25, 45},
+		KnightOutpost:    PhaseScore{20, 10},
+
+		PassedPawn: [9]PhaseScore{
+			{}, {}, {5, 10}, {10, 17}, {17, 30}, {30, 55}, {55, 95}, {90, 150}, {},
+		},
+		CandidatePasser: [9]PhaseScore{
+			{}, {}, {3, 6}, {5, 10}, {9, 17}, {15, 28}, {25, 45}, {}, {},
+		},
+		IsolatedPawn:           PhaseScore{-10, -15},
+		DoubledPawn:            PhaseScore{-10, -20},
+		BackwardPawn:           PhaseScore{-8, -10},
+		PawnChain:              PhaseScore{5, 3},
+		PassedBlockedPercent:   50,
+		PassedSupportedPercent: 130,
+
+		PawnShield:           [3]PhaseScore{{}, {12, 0}, {6, 0}},
+		PawnStorm:            [4]PhaseScore{{}, {-5, 0}, {-20, 0}, {-10, 0}},
+		OpenFileNearKing:     PhaseScore{-25, 0},
+		SemiOpenFileNearKing: PhaseScore{-12, 0},
+		KingAttackWeight:     PieceTable[int]{Knight: 20, Bishop: 20, Rook: 40, Queen: 80},
+		KingAttackerPercent:  [8]int{0, 0, 50, 75, 88, 94, 97, 99},
+		SafeCheck: PieceTable[PhaseScore]{
+			Knight: PhaseScore{-40, 0},
+			Bishop: PhaseScore{-25, 0},
+			Rook:   PhaseScore{-45, 0},
+			Queen:  PhaseScore{-35, 0},
+		},
+	}
+}
+
+// Clone returns a deep copy of the weights.
+func (p *EvalParams) Clone() *EvalParams {
+	c := *p
+	c.Mobility = PieceTable[[]PhaseScore]{
+		Pawn:   slices.Clone(p.Mobility.Pawn),
+		Knight: slices.Clone(p.Mobility.Knight),
+		Bishop: slices.Clone(p.Mobility.Bishop),
+		Rook:   slices.Clone(p.Mobility.Rook),
+		Queen:  slices.Clone(p.Mobility.Queen),
+	}
+	return &c
+}
+
+var activeEvalParams atomic.Pointer[EvalParams]
+
+func init() {
+	activeEvalParams.Store(DefaultEvalParams())
+}
+
+// ActiveEvalParams returns the weights used by Evaluate.
+func ActiveEvalParams() *EvalParams {
+	return activeEvalParams.Load()
+}
+
+// SetEvalParams replaces the weights used by Evaluate.
+func SetEvalParams(p *EvalParams) {
+	activeEvalParams.Store(p)
+}
+
+// LoadEvalParams reads and validates weights from a JSON file. Weights missing from the
+// file keep their default value; unknown keys are rejected so typos do not go unnoticed.
+func LoadEvalParams(path string) (*EvalParams, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := DefaultEvalParams()
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// Validate reports the first weight that is out of range.
+func (p *EvalParams) Validate() error {
+	for _, v := range []struct {
+		name  string
+		value int
+	}{
+		{"pawn", p.PieceValues.Pawn},
+		{"knight", p.PieceValues.Knight},
+		{"bishop", p.PieceValues.Bishop},
+		{"rook", p.PieceValues.Rook},
+		{"queen", p.PieceValues.Queen},
+	} {
+		if v.value <= 0 {
+			return fmt.Errorf("piece_values.%s must be positive, got %d", v.name, v.value)
+		}
+	}
+
+	if p.CenterValueMultiplier < 0 || p.CenterValueMultiplier > 1 {
+		return fmt.Errorf("center_value_multiplier must be between 0 and 1, got %g", p.CenterValueMultiplier)
+	}
+	if p.AttackVisibilityMultiplier < 0 || p.AttackVisibilityMultiplier > 100 {
+		return fmt.Errorf("attack_visibility_multiplier must be between 0 and 100, got %d", p.AttackVisibilityMultiplier)
+	}
+
+	for _, m := range []struct {
+		name  string
+		table []PhaseScore
+	}{
+		{"knight", p.Mobility.Knight},
+		{"bishop", p.Mobility.Bishop},
+		{"rook", p.Mobility.Rook},
+		{"queen", p.Mobility.Queen},
+	} {
+		if len(m.table) == 0 {
+			return fmt.Errorf("mobility.%s must have at least one entry", m.name)
+		}
+	}
+
+	if p.PassedBlockedPercent < 0 || p.PassedBlockedPercent > 100 {
+		return fmt.Errorf("passed_blocked_percent must be between 0 and 100, got %d", p.PassedBlockedPercent)
+	}
+	if p.PassedSupportedPercent < 0 || p.PassedSupportedPercent > 300 {
+		return fmt.Errorf("passed_supported_percent must be between 0 and 300, got %d", p.PassedSupportedPercent)
+	}
+
+	for _, w := range []int{p.KingAttackWeight.Pawn, p.KingAttackWeight.Knight, p.KingAttackWeight.Bishop,
+		p.KingAttackWeight.Rook, p.KingAttackWeight.Queen} {
+		if w < 0 {
+			return fmt.Errorf("king_attack_weight must not be negative, got %d", w)
+		}
+	}
+	for i, pct := range p.KingAttackerPercent {
+		if pct < 0 || pct > 100 {
+			return fmt.Errorf("king_attacker_percent[%d] must be between 0 and 100, got %d", i, pct)
+		}
+	}
+	return nil
+}
+
+// Save writes the weights to path as indented JSON.
+func (p *EvalParams) Save(path string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,115 @@
+package game_state
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(p *EvalParams)
+		wantErr string
+	}{
+		{"defaults", func(*EvalParams) {}, ""},
+		{"zero piece value", func(p *EvalParams) { p.PieceValues.Bishop = 0 }, "piece_values.bishop"},
+		{"center multiplier above one", func(p *EvalParams) { p.CenterValueMultiplier = 1.5 }, "center_value_multiplier"},
+		{"negative attack multiplier", func(p *EvalParams) { p.AttackVisibilityMultiplier = -1 }, "attack_visibility_multiplier"},
+		{"empty mobility table", func(p *EvalParams) { p.Mobility.Rook = nil }, "mobility.rook"},
+		{"blocked passer above 100%", func(p *EvalParams) { p.PassedBlockedPercent = 101 }, "passed_blocked_percent"},
+		{"supported passer above 300%", func(p *EvalParams) { p.PassedSupportedPercent = 301 }, "passed_supported_percent"},
+		{"negative king attack weight", func(p *EvalParams) { p.KingAttackWeight.Queen = -5 }, "king_attack_weight"},
+		{"attacker percent out of range", func(p *EvalParams) { p.KingAttackerPercent[3] = 120 }, "king_attacker_percent[3]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := DefaultEvalParams()
+			tt.change(p)
+			err := p.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want an error about %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	p := DefaultEvalParams()
+	p.PieceValues.Knight = 305
+	p.Mobility.Bishop = append(p.Mobility.Bishop, PhaseScore{MG: 70, EG: 80})
+	p.PawnShield[1] = PhaseScore{MG: 31, EG: 2}
+
+	path := filepath.Join(t.TempDir(), "weights.json")
+	if err := p.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadEvalParams(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, p) {
+		t.Errorf("loaded weights differ from the saved ones:\n%+v\n%+v", loaded, p)
+	}
+}
+
+func TestLoadEvalParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+		check   func(t *testing.T, p *EvalParams)
+	}{
+		{
+			name:    "missing weights keep their defaults",
+			content: `{"piece_values": {"pawn": 90, "knight": 300, "bishop": 310, "rook": 480, "queen": 880}}`,
+			check: func(t *testing.T, p *EvalParams) {
+				want := DefaultEvalParams()
+				want.PieceValues = PieceTable[int]{Pawn: 90, Knight: 300, Bishop: 310, Rook: 480, Queen: 880}
+				if !reflect.DeepEqual(p, want) {
+					t.Errorf("loaded %+v, want the defaults with new piece values", p)
+				}
+			},
+		},
+		{name: "unknown key", content: `{"piece_value": {}}`, wantErr: "unknown field"},
+		{name: "out of range", content: `{"passed_blocked_percent": 150}`, wantErr: "passed_blocked_percent"},
+		{name: "not JSON", content: `pawn = 100`, wantErr: "weights.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "weights.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			p, err := LoadEvalParams(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("LoadEvalParams = %v, want an error about %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, p)
+		})
+	}
+}
+
+func TestCloneIsDeep(t *testing.T) {
+	p := DefaultEvalParams()
+	c := p.Clone()
+	c.Mobility.Knight[0] = PhaseScore{MG: 999}
+	c.PassedPawn[4] = PhaseScore{MG: 999}
+	if p.Mobility.Knight[0].MG == 999 || p.PassedPawn[4].MG == 999 {
+		t.Error("changing the clone changed the original")
+	}
+}
@@ -0,0 +1,226 @@
+package game_state
+
+import "sync"
+
+const (
+	pawnTableSize   = 1 << 14
+	pawnTableShards = 64 // each with its own lock, so search workers rarely wait on each other
+)
+
+type pawnEntry struct {
+	key     uint64
+	weights pawnWeights // the weights the entry was computed with
+	valid   bool
+	score   [2]PhaseScore // per color, excludes passed pawns
+	passed  [2]uint64     // passed pawn squares per color, see squareBit
+}
+
+// pawnWeights are the weights evaluatePawnStructure reads. Entries are matched on their
+// values rather than on the EvalParams pointer, so a copy of the same weights, or weights
+// changed in place by the tuner, get the right entries.
+type pawnWeights struct {
+	candidatePasser                        [9]PhaseScore
+	isolated, doubled, backward, pawnChain PhaseScore
+}
+
+func pawnWeightsOf(params *EvalParams) pawnWeights {
+	return pawnWeights{
+		candidatePasser: params.CandidatePasser,
+		isolated:        params.IsolatedPawn,
+		doubled:         params.DoubledPawn,
+		backward:        params.BackwardPawn,
+		pawnChain:       params.PawnChain,
+	}
+}
+
+// pawnHashTable caches pawn structure results by PawnHash. It is shared by all search workers.
+type pawnHashTable struct {
+	shards []pawnTableShard
+}
+
+type pawnTableShard struct {
+	mu      sync.Mutex
+	entries []pawnEntry
+}
+
+var pawnTable = newPawnHashTable(pawnTableSize, pawnTableShards)
+
+// newPawnHashTable splits size entries evenly between shards.
+func newPawnHashTable(size, shards int) *pawnHashTable {
+	t := &pawnHashTable{shards: make([]pawnTableShard, shards)}
+	for i := range t.shards {
+		t.shards[i].entries = make([]pawnEntry, size/shards)
+	}
+	return t
+}
+
+// slot picks the shard by the key's high bits and the entry within it by the low bits.
+func (t *pawnHashTable) slot(key uint64) (*pawnTableShard, uint64) {
+	s := &t.shards[(key>>32)%uint64(len(t.shards))]
+	return s, key % uint64(len(s.entries))
+}
+
+func (t *pawnHashTable) probe(key uint64, weights pawnWeights) (pawnEntry, bool) {
+	s, i := t.slot(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.entries[i]
+	return e, e.valid && e.key == key && e.weights == weights
+}
+
+func (t *pawnHashTable) store(e pawnEntry) {
+	s, i := t.slot(e.key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.valid = true
+	s.entries[i] = e
+}
+
+func squareBit(pos Position) uint64 {
+	return 1 << uint((pos.Line-1)*8+pos.Column-1)
+}
+
+func squareFromIndex(i int) Position {
+	return Position{Line: int8(i/8) + 1, Column: int8(i%8) + 1}
+}
+
+func pawnDirection(color PieceColor) int8 {
+	if color == WhiteColor {
+		return 1
+	}
+	return -1
+}
+
+func relativeRank(color PieceColor, line int8) int8 {
+	if color == WhiteColor {
+		return line
+	}
+	return 9 - line
+}
+
+func isPawnAt(board Board, color PieceColor, line, column int8) bool {
+	p, found := _Find_Piece_By_Pos(Position{Line: line, Column: column}, board)
+	return found && p.Type == Pawn && p.Color == color
+}
+
+// countPawnsAhead counts pawns of color on column strictly in front of line,
+// where "in front" follows dir.
+func countPawnsAhead(board Board, color PieceColor, column, line, dir int8) int {
+	if column < 1 || column > 8 {
+		return 0
+	}
+	n := 0
+	for l := line + dir; l >= 1 && l <= 8; l += dir {
+		if isPawnAt(board, color, l, column) {
+			n++
+		}
+	}
+	return n
+}
+
+// countPawnsBehind counts pawns of color on column on line or behind it.
+func countPawnsBehind(board Board, color PieceColor, column, line, dir int8) int {
+	if column < 1 || column > 8 {
+		return 0
+	}
+	n := 0
+	for l := line; l >= 1 && l <= 8; l -= dir {
+		if isPawnAt(board, color, l, column) {
+			n++
+		}
+	}
+	return n
+}
+
+func isPawnDefended(board Board, p Piece) bool {
+	dir := pawnDirection(p.Color)
+	return isPawnAt(board, p.Color, p.Pos.Line-dir, p.Pos.Column-1) ||
+		isPawnAt(board, p.Color, p.Pos.Line-dir, p.Pos.Column+1)
+}
+
+// evaluatePawns returns the pawn structure and passed pawn scores per color, using the pawn hash table.
+func evaluatePawns(board Board, params *EvalParams) (structure, passed [2]PhaseScore) {
+	key := PawnHash(board)
+	weights := pawnWeightsOf(params)
+	entry, ok := pawnTable.probe(key, weights)
+	if !ok {
+		entry = evaluatePawnStructure(board, params)
+		entry.key = key
+		entry.weights = weights
+		pawnTable.store(entry)
+	}
+	return entry.score, evaluatePassedPawns(board, entry.passed, params)
+}
+
+func evaluatePawnStructure(board Board, params *EvalParams) pawnEntry {
+	var e pawnEntry
+
+	for _, p := range board.PiecesSlice {
+		if p.Type != Pawn {
+			continue
+		}
+		var s PhaseScore
+		us, them := p.Color, opposite(p.Color)
+		dir := pawnDirection(us)
+		line, col := p.Pos.Line, p.Pos.Column
+
+		enemySameFile := countPawnsAhead(board, them, col, line, dir)
+		enemyAdjacent := countPawnsAhead(board, them, col-1, line, dir) + countPawnsAhead(board, them, col+1, line, dir)
+		ownAhead := countPawnsAhead(board, us, col, line, dir)
+		ownAdjacentBehind := countPawnsBehind(board, us, col-1, line, dir) + countPawnsBehind(board, us, col+1, line, dir)
+		isolated := ownAdjacentBehind+countPawnsAhead(board, us, col-1, line, dir)+countPawnsAhead(board, us, col+1, line, dir) == 0
+		passed := enemySameFile == 0 && enemyAdjacent == 0 && ownAhead == 0
+
+		if passed {
+			e.passed[us] |= squareBit(p.Pos)
+		} else if enemySameFile == 0 && ownAhead == 0 && ownAdjacentBehind >= enemyAdjacent {
+			s = s.add(params.CandidatePasser[relativeRank(us, line)])
+		}
+
+		if isolated {
+			s = s.add(params.IsolatedPawn)
+		} else if !passed && ownAdjacentBehind == 0 &&
+			(isPawnAt(board, them, line+2*dir, col-1) || isPawnAt(board, them, line+2*dir, col+1)) {
+			// no neighbour can ever defend it and its stop square is covered by an enemy pawn
+			s = s.add(params.BackwardPawn)
+		}
+
+		if ownAhead > 0 {
+			s = s.add(params.DoubledPawn)
+		}
+
+		if isPawnDefended(board, p) {
+			s = s.add(params.PawnChain)
+		}
+
+		e.score[us] = e.score[us].add(s)
+	}
+
+	return e
+}
+
+// evaluatePassedPawns scores passed pawns by rank, then scales them down when the
+// stop square is occupied and up when the pawn is defended by another pawn.
+func evaluatePassedPawns(board Board, passed [2]uint64, params *EvalParams) [2]PhaseScore {
+	var total [2]PhaseScore
+	for color := WhiteColor; color <= BlackColor; color++ {
+		for i := 0; i < 64; i++ {
+			if passed[color]&(1<<uint(i)) == 0 {
+				continue
+			}
+			pos := squareFromIndex(i)
+			p := board.PiecesMatrix[pos.Line][pos.Column]
+			bonus := params.PassedPawn[relativeRank(color, pos.Line)]
+
+			stop := Position{Line: pos.Line + pawnDirection(color), Column: pos.Column}
+			if _, blocked := _Find_Piece_By_Pos(stop, board); blocked {
+				bonus = bonus.percent(params.PassedBlockedPercent)
+			}
+			if isPawnDefended(board, p) {
+				bonus = bonus.percent(params.PassedSupportedPercent)
+			}
+			total[color] = total[color].add(bonus)
+		}
+	}
+	return total
+}
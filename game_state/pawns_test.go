@@ -0,0 +1,194 @@
+package game_state
+
+import (
+	"sync"
+	"testing"
+)
+
+// pawnTestParams weights each pawn structure term by its own power of ten in the
+// middlegame, so a score reads as counts: isolated, doubled, backward, chain and
+// candidate passers from the lowest digit up.
+func pawnTestParams() *EvalParams {
+	p := &EvalParams{
+		IsolatedPawn:           PhaseScore{MG: 1},
+		DoubledPawn:            PhaseScore{MG: 10},
+		BackwardPawn:           PhaseScore{MG: 100},
+		PawnChain:              PhaseScore{MG: 1000},
+		PassedBlockedPercent:   50,
+		PassedSupportedPercent: 200,
+	}
+	for rank := range p.CandidatePasser {
+		p.CandidatePasser[rank] = PhaseScore{MG: 10000}
+		p.PassedPawn[rank] = PhaseScore{MG: 8 * rank}
+	}
+	return p
+}
+
+func squares(names ...string) uint64 {
+	var bits uint64
+	for _, name := range names {
+		bits |= squareBit(Position{Line: int8(name[1] - '0'), Column: int8(name[0]-'a') + 1})
+	}
+	return bits
+}
+
+func TestEvaluatePawnStructure(t *testing.T) {
+	tests := []struct {
+		name        string
+		fen         string
+		white       int // middlegame structure score
+		black       int
+		passedWhite uint64
+		passedBlack uint64
+	}{
+		{"lone isolated passer", "4k3/8/8/8/8/8/P7/4K3 w - - 0 1", 1, 0, squares("a2"), 0},
+		{"doubled and isolated", "4k3/8/8/8/8/2P5/2P5/4K3 w - - 0 1", 1 + 1 + 10, 0, squares("c3"), 0},
+		{"chain of passers", "4k3/8/8/8/3P4/4P3/8/4K3 w - - 0 1", 1000, 0, squares("d4", "e3"), 0},
+		{"backward pawn", "4k3/8/8/8/2p1P3/8/3P4/4K3 w - - 0 1", 100, 1, squares("e4"), 0},
+		{"supported candidate", "4k3/8/1p6/8/2P5/1P6/8/4K3 w - - 0 1", 10000 + 1000, 1, 0, 0},
+		{"mirrored for black", "4k3/8/1p6/2p5/8/1P6/8/4K3 w - - 0 1", 1, 10000 + 1000, 0, 0},
+	}
+	params := pawnTestParams()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := evaluatePawnStructure(board, params)
+			if e.score[WhiteColor].MG != tt.white || e.score[BlackColor].MG != tt.black {
+				t.Errorf("scores white %d, black %d; want %d, %d", e.score[WhiteColor].MG, e.score[BlackColor].MG, tt.white, tt.black)
+			}
+			if e.passed[WhiteColor] != tt.passedWhite || e.passed[BlackColor] != tt.passedBlack {
+				t.Errorf("passed white %x, black %x; want %x, %x", e.passed[WhiteColor], e.passed[BlackColor], tt.passedWhite, tt.passedBlack)
+			}
+		})
+	}
+}
+
+func TestEvaluatePassedPawns(t *testing.T) {
+	tests := []struct {
+		name  string
+		fen   string
+		white int
+		black int
+	}{
+		{"by relative rank", "4k3/8/8/8/7p/8/1P6/4K3 w - - 0 1", 8 * 2, 8 * 5},
+		{"blocked", "4k3/8/8/8/8/1n6/1P6/4K3 w - - 0 1", 8 * 2 / 2, 0},
+		{"supported", "4k3/8/8/8/3P4/4P3/8/4K3 w - - 0 1", 8*4*2 + 8*3, 0},
+	}
+	params := pawnTestParams()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, passed := evaluatePawns(board, params)
+			if passed[WhiteColor].MG != tt.white || passed[BlackColor].MG != tt.black {
+				t.Errorf("passed white %d, black %d; want %d, %d", passed[WhiteColor].MG, passed[BlackColor].MG, tt.white, tt.black)
+			}
+		})
+	}
+}
+
+func TestPawnHash(t *testing.T) {
+	board, err := ParseFEN("rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	play := func(b Board, san string) Board {
+		m, err := ParseMove(b, san)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return BoardAfterMove(m, b)
+	}
+
+	knight := play(board, "Nf3")
+	if PawnHash(knight) != PawnHash(board) {
+		t.Error("a knight move changed the pawn hash")
+	}
+	if ZobristHash(knight) == ZobristHash(board) {
+		t.Error("a knight move left the Zobrist hash unchanged")
+	}
+	if PawnHash(play(board, "e4")) == PawnHash(board) {
+		t.Error("a pawn move left the pawn hash unchanged")
+	}
+}
+
+func TestPawnTableMatchesWeights(t *testing.T) {
+	board, err := ParseFEN("4k3/8/8/8/8/8/P7/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := pawnTestParams()
+	before, _ := evaluatePawns(board, params)
+
+	changed := pawnTestParams()
+	changed.IsolatedPawn = PhaseScore{MG: 7}
+	after, _ := evaluatePawns(board, changed)
+	if before[WhiteColor].MG != 1 || after[WhiteColor].MG != 7 {
+		t.Errorf("isolated pawn scored %d then %d with new weights, want 1 then 7", before[WhiteColor].MG, after[WhiteColor].MG)
+	}
+
+	// a copy of the first weights finds the first entry again
+	if again, _ := evaluatePawns(board, pawnTestParams()); again != before {
+		t.Errorf("copied weights scored %+v, want %+v", again, before)
+	}
+}
+
+func TestPawnHashTableShards(t *testing.T) {
+	weights := pawnWeightsOf(pawnTestParams())
+	table := newPawnHashTable(32, 2) // 16 entries per shard
+	tests := []struct {
+		name        string
+		first, next uint64
+		evicts      bool
+	}{
+		{"same slot in one shard", 5, 5 + 16, true},
+		{"same slot in the other shard", 5, 5 + 1<<32, false},
+		{"different slots", 5, 6, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table.store(pawnEntry{key: tt.first, weights: weights})
+			table.store(pawnEntry{key: tt.next, weights: weights})
+			if _, ok := table.probe(tt.first, weights); ok == tt.evicts {
+				t.Errorf("first key found %v after storing the next, want %v", ok, !tt.evicts)
+			}
+			if _, ok := table.probe(tt.next, weights); !ok {
+				t.Error("the key stored last is missing")
+			}
+		})
+	}
+}
+
+func TestPawnTableConcurrentUse(t *testing.T) {
+	fens := []string{
+		"4k3/8/8/8/8/8/P7/4K3 w - - 0 1",
+		"4k3/8/1p6/8/2P5/1P6/8/4K3 w - - 0 1",
+		"4k3/8/8/8/2p1P3/8/3P4/4K3 w - - 0 1",
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+	}
+	params := pawnTestParams()
+	var wg sync.WaitGroup
+	for i := range 8 {
+		board, err := ParseFEN(fens[i%len(fens)])
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := evaluatePawnStructure(board, params).score
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 200 {
+				if got, _ := evaluatePawns(board, params); got != want {
+					t.Errorf("%s: structure %+v, want %+v", fens[i%len(fens)], got, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
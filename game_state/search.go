@@ -1,32 +1,173 @@
 package game_state
 
 import (
+	"context"
 	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const maxWorkers = 11
 
-func BestMove(board Board, depth int, color PieceColor) (best Move, bestScore int) {
+// SearchOptions configures a search. The zero value searches with the handcrafted evaluator.
+type SearchOptions struct {
+	Evaluator Evaluator
+	Context   context.Context
+	Workers   int // goroutines searching root moves; maxWorkers when zero
+	Observer  func(res SearchResult, elapsed time.Duration)
+}
+
+type SearchOption func(*SearchOptions)
+
+// WithEvaluator makes the search score leaf positions with e instead of Evaluate.
+func WithEvaluator(e Evaluator) SearchOption {
+	return func(o *SearchOptions) {
+		o.Evaluator = e
+	}
+}
+
+// WithContext stops the search early once ctx is done. The result of a stopped search is
+// meaningless, so callers should check ctx.Err() before using it.
+func WithContext(ctx context.Context) SearchOption {
+	return func(o *SearchOptions) {
+		o.Context = ctx
+	}
+}
+
+// WithWorkers limits how many goroutines search root moves in parallel.
+func WithWorkers(n int) SearchOption {
+	return func(o *SearchOptions) {
+		o.Workers = n
+	}
+}
+
+// WithObserver calls observe after every completed search to a fixed depth, once per
+// iteration in Analyze. Searches stopped by the context are not observed.
+func WithObserver(observe func(res SearchResult, elapsed time.Duration)) SearchOption {
+	return func(o *SearchOptions) {
+		o.Observer = observe
+	}
+}
+
+// searcher holds the state shared by the workers of a single search.
+type searcher struct {
+	eval     Evaluator
+	leaf     func(board Board, sideToMove PieceColor) int // eval, or its cheaper leafEvaluator path
+	ctx      context.Context
+	workers  int
+	observer func(SearchResult, time.Duration)
+	stopped  atomic.Bool // set once ctx is done; polled at every node
+	nodes    atomic.Int64
+}
+
+func newSearcher(opts []SearchOption) *searcher {
+	var o SearchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Evaluator == nil {
+		o.Evaluator = HandcraftedEvaluator{}
+	}
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	if o.Workers <= 0 {
+		o.Workers = maxWorkers
+	}
+	s := &searcher{eval: o.Evaluator, leaf: o.Evaluator.Evaluate, ctx: o.Context, workers: o.Workers, observer: o.Observer}
+	if l, ok := o.Evaluator.(leafEvaluator); ok {
+		s.leaf = l.evaluateLeaf
+	}
+	return s
+}
+
+// watch sets s.stopped when the context is done. The returned function ends the watch.
+func (s *searcher) watch() (done func()) {
+	if s.ctx.Done() == nil {
+		return func() {}
+	}
+	finished := make(chan struct{})
+	go func() {
+		select {
+		case <-s.ctx.Done():
+			s.stopped.Store(true)
+		case <-finished:
+		}
+	}()
+	return func() { close(finished) }
+}
+
+// SearchResult is the outcome of a search to a fixed depth. Score is from the point of
+// view of the side to move, and PV is the expected line starting with BestMove.
+type SearchResult struct {
+	Depth    int    `json:"depth"`
+	BestMove Move   `json:"best_move"`
+	Score    int    `json:"score"`
+	PV       []Move `json:"pv"`
+	Nodes    int64  `json:"nodes"` // positions visited below the root
+}
+
+func BestMove(board Board, depth int, color PieceColor, opts ...SearchOption) (best Move, bestScore int) {
+	res := Search(board, depth, color, opts...)
+	return res.BestMove, res.Score
+}
+
+// Search is BestMove with the principal variation.
+func Search(board Board, depth int, color PieceColor, opts ...SearchOption) SearchResult {
+	s := newSearcher(opts)
+	if p, ok := s.eval.(boardPreparer); ok {
+		board = p.prepare(board)
+	}
+	defer s.watch()()
+	return s.searchRoot(board, depth, color)
+}
+
+// Analyze searches depth 1, 2, ... up to maxDepth and calls report after each completed
+// depth. It stops early, without reporting the unfinished depth, once the context from
+// WithContext is done.
+func Analyze(board Board, maxDepth int, color PieceColor, report func(SearchResult), opts ...SearchOption) {
+	s := newSearcher(opts)
+	if p, ok := s.eval.(boardPreparer); ok {
+		board = p.prepare(board)
+	}
+	defer s.watch()()
+
+	for depth := 1; depth <= maxDepth; depth++ {
+		res := s.searchRoot(board, depth, color)
+		if s.stopped.Load() {
+			return
+		}
+		report(res)
+	}
+}
+
+func (s *searcher) searchRoot(board Board, depth int, color PieceColor) SearchResult {
 	type result struct {
 		move  Move
 		score int
+		pv    []Move
 	}
 
+	start := time.Now()
+	startNodes := s.nodes.Load()
+
 	jobs := make(chan Move, 100)
 	results := make(chan result, 100)
 	var wg sync.WaitGroup
 
 	// Start worker goroutines
-	for i := 0; i < maxWorkers; i++ {
+	for i := 0; i < s.workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			board := forkSearch(board)
 			for mv := range jobs {
-				child := BoardAfterMove(mv, board)
-				score := -alphaBeta(child, depth-1, -math.MaxInt32, math.MaxInt32, opposite(color))
-				results <- result{mv, score}
+				child := makeMove(mv, board)
+				var pv []Move
+				score := -s.alphaBeta(child, depth-1, -math.MaxInt32, math.MaxInt32, opposite(color), &pv)
+				results <- result{mv, score, pv}
 			}
 		}()
 	}
@@ -36,7 +177,7 @@ func BestMove(board Board, depth int, color PieceColor) (best Move, bestScore in
 		if piece.Color != color {
 			continue
 		}
-		for _, to := range orderedMovesByEval(color, board, piece) {
+		for _, to := range s.orderedMovesByEval(color, board, piece) {
 			jobs <- to
 		}
 	}
@@ -48,39 +189,69 @@ func BestMove(board Board, depth int, color PieceColor) (best Move, bestScore in
 		close(results)
 	}()
 
-	bestScore = math.MinInt32
+	best := SearchResult{Depth: depth, Score: math.MinInt32}
 	for res := range results {
-		if res.score > bestScore {
-			bestScore = res.score
-			best = res.move
+		if res.score > best.Score {
+			best.Score = res.score
+			best.BestMove = res.move
+			best.PV = append([]Move{res.move}, res.pv...)
 		}
 	}
-	return
+	best.Nodes = s.nodes.Load() - startNodes
+	if s.observer != nil && !s.stopped.Load() {
+		s.observer(best, time.Since(start))
+	}
+	return best
 }
 
-func alphaBeta(board Board, depth int, alpha, beta int, color PieceColor) int {
+// alphaBeta returns the score of board for color. pv receives the best line found below
+// this node when the score lands inside the window.
+func (s *searcher) alphaBeta(board Board, depth int, alpha, beta int, color PieceColor, pv *[]Move) int {
+	if s.stopped.Load() {
+		return alpha
+	}
+	s.nodes.Add(1)
 	if depth == 0 {
-		return Evaluate(board, color)
+		return s.leaf(board, color)
 	}
 
+	var childPV []Move
+	hasMoves := false
+
 	for _, piece := range board.PiecesSlice {
 		if piece.Color != color {
 			continue
 		}
-		for _, mv := range orderedMovesByEval(color, board, piece) {
-			child := BoardAfterMove(mv, board)
-			score := -alphaBeta(child, depth-1, -beta, -alpha, opposite(color))
+		for _, mv := range s.orderedMovesByEval(color, board, piece) {
+			hasMoves = true
+			child := makeMove(mv, board)
+			childPV = childPV[:0]
+			score := -s.alphaBeta(child, depth-1, -beta, -alpha, opposite(color), &childPV)
 			if score > alpha {
 				alpha = score
+				*pv = append(append((*pv)[:0], mv), childPV...)
 				if alpha >= beta {
 					return alpha
 				}
 			}
 		}
 	}
+
+	if !hasMoves {
+		// checkmate or stalemate; a mate with more depth left is nearer the root, so worse
+		if !IsKingInCheck(board, color) {
+			return 0
+		}
+		return -INF - depth
+	}
 	return alpha
 }
 
+// IsMateScore reports whether a search score means a forced mate for one side.
+func IsMateScore(score int) bool {
+	return score >= INF-1000 || score <= -(INF-1000)
+}
+
 func opposite(c PieceColor) PieceColor {
 	if c == WhiteColor {
 		return BlackColor
@@ -88,7 +259,7 @@ func opposite(c PieceColor) PieceColor {
 	return WhiteColor
 }
 
-func orderedMovesByEval(color PieceColor, board Board, piece Piece) []Move {
+func (s *searcher) orderedMovesByEval(color PieceColor, board Board, piece Piece) []Move {
 	moves := GenerateAllLegalMoves(piece, board)
 	scored := make([]struct {
 		move  Move
@@ -97,8 +268,8 @@ func orderedMovesByEval(color PieceColor, board Board, piece Piece) []Move {
 
 	for i, to := range moves {
 		mv := Move{From: piece.Pos, To: to}
-		child := BoardAfterMove(mv, board)
-		eval := Evaluate(child, color)
+		child := makeMove(mv, board)
+		eval := s.leaf(child, color)
 		scored[i] = struct {
 			move  Move
 			score int
@@ -110,8 +281,8 @@ func orderedMovesByEval(color PieceColor, board Board, piece Piece) []Move {
 	})
 
 	ordered := make([]Move, len(moves))
-	for i, s := range scored {
-		ordered[i] = s.move
+	for i, sc := range scored {
+		ordered[i] = sc.move
 	}
 	return ordered
 }
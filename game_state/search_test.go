@@ -0,0 +1,93 @@
+package game_state
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestAnalyzeReportsEachDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		maxDepth int
+	}{
+		{"opening", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w - - 2 3", 3},
+		{"black to move", "4k3/8/8/3q4/8/8/3R4/4K3 b - - 0 1", 3},
+		{"mate in one", "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var observed []int
+			var reports []SearchResult
+			Analyze(board, tt.maxDepth, SideToMove(board), func(res SearchResult) {
+				reports = append(reports, res)
+			}, WithObserver(func(res SearchResult, _ time.Duration) {
+				observed = append(observed, res.Depth)
+			}), WithWorkers(1))
+
+			if len(reports) != tt.maxDepth {
+				t.Fatalf("got %d reports, want %d", len(reports), tt.maxDepth)
+			}
+			for i, res := range reports {
+				if res.Depth != i+1 {
+					t.Errorf("report %d is depth %d", i, res.Depth)
+				}
+				if len(res.PV) == 0 || res.PV[0] != res.BestMove || len(res.PV) > res.Depth {
+					t.Errorf("depth %d: PV %v does not start with %v or is too long", res.Depth, res.PV, res.BestMove)
+				}
+				checkLine(t, board, res.PV)
+				if res.Nodes <= 0 {
+					t.Errorf("depth %d: %d nodes", res.Depth, res.Nodes)
+				}
+			}
+			if want := []int{1, 2, 3}[:tt.maxDepth]; !slices.Equal(observed, want) {
+				t.Errorf("observed depths %v, want %v", observed, want)
+			}
+		})
+	}
+}
+
+// checkLine fails the test unless every move of line is legal in turn from board.
+func checkLine(t *testing.T, board Board, line []Move) {
+	t.Helper()
+	for i, m := range line {
+		if !slices.Contains(LegalMoves(board), m) {
+			t.Errorf("PV move %d (%s) is not legal", i, MoveToUCI(board, m))
+			return
+		}
+		board = BoardAfterMove(m, board)
+	}
+}
+
+func TestAnalyzeStopsWithContext(t *testing.T) {
+	board, err := ParseFEN("r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w - - 2 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var depths []int
+	Analyze(board, 20, WhiteColor, func(res SearchResult) {
+		depths = append(depths, res.Depth)
+		if res.Depth == 2 {
+			cancel()
+		}
+	}, WithContext(ctx))
+
+	if !slices.Equal(depths, []int{1, 2}) {
+		t.Errorf("reported depths %v after cancelling at depth 2, want [1 2]", depths)
+	}
+
+	done, stop := context.WithCancel(context.Background())
+	stop()
+	called := false
+	Analyze(board, 3, WhiteColor, func(SearchResult) { called = true }, WithContext(done))
+	if called {
+		t.Error("a search with a finished context reported a depth")
+	}
+}
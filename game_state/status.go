@@ -0,0 +1,73 @@
+package game_state
+
+// Game states reported by GameStatus
+const (
+	Ongoing   = "ongoing"
+	Checkmate = "checkmate"
+	Stalemate = "stalemate"
+	Draw      = "draw"
+)
+
+// Draw reasons
+const (
+	ThreefoldRepetition  = "threefold_repetition"
+	FiftyMoveRule        = "fifty_move_rule"
+	InsufficientMaterial = "insufficient_material"
+)
+
+type Status struct {
+	State  string `json:"state"`
+	Reason string `json:"reason,omitempty"`
+	Winner string `json:"winner,omitempty"` // "white" or "black" after checkmate
+}
+
+// GameStatus reports whether the game is over for the side to move. The board does not
+// track the fifty-move counter, so the caller passes the number of halfmoves since the
+// last capture or pawn move. Repetitions are only detected if board.Played holds the history.
+func GameStatus(board Board, halfmoveClock int) Status {
+	side := SideToMove(board)
+	if !HasLegalMoves(board, side) {
+		if IsKingInCheck(board, side) {
+			winner := "white"
+			if side == WhiteColor {
+				winner = "black"
+			}
+			return Status{State: Checkmate, Winner: winner}
+		}
+		return Status{State: Stalemate}
+	}
+	if board.Played[BoardToFEN(board)] >= 3 {
+		return Status{State: Draw, Reason: ThreefoldRepetition}
+	}
+	if halfmoveClock >= 100 {
+		return Status{State: Draw, Reason: FiftyMoveRule}
+	}
+	if hasInsufficientMaterial(board) {
+		return Status{State: Draw, Reason: InsufficientMaterial}
+	}
+	return Status{State: Ongoing}
+}
+
+// hasInsufficientMaterial covers the dead positions that come up in practice: bare kings,
+// a single minor piece, and bishops that all stand on one square color.
+func hasInsufficientMaterial(board Board) bool {
+	minors := 0
+	bishopSquares := [2]int{}
+	for _, p := range board.PiecesSlice {
+		switch p.Type {
+		case King:
+		case Knight:
+			minors++
+		case Bishop:
+			minors++
+			bishopSquares[(p.Pos.Line+p.Pos.Column)%2]++
+		default:
+			return false
+		}
+	}
+	if minors <= 1 {
+		return true
+	}
+	// only bishops, all on the same color
+	return minors == bishopSquares[0] || minors == bishopSquares[1]
+}
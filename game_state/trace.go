@@ -0,0 +1,75 @@
+package game_state
+
+// EvalTerm is one evaluation term for both sides, each from its own point of view.
+type EvalTerm struct {
+	Name  string     `json:"name"`
+	White PhaseScore `json:"white"`
+	Black PhaseScore `json:"black"`
+	Total int        `json:"total"` // white minus black, tapered by phase; see EvaluateTraceWithParams
+}
+
+// EvalTrace is the breakdown of Evaluate for a position.
+type EvalTrace struct {
+	Fen      string     `json:"fen"`
+	Phase    int        `json:"phase"` // 24 in the opening, 0 in a pawn endgame
+	MaxPhase int        `json:"max_phase"`
+	Terms    []EvalTerm `json:"terms"`
+	Terminal string     `json:"terminal,omitempty"` // checkmate, stalemate or repetition
+	Score    int        `json:"score"`              // white-relative
+}
+
+// EvaluateTrace returns every evaluation term of the position separately, for the side to move in the board.
+func EvaluateTrace(board Board) EvalTrace {
+	return EvaluateTraceWithParams(board, ActiveEvalParams())
+}
+
+// EvaluateTraceWithParams is EvaluateTrace with explicit weights instead of the active ones.
+func EvaluateTraceWithParams(board Board, params *EvalParams) EvalTrace {
+	sideToMove := WhiteColor
+	if !board.WhiteTurn {
+		sideToMove = BlackColor
+	}
+
+	phase := gamePhase(board)
+	trace := EvalTrace{
+		Fen:      BoardToFEN(board),
+		Phase:    phase,
+		MaxPhase: maxPhase,
+	}
+
+	if score, over := terminalScore(board, sideToMove); over {
+		switch {
+		case score != 0:
+			trace.Terminal = "checkmate"
+		case !HasLegalMoves(board, sideToMove):
+			trace.Terminal = "stalemate"
+		default:
+			trace.Terminal = "repetition"
+		}
+		if sideToMove == BlackColor {
+			score = -score // the trace is white-relative
+		}
+		trace.Score = score
+		return trace
+	}
+
+	// Evaluate tapers the sum of the terms, not each term, so a term's total is what it adds
+	// to the tapered running sum. The totals then add up to Score exactly, each within a
+	// point of the term tapered on its own.
+	terms := evaluateTerms(board, params)
+	var sum PhaseScore
+	tapered := 0
+	for i, t := range terms {
+		sum = sum.add(t[WhiteColor]).add(t[BlackColor].scale(-1))
+		total := sum.taper(phase) - tapered
+		tapered += total
+		trace.Terms = append(trace.Terms, EvalTerm{
+			Name:  evalTermNames[i],
+			White: t[WhiteColor],
+			Black: t[BlackColor],
+			Total: total,
+		})
+	}
+	trace.Score = terms.total(phase)
+	return trace
+}
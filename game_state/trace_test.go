@@ -0,0 +1,87 @@
+package game_state
+
+import "testing"
+
+func TestEvaluateTrace(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+	}{
+		{"start", "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"},
+		{"black to move", "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b - - 2 2"},
+		{"endgame", "8/5k2/3p4/3P4/2K5/8/8/8 w - - 0 50"},
+		{"middlegame", "r2q1rk1/pp2bppp/2n1bn2/3p4/3P4/2NBBN2/PP3PPP/R2Q1RK1 w - - 4 11"},
+	}
+	params := DefaultEvalParams()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			trace := EvaluateTraceWithParams(board, params)
+
+			if len(trace.Terms) != numEvalTerms || trace.Terminal != "" {
+				t.Fatalf("trace has %d terms, terminal %q", len(trace.Terms), trace.Terminal)
+			}
+			want := EvaluateWithParams(board, SideToMove(board), params)
+			if !board.WhiteTurn {
+				want = -want
+			}
+			if trace.Score != want {
+				t.Errorf("trace score %d, Evaluate gives %d white-relative", trace.Score, want)
+			}
+
+			sum := 0
+			for i, term := range trace.Terms {
+				if term.Name != evalTermNames[i] {
+					t.Errorf("term %d is %q, want %q", i, term.Name, evalTermNames[i])
+				}
+				alone := term.White.add(term.Black.scale(-1)).taper(trace.Phase)
+				if diff := term.Total - alone; diff < -1 || diff > 1 {
+					t.Errorf("%s: total %d, %d tapered on its own", term.Name, term.Total, alone)
+				}
+				sum += term.Total
+			}
+			if sum != trace.Score {
+				t.Errorf("terms sum to %d, score is %d", sum, trace.Score)
+			}
+		})
+	}
+}
+
+func TestEvaluateTraceTerminal(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		terminal string
+		score    int
+	}{
+		{"white mated", "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w - - 1 3", "checkmate", -INF},
+		{"black mated", "R5k1/5ppp/8/8/8/8/8/6K1 b - - 0 1", "checkmate", INF},
+		{"stalemate", "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1", "stalemate", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			trace := EvaluateTrace(board)
+			if trace.Terminal != tt.terminal || trace.Score != tt.score || len(trace.Terms) != 0 {
+				t.Errorf("terminal %q, score %d, %d terms; want %q, %d and none", trace.Terminal, trace.Score, len(trace.Terms), tt.terminal, tt.score)
+			}
+		})
+	}
+}
+
+func TestEvaluateTraceRepetition(t *testing.T) {
+	board, err := ParseFEN("4k3/8/8/8/8/8/8/R3K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	board.Played = map[string]int{BoardToFEN(board): 2}
+	if trace := EvaluateTrace(board); trace.Terminal != "repetition" || trace.Score != 0 {
+		t.Errorf("terminal %q, score %d; want a drawn repetition", trace.Terminal, trace.Score)
+	}
+}
@@ -0,0 +1,56 @@
+package game_state
+
+// zobristPieces holds one random key per color, piece type and square.
+// Index 0 of the type, line and column dimensions is unused, matching PiecesMatrix.
+var zobristPieces [2][7][9][9]uint64
+var zobristBlackToMove uint64
+
+func init() {
+	// splitmix64 with a fixed seed so hashes are stable across runs
+	seed := uint64(0x9E3779B97F4A7C15)
+	next := func() uint64 {
+		seed += 0x9E3779B97F4A7C15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+
+	for c := 0; c < 2; c++ {
+		for t := Pawn; t <= Queen; t++ {
+			for l := 1; l <= 8; l++ {
+				for f := 1; f <= 8; f++ {
+					zobristPieces[c][t][l][f] = next()
+				}
+			}
+		}
+	}
+	zobristBlackToMove = next()
+}
+
+func zobristPiece(p Piece) uint64 {
+	return zobristPieces[p.Color][p.Type][p.Pos.Line][p.Pos.Column]
+}
+
+// ZobristHash returns a 64-bit hash of the piece placement and side to move.
+func ZobristHash(board Board) uint64 {
+	var h uint64
+	for _, p := range board.PiecesSlice {
+		h ^= zobristPiece(p)
+	}
+	if !board.WhiteTurn {
+		h ^= zobristBlackToMove
+	}
+	return h
+}
+
+// PawnHash returns a Zobrist hash of the pawns only, used to index the pawn hash table.
+func PawnHash(board Board) uint64 {
+	var h uint64
+	for _, p := range board.PiecesSlice {
+		if p.Type == Pawn {
+			h ^= zobristPiece(p)
+		}
+	}
+	return h
+}
@@ -0,0 +1,403 @@
+// Package games keeps in-memory game sessions: the move history from a start position,
+// with repetition and fifty-move state carried across moves, undo, resignation and PGN export.
+package games
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/utils"
+)
+
+const StartFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+
+// Resigned is the status state of a game ended by resignation.
+const Resigned = "resigned"
+
+var (
+	ErrGameOver   = errors.New("game is over")
+	ErrNoMoves    = errors.New("no moves to undo")
+	ErrNotFound   = errors.New("game not found")
+	ErrEngineTurn = errors.New("it is the engine's turn")
+	ErrUndoEngine = errors.New("cannot undo to a position with the engine to move")
+)
+
+// Settings control how the engine plays in a game.
+type Settings struct {
+	Depth       int    `json:"depth"`
+	Profile     string `json:"profile,omitempty"`
+	EngineColor string `json:"engine_color,omitempty"` // "white" or "black" to have the engine reply automatically
+}
+
+// Ply is one move of the history.
+type Ply struct {
+	Number int    `json:"number"` // fullmove number
+	Color  string `json:"color"`
+	UCI    string `json:"uci"`
+	SAN    string `json:"san"`
+	FEN    string `json:"fen"` // position after the move
+	Engine bool   `json:"engine"`
+}
+
+type position struct {
+	board    engine.Board
+	halfmove int // halfmoves since the last capture or pawn move
+}
+
+type Game struct {
+	ID       string
+	Settings Settings
+	Created  time.Time
+	Owner    string // who created the game; empty when anyone may use it
+
+	mu        sync.Mutex
+	startFEN  string
+	firstMove int // fullmove number of the start position
+	positions []position
+	plies     []Ply
+	resigned  string // color that resigned
+
+	// lastTouched is the UnixNano of the last change. It is read without g.mu, which
+	// PlayEngine holds for a whole search.
+	lastTouched atomic.Int64
+}
+
+// Snapshot is the JSON view of a game.
+type Snapshot struct {
+	ID         string        `json:"id"`
+	Settings   Settings      `json:"settings"`
+	StartFEN   string        `json:"start_fen"`
+	FEN        string        `json:"fen"`
+	Turn       string        `json:"turn"`
+	Moves      []Ply         `json:"moves"`
+	Status     engine.Status `json:"status"`
+	Result     string        `json:"result"`
+	Resigned   string        `json:"resigned,omitempty"`
+	LegalMoves []string      `json:"legal_moves"`
+	PGN        string        `json:"pgn"`
+	Created    time.Time     `json:"created_at"`
+}
+
+func newGame(id, fen string, settings Settings, owner string) (*Game, error) {
+	board, err := engine.ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+
+	// FENToBoard leaves the history empty; count the start position for repetitions
+	board.Played = map[string]int{engine.BoardToFEN(board): 1}
+
+	halfmove, fullmove := engine.FENCounters(fen)
+
+	g := &Game{
+		ID:        id,
+		Settings:  settings,
+		Created:   time.Now(),
+		Owner:     owner,
+		startFEN:  fen,
+		firstMove: fullmove,
+		positions: []position{{board: board, halfmove: halfmove}},
+	}
+	g.touch()
+	return g, nil
+}
+
+func (g *Game) touch() {
+	g.lastTouched.Store(time.Now().UnixNano())
+}
+
+func (g *Game) current() position {
+	return g.positions[len(g.positions)-1]
+}
+
+// status is the state of the current position, including resignation. Callers hold g.mu.
+func (g *Game) status() engine.Status {
+	if g.resigned != "" {
+		return engine.Status{State: Resigned, Winner: otherColor(g.resigned)}
+	}
+	pos := g.current()
+	return engine.GameStatus(pos.board, pos.halfmove)
+}
+
+// Board returns the current position and whether the game is still going.
+func (g *Game) Board() (engine.Board, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.current().board, g.status().State == engine.Ongoing
+}
+
+// EngineToMove reports whether the engine plays the side to move.
+func (g *Game) EngineToMove() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.engineToMove()
+}
+
+func (g *Game) engineToMove() bool {
+	return g.Settings.EngineColor == colorName(engine.SideToMove(g.current().board))
+}
+
+// Play makes a human move given in UCI or SAN notation.
+func (g *Game) Play(move string) (Ply, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.status().State != engine.Ongoing {
+		return Ply{}, ErrGameOver
+	}
+	if g.engineToMove() {
+		return Ply{}, ErrEngineTurn
+	}
+	m, err := engine.ParseMove(g.current().board, move)
+	if err != nil {
+		return Ply{}, err
+	}
+	return g.apply(m, false), nil
+}
+
+// Turn is the position PlayEngine asks its search to move in.
+type Turn struct {
+	Board    engine.Board
+	Color    engine.PieceColor
+	LastMove *engine.Move // the move that reached Board, nil at the start of the game
+	Fullmove int
+}
+
+// PlayEngine searches the current position with search and plays the result. The game
+// is locked for the duration of the search, so moves cannot interleave. Nothing is played
+// when search fails.
+func (g *Game) PlayEngine(search func(t Turn) (engine.Move, error)) (Ply, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.status().State != engine.Ongoing {
+		return Ply{}, ErrGameOver
+	}
+	board := g.current().board
+	t := Turn{
+		Board:    board,
+		Color:    engine.SideToMove(board),
+		Fullmove: g.firstMove + (len(g.plies)+g.blackStartOffset())/2,
+	}
+	if len(g.plies) > 0 {
+		last, _ := engine.ParseUCIMove(g.plies[len(g.plies)-1].UCI)
+		t.LastMove = &last
+	}
+	m, err := search(t)
+	if err != nil {
+		return Ply{}, err
+	}
+	if !slices.Contains(engine.LegalMoves(board), m) {
+		return Ply{}, fmt.Errorf("engine returned illegal move %s", engine.MoveToUCI(board, m))
+	}
+	return g.apply(m, true), nil
+}
+
+// apply plays a legal move. Callers hold g.mu.
+func (g *Game) apply(m engine.Move, byEngine bool) Ply {
+	pos := g.current()
+	moved := pos.board.PiecesMatrix[m.From.Line][m.From.Column]
+	captured := pos.board.PiecesMatrix[m.To.Line][m.To.Column].Type != 0
+
+	next := position{board: engine.BoardAfterMove(m, pos.board), halfmove: pos.halfmove + 1}
+	if moved.Type == engine.Pawn || captured {
+		next.halfmove = 0
+	}
+
+	ply := Ply{
+		Number: g.firstMove + (len(g.plies)+g.blackStartOffset())/2,
+		Color:  colorName(moved.Color),
+		UCI:    engine.MoveToUCI(pos.board, m),
+		SAN:    engine.MoveToSAN(pos.board, m),
+		Engine: byEngine,
+	}
+	// the fullmove number advances after black's move
+	ply.FEN = engine.FENWithCounters(next.board, next.halfmove, ply.Number+int(utils.BoolToInt8(ply.Color == "black")))
+
+	g.positions = append(g.positions, next)
+	g.plies = append(g.plies, ply)
+	g.touch()
+	return ply
+}
+
+// Undo takes back the last n plies.
+func (g *Game) Undo(n int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.undo(n)
+}
+
+// Retract takes back played if it is still the last move, for when the engine could not
+// answer it. It reports whether the move was taken back.
+func (g *Game) Retract(played Ply) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.plies) == 0 || g.plies[len(g.plies)-1] != played {
+		return false
+	}
+	g.positions = g.positions[:len(g.positions)-1]
+	g.plies = g.plies[:len(g.plies)-1]
+	g.touch()
+	return true
+}
+
+// UndoToHuman takes back moves until it is the human's turn again, at least one ply.
+func (g *Game) UndoToHuman() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	n := 1
+	if len(g.plies) >= 2 && g.plies[len(g.plies)-1].Engine {
+		n = 2
+	}
+	return g.undo(n)
+}
+
+// undo takes back n plies, unless that leaves the engine to move: nothing would make it
+// play again. Callers hold g.mu.
+func (g *Game) undo(n int) error {
+	if n < 1 || n > len(g.plies) {
+		return ErrNoMoves
+	}
+	if g.Settings.EngineColor == colorName(engine.SideToMove(g.positions[len(g.positions)-1-n].board)) {
+		return ErrUndoEngine
+	}
+	g.positions = g.positions[:len(g.positions)-n]
+	g.plies = g.plies[:len(g.plies)-n]
+	g.resigned = ""
+	g.touch()
+	return nil
+}
+
+// Resign ends the game with color ("white" or "black") losing.
+func (g *Game) Resign(color string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if color != "white" && color != "black" {
+		return fmt.Errorf("color must be white or black, got %q", color)
+	}
+	if g.status().State != engine.Ongoing {
+		return ErrGameOver
+	}
+	g.resigned = color
+	g.touch()
+	return nil
+}
+
+func (g *Game) Snapshot() Snapshot {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	pos := g.current()
+	status := g.status()
+
+	legal := []string{}
+	if status.State == engine.Ongoing {
+		for _, m := range engine.LegalMoves(pos.board) {
+			legal = append(legal, engine.MoveToUCI(pos.board, m))
+		}
+	}
+
+	return Snapshot{
+		ID:         g.ID,
+		Settings:   g.Settings,
+		StartFEN:   g.startFEN,
+		FEN:        g.currentFEN(),
+		Turn:       colorName(engine.SideToMove(pos.board)),
+		Moves:      append([]Ply{}, g.plies...),
+		Status:     status,
+		Result:     result(status),
+		Resigned:   g.resigned,
+		LegalMoves: legal,
+		PGN:        g.pgn(status),
+		Created:    g.Created,
+	}
+}
+
+// currentFEN includes the move counters, which the board itself does not track.
+func (g *Game) currentFEN() string {
+	if len(g.plies) == 0 {
+		return engine.FENWithCounters(g.current().board, g.current().halfmove, g.firstMove)
+	}
+	return g.plies[len(g.plies)-1].FEN
+}
+
+// blackStartOffset is 1 when the start position has black to move, so ply numbering lines up.
+func (g *Game) blackStartOffset() int {
+	if g.positions[0].board.WhiteTurn {
+		return 0
+	}
+	return 1
+}
+
+func (g *Game) pgn(status engine.Status) string {
+	var sb strings.Builder
+	res := result(status)
+	white, black := "Human", "Human"
+	switch g.Settings.EngineColor {
+	case "white":
+		white = "Engine"
+	case "black":
+		black = "Engine"
+	}
+
+	fmt.Fprintf(&sb, "[Event \"Casual game\"]\n[Site \"?\"]\n[Date \"%s\"]\n[Round \"-\"]\n", g.Created.Format("2006.01.02"))
+	fmt.Fprintf(&sb, "[White \"%s\"]\n[Black \"%s\"]\n[Result \"%s\"]\n", white, black, res)
+	if g.startFEN != StartFEN {
+		fmt.Fprintf(&sb, "[SetUp \"1\"]\n[FEN \"%s\"]\n", g.startFEN)
+	}
+	switch {
+	case g.resigned != "":
+		sb.WriteString("[Termination \"" + g.resigned + " resigns\"]\n")
+	case status.Reason != "":
+		sb.WriteString("[Termination \"" + strings.ReplaceAll(status.Reason, "_", " ") + "\"]\n")
+	}
+	sb.WriteByte('\n')
+
+	for i, ply := range g.plies {
+		switch {
+		case ply.Color == "white":
+			fmt.Fprintf(&sb, "%d. ", ply.Number)
+		case i == 0:
+			fmt.Fprintf(&sb, "%d... ", ply.Number)
+		}
+		sb.WriteString(ply.SAN)
+		sb.WriteByte(' ')
+	}
+	sb.WriteString(res)
+	return sb.String()
+}
+
+func result(status engine.Status) string {
+	switch {
+	case status.Winner == "white":
+		return "1-0"
+	case status.Winner == "black":
+		return "0-1"
+	case status.State == engine.Ongoing:
+		return "*"
+	}
+	return "1/2-1/2"
+}
+
+func colorName(c engine.PieceColor) string {
+	if c == engine.WhiteColor {
+		return "white"
+	}
+	return "black"
+}
+
+func otherColor(color string) string {
+	if color == "white" {
+		return "black"
+	}
+	return "white"
+}
@@ -0,0 +1,272 @@
+package games
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/pgn"
+)
+
+func startGame(t *testing.T, fen string, settings Settings, moves ...string) *Game {
+	t.Helper()
+	if fen == "" {
+		fen = StartFEN
+	}
+	g, err := newGame("test", fen, settings, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range moves {
+		if _, err := g.Play(m); err != nil {
+			t.Fatalf("Play(%q): %v", m, err)
+		}
+	}
+	return g
+}
+
+// engineReply returns a search that plays uci.
+func engineReply(uci string) func(Turn) (engine.Move, error) {
+	return func(Turn) (engine.Move, error) { return engine.ParseUCIMove(uci) }
+}
+
+func TestPlayRecordsPlies(t *testing.T) {
+	g := startGame(t, "", Settings{}, "e4", "e7e5", "Nf3")
+	snap := g.Snapshot()
+
+	want := []Ply{
+		{Number: 1, Color: "white", UCI: "e2e4", SAN: "e4", FEN: "rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b - - 0 1"},
+		{Number: 1, Color: "black", UCI: "e7e5", SAN: "e5", FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w - - 0 2"},
+		{Number: 2, Color: "white", UCI: "g1f3", SAN: "Nf3", FEN: "rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b - - 1 2"},
+	}
+	if !slices.Equal(snap.Moves, want) {
+		t.Errorf("moves = %+v\nwant %+v", snap.Moves, want)
+	}
+	if snap.FEN != want[2].FEN || snap.Turn != "black" || snap.Result != "*" {
+		t.Errorf("snapshot FEN %q, turn %q, result %q", snap.FEN, snap.Turn, snap.Result)
+	}
+	if _, err := g.Play("e2e4"); err == nil {
+		t.Error("played a move for the wrong side")
+	}
+}
+
+func TestPGNRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		fen      string
+		settings Settings
+		moves    []string
+		resign   string
+		tags     map[string]string
+	}{
+		{
+			name:  "standard start",
+			moves: []string{"e4", "e5", "Nf3", "Nc6", "Bb5", "a6", "Bxc6", "dxc6"},
+			tags:  map[string]string{"White": "Human", "Black": "Human", "Result": "*"},
+		},
+		{
+			name:     "black to move from a FEN",
+			fen:      "4k3/8/8/8/8/8/4P3/4K3 b - - 5 30",
+			settings: Settings{EngineColor: "white"},
+			moves:    []string{"Kd7"},
+			tags:     map[string]string{"White": "Engine", "SetUp": "1", "FEN": "4k3/8/8/8/8/8/4P3/4K3 b - - 5 30"},
+		},
+		{
+			name:   "resigned",
+			moves:  []string{"d4", "d5"},
+			resign: "white",
+			tags:   map[string]string{"Result": "0-1", "Termination": "white resigns"},
+		},
+		{
+			name:  "checkmate",
+			moves: []string{"f3", "e5", "g4", "Qh4#"},
+			tags:  map[string]string{"Result": "0-1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := startGame(t, tt.fen, Settings{}, tt.moves...)
+			g.Settings = tt.settings
+			if tt.resign != "" {
+				if err := g.Resign(tt.resign); err != nil {
+					t.Fatal(err)
+				}
+			}
+			snap := g.Snapshot()
+
+			parsed, err := pgn.Parse(snap.PGN)
+			if err != nil {
+				t.Fatalf("%v\n%s", err, snap.PGN)
+			}
+			if len(parsed) != 1 {
+				t.Fatalf("exported PGN holds %d games", len(parsed))
+			}
+			for name, want := range tt.tags {
+				if got := parsed[0].Tags[name]; got != want {
+					t.Errorf("tag %s = %q, want %q", name, got, want)
+				}
+			}
+			if parsed[0].Result != snap.Result {
+				t.Errorf("result = %q, want %q", parsed[0].Result, snap.Result)
+			}
+
+			steps, err := parsed[0].Replay()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(steps) != len(snap.Moves) {
+				t.Fatalf("replayed %d of %d moves", len(steps), len(snap.Moves))
+			}
+			for i, step := range steps {
+				if step.SAN != snap.Moves[i].SAN {
+					t.Errorf("ply %d: SAN %q, want %q", i, step.SAN, snap.Moves[i].SAN)
+				}
+				if i > 0 && step.FEN != snap.Moves[i-1].FEN {
+					t.Errorf("ply %d: FEN %q, want %q", i, step.FEN, snap.Moves[i-1].FEN)
+				}
+			}
+		})
+	}
+}
+
+func TestPlayEngine(t *testing.T) {
+	tests := []struct {
+		name     string
+		reply    func(Turn) (engine.Move, error)
+		wantErr  bool
+		wantPlys int
+	}{
+		{"legal reply", engineReply("e7e5"), false, 2},
+		{"illegal reply", engineReply("e7e4"), true, 1},
+		{"search error", func(Turn) (engine.Move, error) { return engine.Move{}, errors.New("timeout") }, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := startGame(t, "", Settings{EngineColor: "black"}, "e4")
+			if _, err := g.Play("d4"); err != ErrEngineTurn {
+				t.Errorf("human move on the engine's turn = %v, want ErrEngineTurn", err)
+			}
+
+			var turn Turn
+			ply, err := g.PlayEngine(func(t Turn) (engine.Move, error) {
+				turn = t
+				return tt.reply(t)
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("PlayEngine err = %v", err)
+			}
+			if turn.Color != engine.BlackColor || turn.Fullmove != 1 || turn.LastMove == nil || engine.MoveToUCI(turn.Board, *turn.LastMove) != "e2e4" {
+				t.Errorf("search was asked %+v", turn)
+			}
+			if err == nil && !ply.Engine {
+				t.Error("engine ply not marked as such")
+			}
+			if n := len(g.Snapshot().Moves); n != tt.wantPlys {
+				t.Errorf("%d plies after PlayEngine, want %d", n, tt.wantPlys)
+			}
+		})
+	}
+}
+
+func TestUndo(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings Settings
+		moves    []string
+		undo     int
+		wantErr  error
+		wantPlys int
+	}{
+		{"one ply", Settings{}, []string{"e4", "e5"}, 1, nil, 1},
+		{"all plies", Settings{}, []string{"e4", "e5"}, 2, nil, 0},
+		{"too many", Settings{}, []string{"e4"}, 2, ErrNoMoves, 1},
+		{"none", Settings{}, []string{"e4"}, 0, ErrNoMoves, 1},
+		{"would leave the engine to move", Settings{EngineColor: "black"}, []string{"e4", "e5"}, 1, ErrUndoEngine, 2},
+		{"back to the human", Settings{EngineColor: "black"}, []string{"e4", "e5"}, 2, nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := startGame(t, "", Settings{}, tt.moves...)
+			g.Settings = tt.settings
+			if err := g.Undo(tt.undo); err != tt.wantErr {
+				t.Errorf("Undo(%d) = %v, want %v", tt.undo, err, tt.wantErr)
+			}
+			if n := len(g.Snapshot().Moves); n != tt.wantPlys {
+				t.Errorf("%d plies left, want %d", n, tt.wantPlys)
+			}
+		})
+	}
+}
+
+func TestUndoToHumanAndRepetition(t *testing.T) {
+	g := startGame(t, "", Settings{EngineColor: "black"})
+	for _, pair := range [][2]string{{"Nf3", "g8f6"}, {"Ng1", "f6g8"}, {"Nf3", "g8f6"}, {"Ng1", "f6g8"}} {
+		if _, err := g.Play(pair[0]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.PlayEngine(engineReply(pair[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if snap := g.Snapshot(); snap.Status.Reason != engine.ThreefoldRepetition {
+		t.Fatalf("status = %+v, want a threefold repetition", snap.Status)
+	}
+
+	if err := g.UndoToHuman(); err != nil {
+		t.Fatal(err)
+	}
+	snap := g.Snapshot()
+	if len(snap.Moves) != 6 || snap.Turn != "white" || snap.Status.State != engine.Ongoing {
+		t.Errorf("after UndoToHuman: %d plies, %s to move, %+v", len(snap.Moves), snap.Turn, snap.Status)
+	}
+}
+
+func TestResign(t *testing.T) {
+	g := startGame(t, "", Settings{}, "e4")
+	if err := g.Resign("red"); err == nil {
+		t.Error("resigned as an unknown color")
+	}
+	if err := g.Resign("black"); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Resign("white"); err != ErrGameOver {
+		t.Errorf("second resignation = %v, want ErrGameOver", err)
+	}
+	if _, err := g.Play("e5"); err != ErrGameOver {
+		t.Errorf("move after resignation = %v, want ErrGameOver", err)
+	}
+	snap := g.Snapshot()
+	if snap.Result != "1-0" || snap.Status.State != Resigned || len(snap.LegalMoves) != 0 {
+		t.Errorf("snapshot after resignation: result %q, status %+v, %d legal moves", snap.Result, snap.Status, len(snap.LegalMoves))
+	}
+
+	if err := g.Undo(1); err != nil {
+		t.Fatal(err)
+	}
+	if snap := g.Snapshot(); snap.Status.State != engine.Ongoing || snap.Resigned != "" {
+		t.Errorf("undo did not lift the resignation: %+v", snap.Status)
+	}
+}
+
+func TestRetract(t *testing.T) {
+	g := startGame(t, "", Settings{})
+	played, err := g.Play("e4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := played
+	other.SAN = "d4"
+	if g.Retract(other) {
+		t.Error("retracted a move that was not played last")
+	}
+	if !g.Retract(played) {
+		t.Error("did not retract the last move")
+	}
+	if g.Retract(played) {
+		t.Error("retracted the same move twice")
+	}
+	if snap := g.Snapshot(); len(snap.Moves) != 0 || snap.Turn != "white" {
+		t.Errorf("after Retract: %d plies, %s to move", len(snap.Moves), snap.Turn)
+	}
+}
@@ -0,0 +1,76 @@
+package games
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+// Store holds the games of one server. When it is full, the game that has gone longest
+// without a move is dropped to make room.
+type Store struct {
+	mu       sync.Mutex
+	games    map[string]*Game
+	maxGames int
+}
+
+func NewStore(maxGames int) *Store {
+	return &Store{games: make(map[string]*Game), maxGames: max(maxGames, 1)}
+}
+
+// Create starts a game for owner from fen, or the standard start position when fen is empty.
+func (s *Store) Create(fen string, settings Settings, owner string) (*Game, error) {
+	if fen == "" {
+		fen = StartFEN
+	}
+	g, err := newGame(NewID(), fen, settings, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.games) >= s.maxGames {
+		s.evictIdle()
+	}
+	s.games[g.ID] = g
+	return g, nil
+}
+
+func (s *Store) Get(id string) (*Game, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	g, ok := s.games[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return g, nil
+}
+
+func (s *Store) Delete(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.games[id]
+	delete(s.games, id)
+	return ok
+}
+
+// evictIdle drops the least recently played game. Callers hold s.mu, so it must not wait
+// for a game's own lock.
+func (s *Store) evictIdle() {
+	var oldestID string
+	var oldest int64
+	for id, g := range s.games {
+		touched := g.lastTouched.Load()
+		if oldestID == "" || touched < oldest {
+			oldestID, oldest = id, touched
+		}
+	}
+	delete(s.games, oldestID)
+}
+
+func NewID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,71 @@
+package games
+
+import "testing"
+
+func TestStoreEvictsIdlest(t *testing.T) {
+	s := NewStore(3)
+	var created []*Game
+	for i := 0; i < 3; i++ {
+		g, err := s.Create("", Settings{}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, g)
+	}
+	for i, g := range created {
+		g.lastTouched.Store(int64(100 - i))
+	}
+	// a move makes the oldest game the most recently played
+	created[2].Play("e4")
+
+	if _, err := s.Create("", Settings{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, false, true} {
+		if _, err := s.Get(created[i].ID); (err == nil) != want {
+			t.Errorf("game %d kept = %v, want %v", i, err == nil, want)
+		}
+	}
+}
+
+func TestStoreEvictsWithoutGameLocks(t *testing.T) {
+	s := NewStore(1)
+	busy, err := s.Create("", Settings{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// PlayEngine holds the game lock for a whole search
+	busy.mu.Lock()
+	defer busy.mu.Unlock()
+
+	if _, err := s.Create("", Settings{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(busy.ID); err != ErrNotFound {
+		t.Errorf("busy game: %v, want ErrNotFound", err)
+	}
+}
+
+func TestStoreCreate(t *testing.T) {
+	s := NewStore(10)
+	g, err := s.Create("", Settings{Depth: 3}, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Owner != "a" || g.Snapshot().StartFEN != StartFEN {
+		t.Errorf("created %+v", g.Snapshot())
+	}
+	if _, err := s.Create("not a fen", Settings{}, ""); err == nil {
+		t.Error("created a game from an invalid FEN")
+	}
+
+	if !s.Delete(g.ID) {
+		t.Error("Delete of an existing game = false")
+	}
+	if s.Delete(g.ID) {
+		t.Error("Delete of a deleted game = true")
+	}
+	if _, err := s.Get(g.ID); err != ErrNotFound {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+}
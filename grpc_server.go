@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/book"
+	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/g0g05arui/chess-engine/enginepb"
+	"github.com/g0g05arui/chess-engine/game_state"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// maxPerftDepth bounds Perft; depth 5 from the start position already takes seconds.
+const maxPerftDepth = 6
+
+// engineServer implements the gRPC service on the same caches, profiles and limits as the
+// HTTP routes.
+type engineServer struct {
+	enginepb.UnimplementedEngineServer
+
+	results       resultCache
+	optsFor       func(profile string) ([]game_state.SearchOption, error)
+	cfg           Config
+	searchTimeout time.Duration
+	shutdown      context.Context // done once the server starts shutting down
+	book          *book.Book      // nil without an opening book
+}
+
+// Analyze streams one SearchInfo per completed depth, like /best-move/stream.
+func (s *engineServer) Analyze(req *enginepb.AnalyzeRequest, stream enginepb.Engine_AnalyzeServer) error {
+	pos, err := positionFrom(req.GetPosition())
+	if err != nil {
+		return err
+	}
+	board := pos.board
+	client := grpcClientFrom(stream.Context())
+	depthLimit := client.MaxDepth(s.cfg.MaxDepth)
+	maxDepth := int(req.GetLimits().GetDepth())
+	if maxDepth == 0 {
+		maxDepth = min(6, depthLimit)
+	}
+	if maxDepth < 1 || maxDepth > depthLimit {
+		return status.Errorf(codes.InvalidArgument, "depth must be between 1 and %d", depthLimit)
+	}
+	timeLimit := client.MaxMoveTimeMS(s.cfg.MaxMoveTimeMS)
+	moveTime := int(req.GetLimits().GetMovetimeMs())
+	if moveTime == 0 {
+		moveTime = min(30000, timeLimit)
+	}
+	if moveTime < 1 || moveTime > timeLimit {
+		return status.Errorf(codes.InvalidArgument, "movetime_ms must be between 1 and %d", timeLimit)
+	}
+	opts, err := s.optsFor(req.GetProfile())
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !game_state.HasLegalMoves(board, game_state.SideToMove(board)) {
+		return status.Error(codes.FailedPrecondition, "the game is over")
+	}
+
+	// the call's deadline and cancellation end the search too, and shutting down ends
+	// it with what has been found so far
+	ctx, cancel := context.WithTimeout(stream.Context(), time.Duration(moveTime)*time.Millisecond)
+	defer cancel()
+	defer context.AfterFunc(s.shutdown, cancel)()
+
+	start := time.Now()
+	key := newResultKey(board, board.WhiteTurn, req.GetProfile())
+	var sendErr error
+	opts = append(slices.Clip(opts), game_state.WithContext(ctx))
+	game_state.Analyze(board, maxDepth, game_state.SideToMove(board), func(res game_state.SearchResult) {
+		if sendErr != nil {
+			return
+		}
+		if res.Depth >= s.cfg.DefaultDepth {
+			s.results.save(key, computed.CacheValue{BestMove: res.BestMove, Depth: res.Depth, Score: res.Score})
+		}
+		info := &enginepb.SearchInfo{
+			Depth:     int32(res.Depth),
+			BestMove:  pbMove(board, res.BestMove),
+			Score:     int32(res.Score),
+			Mate:      game_state.IsMateScore(res.Score),
+			Nodes:     res.Nodes,
+			ElapsedMs: time.Since(start).Milliseconds(),
+		}
+		pvBoard := board
+		for _, m := range res.PV {
+			info.Pv = append(info.Pv, pbMove(pvBoard, m))
+			pvBoard = game_state.BoardAfterMove(m, pvBoard)
+		}
+		if sendErr = stream.Send(info); sendErr != nil {
+			cancel()
+		}
+	}, opts...)
+
+	switch {
+	case sendErr != nil:
+		return sendErr
+	case stream.Context().Err() != nil:
+		return status.FromContextError(stream.Context().Err()).Err()
+	case s.shutdown.Err() != nil:
+		return status.Error(codes.Unavailable, "server shutting down")
+	}
+	return nil // depth or movetime reached
+}
+
+// BestMove answers with a book move, or the deepest cached result at or a little past the
+// requested depth, searching when there is neither.
+func (s *engineServer) BestMove(ctx context.Context, req *enginepb.BestMoveRequest) (*enginepb.BestMoveResponse, error) {
+	pos, err := positionFrom(req.GetPosition())
+	if err != nil {
+		return nil, err
+	}
+	board := pos.board
+	maxDepth := grpcClientFrom(ctx).MaxDepth(s.cfg.MaxDepth)
+	depth := int(req.GetLimits().GetDepth())
+	if depth == 0 {
+		depth = min(s.cfg.DefaultDepth, maxDepth)
+	}
+	if depth < 1 || depth > maxDepth {
+		return nil, status.Errorf(codes.InvalidArgument, "depth must be between 1 and %d", maxDepth)
+	}
+	opts, err := s.optsFor(req.GetProfile())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !game_state.HasLegalMoves(board, game_state.SideToMove(board)) {
+		return nil, status.Error(codes.FailedPrecondition, "the game is over")
+	}
+	if !req.GetNoBook() {
+		if move, ok := s.book.Pick(board, pos.bookKey, book.Ply(pos.fullmove, game_state.SideToMove(board))); ok {
+			return &enginepb.BestMoveResponse{BestMove: pbMove(board, move), Book: true}, nil
+		}
+	}
+
+	key := newResultKey(board, board.WhiteTurn, req.GetProfile())
+	var result computed.CacheValue
+	cached := false
+	for d := depth; d <= min(depth+3, maxDepth); d++ {
+		if v, ok := s.results.lookup(key, d); ok {
+			result, cached = v, true
+		}
+	}
+	if !cached {
+		ctx, cancel := context.WithTimeout(ctx, searchTimeoutFor(grpcClientFrom(ctx), s.searchTimeout, s.cfg.MaxMoveTimeMS))
+		defer cancel()
+		move, score := game_state.BestMove(board, depth, game_state.SideToMove(board), append(slices.Clip(opts), game_state.WithContext(ctx))...)
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+		result = computed.CacheValue{BestMove: move, Depth: depth, Score: score}
+		s.results.save(key, result)
+	}
+
+	return &enginepb.BestMoveResponse{
+		BestMove: pbMove(board, result.BestMove),
+		Score:    int32(result.Score),
+		Depth:    int32(result.Depth),
+		Cached:   cached,
+	}, nil
+}
+
+func (s *engineServer) LegalMoves(ctx context.Context, req *enginepb.LegalMovesRequest) (*enginepb.LegalMovesResponse, error) {
+	pos, err := positionFrom(req.GetPosition())
+	if err != nil {
+		return nil, err
+	}
+	board := pos.board
+	resp := &enginepb.LegalMovesResponse{
+		Fen:     game_state.FENWithCounters(board, pos.halfmove, pos.fullmove),
+		InCheck: game_state.IsKingInCheck(board, game_state.SideToMove(board)),
+	}
+	for _, m := range game_state.LegalMoves(board) {
+		resp.Moves = append(resp.Moves, &enginepb.LegalMove{
+			Move:    pbMove(board, m),
+			San:     game_state.MoveToSAN(board, m),
+			Capture: board.PiecesMatrix[m.To.Line][m.To.Column].Type != 0,
+		})
+	}
+	return resp, nil
+}
+
+// Perft runs under the same deadline as a search, since deep counts take minutes.
+func (s *engineServer) Perft(ctx context.Context, req *enginepb.PerftRequest) (*enginepb.PerftResponse, error) {
+	pos, err := positionFrom(req.GetPosition())
+	if err != nil {
+		return nil, err
+	}
+	board := pos.board
+	depth := int(req.GetDepth())
+	if depth < 1 || depth > maxPerftDepth {
+		return nil, status.Errorf(codes.InvalidArgument, "depth must be between 1 and %d", maxPerftDepth)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.searchTimeout)
+	defer cancel()
+
+	start := time.Now()
+	resp := &enginepb.PerftResponse{}
+	for _, m := range game_state.LegalMoves(board) {
+		n, err := perft(ctx, game_state.BoardAfterMove(m, board), depth-1)
+		if err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		resp.Nodes += n
+		if req.GetDivide() {
+			resp.Divide = append(resp.Divide, &enginepb.PerftResponse_Divide{Move: pbMove(board, m), Nodes: n})
+		}
+	}
+	resp.ElapsedMs = time.Since(start).Milliseconds()
+	return resp, nil
+}
+
+// perft is game_state.Perft checking ctx between subtrees small enough to finish quickly.
+func perft(ctx context.Context, board game_state.Board, depth int) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if depth <= 3 {
+		return int64(game_state.Perft(board, depth, game_state.SideToMove(board))), nil
+	}
+	var total int64
+	for _, m := range game_state.LegalMoves(board) {
+		n, err := perft(ctx, game_state.BoardAfterMove(m, board), depth-1)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// setup is a Position played out: the board with its repetition history for the search,
+// the move counters for the FEN of the result, and the opening book key.
+type setup struct {
+	board              game_state.Board
+	halfmove, fullmove int
+	bookKey            uint64
+}
+
+func positionFrom(p *enginepb.Position) (setup, error) {
+	if p.GetFen() == "" {
+		return setup{}, status.Error(codes.InvalidArgument, "position.fen is required")
+	}
+	board, err := game_state.ParseFEN(p.GetFen())
+	if err != nil {
+		return setup{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	pos := setup{board: board}
+	pos.halfmove, pos.fullmove = game_state.FENCounters(p.GetFen())
+	pos.bookKey, err = book.KeyFEN(p.GetFen())
+	if err != nil {
+		return setup{}, status.Error(codes.InvalidArgument, err.Error())
+	}
+	for i, s := range p.GetMoves() {
+		m, err := game_state.ParseMove(pos.board, s)
+		if err != nil {
+			return setup{}, status.Errorf(codes.InvalidArgument, "move %d: %v", i+1, err)
+		}
+		pos.halfmove++
+		if pos.board.PiecesMatrix[m.From.Line][m.From.Column].Type == game_state.Pawn || pos.board.PiecesMatrix[m.To.Line][m.To.Column].Type != 0 {
+			pos.halfmove = 0
+		}
+		if !pos.board.WhiteTurn {
+			pos.fullmove++
+		}
+		pos.board = game_state.BoardAfterMove(m, pos.board)
+		pos.bookKey = book.Key(pos.board, &m)
+	}
+	return pos, nil
+}
+
+func pbMove(board game_state.Board, m game_state.Move) *enginepb.Move {
+	return &enginepb.Move{
+		From: &enginepb.Square{Line: int32(m.From.Line), Column: int32(m.From.Column)},
+		To:   &enginepb.Square{Line: int32(m.To.Line), Column: int32(m.To.Column)},
+		Uci:  game_state.MoveToUCI(board, m),
+	}
+}
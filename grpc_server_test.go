@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/access"
+	"github.com/g0g05arui/chess-engine/enginepb"
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/jobs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGRPC serves the engine service in memory, with API keys from keysFile when it is
+// not empty, and returns a client for it.
+func startGRPC(t *testing.T, shutdown context.Context, keysFile string) enginepb.EngineClient {
+	t.Helper()
+	var opts []grpc.ServerOption
+	if keysFile != "" {
+		keys, err := access.Load(keysFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unary, stream := grpcAuthenticate(keys, newServerMetrics(jobs.New(jobs.Options{})))
+		opts = append(opts, grpc.UnaryInterceptor(unary), grpc.StreamInterceptor(stream))
+	}
+	srv := grpc.NewServer(opts...)
+	enginepb.RegisterEngineServer(srv, &engineServer{
+		results:       resultCache{defaultDepth: 2, evalID: func(string) string { return "grpc-test" }},
+		optsFor:       func(string) ([]game_state.SearchOption, error) { return nil, nil },
+		cfg:           Config{DefaultDepth: 2, MaxDepth: 5, MaxMoveTimeMS: 20000},
+		searchTimeout: 20 * time.Second,
+		shutdown:      shutdown,
+	})
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return enginepb.NewEngineClient(conn)
+}
+
+func wantCode(t *testing.T, err error, code codes.Code, msg string) {
+	t.Helper()
+	if status.Code(err) != code || !strings.Contains(status.Convert(err).Message(), msg) {
+		t.Errorf("err = %v, want %v containing %q", err, code, msg)
+	}
+}
+
+func TestGRPCBestMove(t *testing.T) {
+	c := startGRPC(t, context.Background(), "")
+	ctx := context.Background()
+	mateInOne := &enginepb.Position{Fen: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"}
+
+	res, err := c.BestMove(ctx, &enginepb.BestMoveRequest{Position: mateInOne, Limits: &enginepb.SearchLimits{Depth: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.GetBestMove().GetUci() != "a1a8" || res.GetDepth() != 3 || res.GetCached() || !game_state.IsMateScore(int(res.GetScore())) {
+		t.Errorf("BestMove = %v, want an uncached mate with a1a8 at depth 3", res)
+	}
+	again, err := c.BestMove(ctx, &enginepb.BestMoveRequest{Position: mateInOne, Limits: &enginepb.SearchLimits{Depth: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !again.GetCached() || again.GetDepth() != 3 {
+		t.Errorf("second BestMove = %v, want the cached depth 3 result", again)
+	}
+
+	tests := []struct {
+		name string
+		req  *enginepb.BestMoveRequest
+		code codes.Code
+		msg  string
+	}{
+		{"no position", &enginepb.BestMoveRequest{}, codes.InvalidArgument, "position.fen is required"},
+		{"bad fen", &enginepb.BestMoveRequest{Position: &enginepb.Position{Fen: "8/8/8 w"}}, codes.InvalidArgument, ""},
+		{"too deep", &enginepb.BestMoveRequest{Position: mateInOne, Limits: &enginepb.SearchLimits{Depth: 6}}, codes.InvalidArgument, "between 1 and 5"},
+		{"game over", &enginepb.BestMoveRequest{Position: &enginepb.Position{Fen: "R5k1/5ppp/8/8/8/8/8/6K1 b - - 1 1"}}, codes.FailedPrecondition, "the game is over"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := c.BestMove(ctx, tt.req)
+			wantCode(t, err, tt.code, tt.msg)
+		})
+	}
+}
+
+func TestGRPCLegalMoves(t *testing.T) {
+	c := startGRPC(t, context.Background(), "")
+	const start = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+	tests := []struct {
+		name    string
+		pos     *enginepb.Position
+		fen     string
+		moves   int
+		inCheck bool
+		code    codes.Code
+		msg     string
+	}{
+		{"start", &enginepb.Position{Fen: start}, start, 20, false, codes.OK, ""},
+		{"after UCI and SAN moves", &enginepb.Position{Fen: start, Moves: []string{"e4", "e7e5", "Nf3"}},
+			"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b - - 1 2", 29, false, codes.OK, ""},
+		{"check", &enginepb.Position{Fen: "4k3/8/8/8/8/8/4r3/4K3 w - - 0 1"}, "4k3/8/8/8/8/8/4r3/4K3 w - - 0 1", 3, true, codes.OK, ""},
+		{"illegal move", &enginepb.Position{Fen: start, Moves: []string{"e4", "e4"}}, "", 0, false, codes.InvalidArgument, "move 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := c.LegalMoves(context.Background(), &enginepb.LegalMovesRequest{Position: tt.pos})
+			if tt.code != codes.OK {
+				wantCode(t, err, tt.code, tt.msg)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.GetFen() != tt.fen || len(res.GetMoves()) != tt.moves || res.GetInCheck() != tt.inCheck {
+				t.Errorf("LegalMoves = %s, %d moves, in check %v; want %s, %d, %v", res.GetFen(), len(res.GetMoves()), res.GetInCheck(), tt.fen, tt.moves, tt.inCheck)
+			}
+		})
+	}
+}
+
+func TestGRPCPerft(t *testing.T) {
+	c := startGRPC(t, context.Background(), "")
+	start := &enginepb.Position{Fen: "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"}
+	tests := []struct {
+		depth int32
+		nodes int64
+	}{
+		{1, 20},
+		{2, 400},
+		{3, 8902},
+		{4, 197281},
+	}
+	for _, tt := range tests {
+		res, err := c.Perft(context.Background(), &enginepb.PerftRequest{Position: start, Depth: tt.depth, Divide: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum int64
+		for _, d := range res.GetDivide() {
+			sum += d.GetNodes()
+		}
+		if res.GetNodes() != tt.nodes || len(res.GetDivide()) != 20 || sum != tt.nodes {
+			t.Errorf("perft %d = %d with %d moves summing to %d, want %d", tt.depth, res.GetNodes(), len(res.GetDivide()), sum, tt.nodes)
+		}
+	}
+	_, err := c.Perft(context.Background(), &enginepb.PerftRequest{Position: start, Depth: maxPerftDepth + 1})
+	wantCode(t, err, codes.InvalidArgument, "depth must be between 1 and 6")
+}
+
+func TestGRPCAnalyze(t *testing.T) {
+	c := startGRPC(t, context.Background(), "")
+	pos := &enginepb.Position{Fen: "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w - - 2 3"}
+	stream, err := c.Analyze(context.Background(), &enginepb.AnalyzeRequest{Position: pos, Limits: &enginepb.SearchLimits{Depth: 3}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var depths []int32
+	for {
+		info, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		depths = append(depths, info.GetDepth())
+		if len(info.GetPv()) == 0 || info.GetPv()[0].GetUci() != info.GetBestMove().GetUci() {
+			t.Errorf("depth %d: PV %v does not start with %v", info.GetDepth(), info.GetPv(), info.GetBestMove())
+		}
+	}
+	if len(depths) != 3 || depths[0] != 1 || depths[2] != 3 {
+		t.Errorf("depths %v, want 1 to 3", depths)
+	}
+
+	tests := []struct {
+		name   string
+		limits *enginepb.SearchLimits
+		msg    string
+	}{
+		{"too deep", &enginepb.SearchLimits{Depth: 6}, "depth must be between 1 and 5"},
+		{"too long", &enginepb.SearchLimits{MovetimeMs: 20001}, "movetime_ms must be between 1 and 20000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := c.Analyze(context.Background(), &enginepb.AnalyzeRequest{Position: pos, Limits: tt.limits})
+			if err == nil {
+				_, err = stream.Recv()
+			}
+			wantCode(t, err, codes.InvalidArgument, tt.msg)
+		})
+	}
+}
+
+func TestGRPCAnalyzeDuringShutdown(t *testing.T) {
+	shutdown, stop := context.WithCancel(context.Background())
+	stop()
+	c := startGRPC(t, shutdown, "")
+	stream, err := c.Analyze(context.Background(), &enginepb.AnalyzeRequest{
+		Position: &enginepb.Position{Fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"},
+		Limits:   &enginepb.SearchLimits{Depth: 5},
+	})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	wantCode(t, err, codes.Unavailable, "shutting down")
+}
+
+func TestGRPCAuthentication(t *testing.T) {
+	keys := writeKeysFile(t, `{"keys": [{"name": "a", "key": "secret-a", "max_depth": 2, "rate_per_minute": 60, "burst": 2}]}`)
+	c := startGRPC(t, context.Background(), keys)
+	req := &enginepb.BestMoveRequest{
+		Position: &enginepb.Position{Fen: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1"},
+		Limits:   &enginepb.SearchLimits{Depth: 3},
+	}
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+
+	_, err := c.BestMove(context.Background(), req)
+	wantCode(t, err, codes.Unauthenticated, "")
+	_, err = c.BestMove(withKey("wrong"), req)
+	wantCode(t, err, codes.Unauthenticated, "")
+
+	// the key's max_depth caps the request
+	_, err = c.BestMove(withKey("secret-a"), req)
+	wantCode(t, err, codes.InvalidArgument, "depth must be between 1 and 2")
+
+	req.Limits.Depth = 2
+	if _, err := c.BestMove(withKey("secret-a"), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// the burst of 2 is spent
+	var header metadata.MD
+	_, err = c.BestMove(withKey("secret-a"), req, grpc.Header(&header))
+	wantCode(t, err, codes.ResourceExhausted, "rate limit exceeded")
+	if len(header.Get("retry-after")) == 0 {
+		t.Error("no retry-after header on a rate-limited call")
+	}
+}
+
+func TestGRPCInterceptor(t *testing.T) {
+	r, m, _, _ := newObservedServer(t, jobs.Options{})
+	intercept := m.unaryInterceptor()
+	tests := []struct {
+		method  string
+		handler grpc.UnaryHandler
+		code    codes.Code
+	}{
+		{"/chess.Engine/BestMove", func(context.Context, any) (any, error) { return "ok", nil }, codes.OK},
+		{"/chess.Engine/BestMove", func(context.Context, any) (any, error) {
+			return nil, status.Error(codes.InvalidArgument, "bad fen")
+		}, codes.InvalidArgument},
+		{"/chess.Engine/Evaluate", func(context.Context, any) (any, error) { panic("boom") }, codes.Internal},
+	}
+	for _, tt := range tests {
+		_, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: tt.method}, tt.handler)
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: code %v, want %v", tt.method, status.Code(err), tt.code)
+		}
+	}
+
+	body := scrape(t, r)
+	for _, line := range []string{
+		`chess_grpc_requests_total{method="BestMove",code="OK"} 1`,
+		`chess_grpc_requests_total{method="BestMove",code="InvalidArgument"} 1`,
+		`chess_grpc_requests_total{method="Evaluate",code="Internal"} 1`,
+		`chess_grpc_request_duration_seconds_count{method="BestMove"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("/metrics lacks %s", line)
+		}
+	}
+}
@@ -0,0 +1,284 @@
+// Package jobs runs background work on a bounded pool of workers. Queued jobs are ordered
+// by priority, which grows each time the same job is requested again, so the positions
+// clients ask about most are searched first.
+package jobs
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull = errors.New("job queue is full")
+	ErrClosed    = errors.New("scheduler is closed")
+)
+
+type State string
+
+const (
+	Queued  State = "queued"
+	Running State = "running"
+)
+
+// RunFunc does the work of a job. It must return promptly once ctx is done.
+type RunFunc func(ctx context.Context)
+
+// Info describes a queued or running job.
+type Info struct {
+	ID       int64      `json:"id"`
+	Key      string     `json:"key"`
+	Params   any        `json:"params,omitempty"`
+	Priority int        `json:"priority"`
+	State    State      `json:"state"`
+	Queued   time.Time  `json:"queued_at"`
+	Started  *time.Time `json:"started_at,omitempty"`
+}
+
+type job struct {
+	Info
+	owner  string // who submitted it; only they can list or cancel it
+	seq    int64  // submission order, breaks priority ties
+	index  int    // position in the queue heap, -1 once dequeued
+	run    RunFunc
+	cancel context.CancelFunc
+}
+
+// Options configures a Scheduler. Zero values select the defaults.
+type Options struct {
+	Workers  int           // jobs run concurrently; 2 by default
+	MaxQueue int           // jobs waiting to run; 256 by default
+	Timeout  time.Duration // per-job time limit; none when zero
+}
+
+type Scheduler struct {
+	opts Options
+
+	mu      sync.Mutex
+	wake    *sync.Cond
+	queue   jobQueue
+	byKey   map[string]*job // queued and running jobs
+	byID    map[int64]*job
+	nextID  int64
+	closed  bool
+	ctx     context.Context
+	stopAll context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func New(opts Options) *Scheduler {
+	if opts.Workers <= 0 {
+		opts.Workers = 2
+	}
+	if opts.MaxQueue <= 0 {
+		opts.MaxQueue = 256
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		opts:    opts,
+		byKey:   make(map[string]*job),
+		byID:    make(map[int64]*job),
+		ctx:     ctx,
+		stopAll: cancel,
+	}
+	s.wake = sync.NewCond(&s.mu)
+	for i := 0; i < opts.Workers; i++ {
+		s.wg.Add(1)
+		go s.worker()
+	}
+	return s
+}
+
+// Submit queues run under key for owner. If a job with the same key is already queued its
+// priority is raised instead, and if it is running nothing happens; both return the existing
+// job, which stays with the owner that first submitted it.
+func (s *Scheduler) Submit(key, owner string, params any, run RunFunc) (Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return Info{}, ErrClosed
+	}
+	if j, ok := s.byKey[key]; ok {
+		if j.State == Queued {
+			j.Priority++
+			heap.Fix(&s.queue, j.index)
+		}
+		return j.Info, nil
+	}
+	if s.queue.Len() >= s.opts.MaxQueue {
+		return Info{}, ErrQueueFull
+	}
+
+	s.nextID++
+	j := &job{
+		Info: Info{
+			ID:       s.nextID,
+			Key:      key,
+			Params:   params,
+			Priority: 1,
+			State:    Queued,
+			Queued:   time.Now(),
+		},
+		owner: owner,
+		seq:   s.nextID,
+		run:   run,
+	}
+	heap.Push(&s.queue, j)
+	s.byKey[key] = j
+	s.byID[j.ID] = j
+	s.wake.Signal()
+	return j.Info, nil
+}
+
+// Cancel drops a queued job or stops a running one. It reports whether owner had such a job.
+func (s *Scheduler) Cancel(id int64, owner string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, ok := s.byID[id]
+	if !ok || j.owner != owner {
+		return false
+	}
+	if j.State == Queued {
+		heap.Remove(&s.queue, j.index)
+		s.forget(j)
+		return true
+	}
+	j.cancel()
+	return true
+}
+
+// Jobs lists owner's running jobs first, then their queued jobs in the order they will run.
+func (s *Scheduler) Jobs(owner string) []Info {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]Info, 0, len(s.byID))
+	queued := make([]*job, 0, s.queue.Len())
+	for _, j := range s.byID {
+		if j.owner != owner {
+			continue
+		}
+		if j.State == Running {
+			infos = append(infos, j.Info)
+		} else {
+			queued = append(queued, j)
+		}
+	}
+	sort.Slice(infos, func(a, b int) bool { return infos[a].ID < infos[b].ID })
+	sort.Slice(queued, func(a, b int) bool { return queued[a].before(queued[b]) })
+	for _, j := range queued {
+		infos = append(infos, j.Info)
+	}
+	return infos
+}
+
+// Stats counts the jobs in each state.
+type Stats struct {
+	Queued   int  `json:"queued"`
+	Running  int  `json:"running"`
+	MaxQueue int  `json:"max_queue"`
+	Closed   bool `json:"closed"`
+}
+
+func (s *Scheduler) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{
+		Queued:   s.queue.Len(),
+		Running:  len(s.byID) - s.queue.Len(),
+		MaxQueue: s.opts.MaxQueue,
+		Closed:   s.closed,
+	}
+}
+
+// Close stops accepting jobs, drops the queue, cancels running jobs and waits for them to return.
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	s.closed = true
+	for s.queue.Len() > 0 {
+		s.forget(heap.Pop(&s.queue).(*job))
+	}
+	s.wake.Broadcast()
+	s.mu.Unlock()
+
+	s.stopAll()
+	s.wg.Wait()
+}
+
+func (s *Scheduler) worker() {
+	defer s.wg.Done()
+	for {
+		s.mu.Lock()
+		for s.queue.Len() == 0 && !s.closed {
+			s.wake.Wait()
+		}
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		j := heap.Pop(&s.queue).(*job)
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if s.opts.Timeout > 0 {
+			ctx, cancel = context.WithTimeout(s.ctx, s.opts.Timeout)
+		} else {
+			ctx, cancel = context.WithCancel(s.ctx)
+		}
+		j.cancel = cancel
+		j.State = Running
+		now := time.Now()
+		j.Started = &now
+		s.mu.Unlock()
+
+		j.run(ctx)
+		cancel()
+
+		s.mu.Lock()
+		s.forget(j)
+		s.mu.Unlock()
+	}
+}
+
+// forget drops j from the indexes. Callers hold s.mu.
+func (s *Scheduler) forget(j *job) {
+	delete(s.byKey, j.Key)
+	delete(s.byID, j.ID)
+}
+
+func (j *job) before(other *job) bool {
+	if j.Priority != other.Priority {
+		return j.Priority > other.Priority
+	}
+	return j.seq < other.seq
+}
+
+// jobQueue is a max-heap on priority, oldest first among equals.
+type jobQueue []*job
+
+func (q jobQueue) Len() int           { return len(q) }
+func (q jobQueue) Less(a, b int) bool { return q[a].before(q[b]) }
+func (q jobQueue) Swap(a, b int) {
+	q[a], q[b] = q[b], q[a]
+	q[a].index = a
+	q[b].index = b
+}
+
+func (q *jobQueue) Push(x any) {
+	j := x.(*job)
+	j.index = len(*q)
+	*q = append(*q, j)
+}
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	j := old[len(old)-1]
+	old[len(old)-1] = nil
+	j.index = -1
+	*q = old[:len(old)-1]
+	return j
+}
@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockWorker occupies the only worker of s until the returned function is called.
+func blockWorker(t *testing.T, s *Scheduler) (release func()) {
+	t.Helper()
+	started := make(chan struct{})
+	done := make(chan struct{})
+	if _, err := s.Submit("blocker", "", nil, func(context.Context) {
+		close(started)
+		<-done
+	}); err != nil {
+		t.Fatal(err)
+	}
+	<-started
+	return func() { close(done) }
+}
+
+func TestSchedulerRunsByPriority(t *testing.T) {
+	tests := []struct {
+		name    string
+		submits []string // a repeated key raises that job's priority
+		want    []string
+	}{
+		{"submission order among equals", []string{"a", "b", "c"}, []string{"a", "b", "c"}},
+		{"repeated key runs first", []string{"a", "b", "c", "c"}, []string{"c", "a", "b"}},
+		{"highest count wins", []string{"a", "b", "b", "c", "c", "c", "a"}, []string{"c", "a", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(Options{Workers: 1})
+			defer s.Close()
+			release := blockWorker(t, s)
+
+			var mu sync.Mutex
+			var ran []string
+			var wg sync.WaitGroup
+			seen := map[string]bool{}
+			for _, key := range tt.submits {
+				if !seen[key] {
+					seen[key] = true
+					wg.Add(1)
+				}
+				if _, err := s.Submit(key, "", nil, func(context.Context) {
+					mu.Lock()
+					ran = append(ran, key)
+					mu.Unlock()
+					wg.Done()
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var queued []string
+			for _, info := range s.Jobs("")[1:] {
+				queued = append(queued, info.Key)
+			}
+			if !slices.Equal(queued, tt.want) {
+				t.Errorf("Jobs = %v, want %v", queued, tt.want)
+			}
+
+			release()
+			wg.Wait()
+			if !slices.Equal(ran, tt.want) {
+				t.Errorf("ran %v, want %v", ran, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerSubmitReturnsExistingJob(t *testing.T) {
+	s := New(Options{Workers: 1})
+	defer s.Close()
+	release := blockWorker(t, s)
+	defer release()
+
+	first, _ := s.Submit("a", "", nil, func(context.Context) {})
+	again, _ := s.Submit("a", "", nil, func(context.Context) {})
+	if again.ID != first.ID || again.Priority != 2 {
+		t.Errorf("resubmit = %+v, want id %d at priority 2", again, first.ID)
+	}
+
+	running, _ := s.Submit("blocker", "", nil, func(context.Context) {})
+	if running.State != Running || running.Priority != 1 {
+		t.Errorf("resubmitting a running job = %+v, want it running at priority 1", running)
+	}
+}
+
+func TestSchedulerQueueFull(t *testing.T) {
+	s := New(Options{Workers: 1, MaxQueue: 2})
+	defer s.Close()
+	release := blockWorker(t, s)
+	defer release()
+
+	for _, key := range []string{"a", "b"} {
+		if _, err := s.Submit(key, "", nil, func(context.Context) {}); err != nil {
+			t.Fatalf("Submit(%q) = %v", key, err)
+		}
+	}
+	if _, err := s.Submit("c", "", nil, func(context.Context) {}); err != ErrQueueFull {
+		t.Errorf("Submit over the limit = %v, want ErrQueueFull", err)
+	}
+	if _, err := s.Submit("a", "", nil, func(context.Context) {}); err != nil {
+		t.Errorf("raising a queued job on a full queue = %v", err)
+	}
+}
+
+func TestSchedulerCancel(t *testing.T) {
+	s := New(Options{Workers: 1})
+	defer s.Close()
+
+	stopped := make(chan struct{})
+	started := make(chan struct{})
+	running, _ := s.Submit("running", "", nil, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+	})
+	<-started
+	queued, _ := s.Submit("queued", "", nil, func(context.Context) {
+		t.Error("cancelled job ran")
+	})
+
+	if !s.Cancel(queued.ID, "") {
+		t.Error("Cancel(queued) = false")
+	}
+	if stats := s.Stats(); stats.Queued != 0 || stats.Running != 1 {
+		t.Errorf("Stats after cancelling the queued job = %+v", stats)
+	}
+	if !s.Cancel(running.ID, "") {
+		t.Error("Cancel(running) = false")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("running job was not cancelled")
+	}
+	if s.Cancel(12345, "") {
+		t.Error("Cancel of an unknown id = true")
+	}
+}
+
+func TestSchedulerOwners(t *testing.T) {
+	s := New(Options{Workers: 1})
+	defer s.Close()
+	release := blockWorker(t, s)
+	defer release()
+
+	a, _ := s.Submit("job-a", "a", nil, func(context.Context) {})
+	s.Submit("job-b", "b", nil, func(context.Context) {})
+	// a resubmitted job stays with the owner that queued it first
+	if again, _ := s.Submit("job-a", "b", nil, func(context.Context) {}); again.ID != a.ID {
+		t.Errorf("resubmit = %+v, want job %d", again, a.ID)
+	}
+
+	tests := []struct {
+		owner string
+		keys  []string
+	}{
+		{"a", []string{"job-a"}},
+		{"b", []string{"job-b"}},
+		{"c", nil},
+	}
+	for _, tt := range tests {
+		var keys []string
+		for _, info := range s.Jobs(tt.owner) {
+			keys = append(keys, info.Key)
+		}
+		if !slices.Equal(keys, tt.keys) {
+			t.Errorf("Jobs(%q) = %q, want %q", tt.owner, keys, tt.keys)
+		}
+	}
+
+	if s.Cancel(a.ID, "b") {
+		t.Error("cancelled another owner's job")
+	}
+	if !s.Cancel(a.ID, "a") || len(s.Jobs("a")) != 0 {
+		t.Error("the owner could not cancel its job")
+	}
+}
+
+func TestSchedulerTimeout(t *testing.T) {
+	s := New(Options{Workers: 1, Timeout: 10 * time.Millisecond})
+	defer s.Close()
+
+	done := make(chan error, 1)
+	s.Submit("slow", "", nil, func(ctx context.Context) {
+		<-ctx.Done()
+		done <- ctx.Err()
+	})
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Errorf("ctx.Err() = %v, want DeadlineExceeded", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("job ran past its timeout")
+	}
+}
+
+func TestSchedulerClose(t *testing.T) {
+	s := New(Options{Workers: 1})
+	release := blockWorker(t, s)
+	s.Submit("queued", "", nil, func(context.Context) {
+		t.Error("queued job ran after Close")
+	})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		release()
+	}()
+	s.Close()
+
+	if _, err := s.Submit("late", "", nil, func(context.Context) {}); err != ErrClosed {
+		t.Errorf("Submit after Close = %v, want ErrClosed", err)
+	}
+	if stats := s.Stats(); stats.Queued != 0 || stats.Running != 0 || !stats.Closed {
+		t.Errorf("Stats after Close = %+v", stats)
+	}
+}
@@ -1,134 +1,563 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"sync"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/g0g05arui/chess-engine/access"
+	"github.com/g0g05arui/chess-engine/api"
+	"github.com/g0g05arui/chess-engine/book"
 	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/g0g05arui/chess-engine/enginepb"
 	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/games"
+	"github.com/g0g05arui/chess-engine/jobs"
+	"github.com/g0g05arui/chess-engine/render"
 	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
-func fetchAndSaveImageAsync(fen string, moveNumber int, wg *sync.WaitGroup) {
-	defer wg.Done()
-
-	url := fmt.Sprintf("https://fen2image.chessvision.ai/%s", fen)
-	resp, err := http.Get(url)
+func main() {
+	cfg, printOnly, err := loadConfig(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		fmt.Printf("Move %d: failed to fetch image: %v\n", moveNumber, err)
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if printOnly {
+		cfg.print(os.Stdout)
 		return
 	}
-	defer resp.Body.Close()
+	slog.SetDefault(cfg.newLogger(os.Stderr))
+	if cfg.LogLevel != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		fmt.Printf("Move %d: bad response: %s\n", moveNumber, resp.Status)
-		return
+	// run returns only once its deferred calls have flushed the analysis store, so exiting
+	// here loses no results
+	if err := run(cfg); err != nil {
+		slog.Error("Server stopped", "err", err)
+		os.Exit(1)
 	}
+}
 
-	filePath := filepath.Join("game_status", fmt.Sprintf("%d.png", moveNumber))
-	out, err := os.Create(filePath)
+// run starts the servers and blocks until they have shut down.
+func run(cfg Config) error {
+	var err error
 
+	// Optional NNUE weights; without them the search uses the handcrafted evaluation
+	searchOpts := []game_state.SearchOption{game_state.WithWorkers(cfg.Workers)}
+	var defaultEval string // fingerprint of the default weights; see resultCache.storeKey
+	if cfg.NNUEFile != "" {
+		net, err := game_state.LoadNetwork(cfg.NNUEFile)
+		if err != nil {
+			return fmt.Errorf("loading NNUE weights: %w", err)
+		}
+		searchOpts = append(searchOpts, game_state.WithEvaluator(game_state.NNUEEvaluator{Net: net}))
+		data, err := os.ReadFile(cfg.NNUEFile)
+		if err != nil {
+			return fmt.Errorf("loading NNUE weights: %w", err)
+		}
+		defaultEval = "nnue:" + weightsFingerprint(data)
+	}
 
+	// Default evaluation profile, plus a directory of named profiles requests can pick with ?profile=
+	activeProfile := "default"
+	if cfg.EvalProfile != "" {
+		params, err := game_state.LoadEvalParams(cfg.EvalProfile)
+		if err != nil {
+			return fmt.Errorf("loading evaluation profile: %w", err)
+		}
+		game_state.SetEvalParams(params)
+		activeProfile = strings.TrimSuffix(filepath.Base(cfg.EvalProfile), filepath.Ext(cfg.EvalProfile))
+	}
+	profiles := newEvalProfiles(cfg.EvalProfileDir)
+	if defaultEval == "" {
+		defaultEval = paramsFingerprint(game_state.ActiveEvalParams())
+	}
 
-	if err != nil {
-		fmt.Printf("Move %d: failed to create file: %v\n", moveNumber, err)
-		return
+	// Optional opening book; its moves are played without searching
+	var openingBook *book.Book
+	if cfg.BookFile != "" {
+		openingBook, err = book.Open(cfg.BookFile, book.Options{Depth: cfg.BookDepth, Selection: book.Selection(cfg.BookSelection)})
+		if err != nil {
+			return fmt.Errorf("loading opening book: %w", err)
+		}
+		slog.Info("Loaded opening book", "entries", openingBook.Len(), "file", cfg.BookFile)
 	}
-	defer out.Close()
 
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		fmt.Printf("Move %d: failed to save image: %v\n", moveNumber, err)
+	computed.Cache.SetCapacity(cfg.CacheCapacity)
+	computed.DeepCache.SetCapacity(cfg.DeepCacheCapacity)
+
+	// Optional on-disk store so deep results survive restarts
+	var store *computed.Store
+	if dir := cfg.AnalysisStoreDir; dir != "" {
+		store, err = computed.OpenStore(dir)
+		if err != nil {
+			return fmt.Errorf("opening analysis store: %w", err)
+		}
+		stopCompaction := store.CompactEvery(10*time.Minute, slog.Default())
+		defer func() {
+			stopCompaction()
+			if err := store.Close(); err != nil {
+				slog.Error("Error closing analysis store", "err", err)
+			}
+		}()
+		slog.Info("Loaded stored results", "count", store.Len(), "dir", dir)
 	}
-}
 
-func main() {
+	// Deeper searches run in the background on a bounded pool
+	scheduler := jobs.New(jobs.Options{
+		Workers:  cfg.JobWorkers,
+		MaxQueue: cfg.JobQueueLimit,
+		Timeout:  time.Duration(cfg.JobTimeoutSeconds) * time.Second,
+	})
+	defer scheduler.Close()
+	searchWorkers := cfg.JobSearchWorkers
+	searchTimeout := time.Duration(cfg.SearchTimeoutSeconds) * time.Second
 
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatal("Error loading .env file")
+	// shutdown is done once SIGTERM arrives; long streams watch it to end early
+	shutdown, startShutdown := context.WithCancel(context.Background())
+	defer startShutdown()
+
+	serverMetrics := newServerMetrics(scheduler)
+	var ready atomic.Bool
+
+	// optsFor returns the search options for a request's evaluation profile
+	optsFor := func(profile string) ([]game_state.SearchOption, error) {
+		opts := append(slices.Clip(searchOpts), serverMetrics.searchObserver(profile))
+		if profile == "" {
+			return opts, nil
+		}
+		params, err := profiles.get(profile)
+		if err != nil {
+			return nil, err
+		}
+		return append(opts, game_state.WithEvaluator(game_state.HandcraftedEvaluator{Params: params})), nil
 	}
-	PORT := os.Getenv("PORT")
 
-	r := gin.Default()
+	results := resultCache{store: store, defaultDepth: cfg.DefaultDepth, evalID: func(profile string) string {
+		if profile == "" {
+			return defaultEval
+		}
+		return profiles.fingerprint(profile)
+	}}
+	defaultDepth := cfg.DefaultDepth
+
+	// metrics sit outside Recovery so panics are counted as the 500s they become
+	r := gin.New()
+	// X-Forwarded-For is only believed from the configured proxies, so clients cannot
+	// pick their own address to dodge per-IP limits
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return fmt.Errorf("invalid trusted_proxies: %w", err)
+	}
+	r.Use(requestLogger(), serverMetrics.middleware(), gin.Recovery(), cors(cfg.CORSOrigins))
+
+	// Optional API keys with per-key rate limits, search quotas and depth/time caps
+	var keys *access.Registry
+	if cfg.APIKeysFile != "" {
+		keys, err = access.Load(cfg.APIKeysFile)
+		if err != nil {
+			return fmt.Errorf("loading API keys: %w", err)
+		}
+		r.Use(authenticate(keys, serverMetrics))
+		slog.Info("API keys enabled", "keys", keys.Len())
+	}
+	serverMetrics.register(r, &ready, scheduler)
 
 	r.GET("/best-move", func(c *gin.Context) {
 		fen := c.DefaultQuery("fen", "")
 		turn := c.DefaultQuery("turn", "white")
+		profile := c.Query("profile")
 		if fen == "" {
 			c.Status(400)
 			return
 		}
 
+		opts, err := optsFor(profile)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
 		color := game_state.WhiteColor
 		if turn != "white" {
 			color = game_state.BlackColor
 		}
-		board := game_state.FENToBoard(fen)
-		cacheKey := computed.CacheKey{Fen: fen, WhiteTurn: turn == "white"}
+		board, err := game_state.ParseFEN(fen)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Book moves are answered straight away, unless ?book=false asks for a search
+		if c.Query("book") != "false" && board.WhiteTurn == (turn == "white") {
+			if move, ok := bookMove(openingBook, board, fen); ok {
+				c.JSON(200, gin.H{"best_move": move, "depth": 0, "score": 0, "book": true})
+				return
+			}
+		}
 
-		const defaultDepth = 4
+		key := newResultKey(board, turn == "white", profile)
+		maxDepth := clientFrom(c).MaxDepth(cfg.MaxDepth)
+		depth := min(defaultDepth, maxDepth)
 
-		// Start with default cached result
-		move, ok := computed.Cache[cacheKey]
-		currentDepth := defaultDepth
+		// Start with the default-depth result, searching now if it is not cached
+		result, ok := results.lookup(key, depth)
 		if !ok {
-			move, _ = game_state.BestMove(board, defaultDepth, color)
-			computed.Cache[cacheKey] = move
+			ctx, cancel := context.WithTimeout(c.Request.Context(), searchTimeoutFor(clientFrom(c), searchTimeout, cfg.MaxMoveTimeMS))
+			defer cancel()
+			move, score := game_state.BestMove(board, depth, color, append(slices.Clip(opts), game_state.WithContext(ctx))...)
+			if ctx.Err() != nil {
+				c.JSON(503, gin.H{"error": searchErr(ctx).Error()})
+				return
+			}
+			result = computed.CacheValue{BestMove: move, Depth: depth, Score: score}
+			results.save(key, result)
 		}
 
-		// Search for the deepest available result in DeepCache
-		for d := defaultDepth + 1; d <= defaultDepth+3; d++ { // Look ahead up to 3 levels
-			deepKey := computed.DeepCacheKey{Fen: fen, WhiteTurn: turn == "white", Depth: d}
-			if val, exists := computed.DeepCache[deepKey]; exists {
-				move = val.BestMove
-				currentDepth = val.Depth
+		// Use the deepest result the background searches have produced so far
+		for d := depth + 1; d <= min(depth+3, maxDepth); d++ { // Look ahead up to 3 levels
+			if deeper, exists := results.lookup(key, d); exists {
+				result = deeper
 			}
 		}
 
 		// Return the best available move
 		c.JSON(200, gin.H{
-			"best_move": move,
-			"depth":     currentDepth,
+			"best_move": result.BestMove,
+			"depth":     result.Depth,
+			"score":     result.Score,
 		})
 
-		// Launch next-depth search if not already present
-		go func(fen string, turn string, board game_state.Board, currentDepth int) {
-			color := game_state.WhiteColor
-			if turn != "white" {
-				color = game_state.BlackColor
+		// Queue the next-depth search unless it is already cached
+		nextDepth := result.Depth + 1
+		if nextDepth > maxDepth || results.has(key, nextDepth) {
+			return
+		}
+		jobKey := fmt.Sprintf("%s|%s|%s|%d", key.fen, turn, profile, nextDepth)
+		jobParams := gin.H{"fen": fen, "turn": turn, "profile": profile, "depth": nextDepth}
+		client := clientFrom(c)
+		holdSearch(c)() // this request's search is over, so the background one can have its slot
+		_, err = scheduler.Submit(jobKey, client.ID(), jobParams, func(ctx context.Context) {
+			// the search counts against the quota of the client that queued it, and is
+			// skipped when that client is already using all of it
+			release, ok := client.AcquireSearch()
+			if !ok {
+				return
 			}
-			nextDepth := currentDepth + 1
-			deepKey := computed.DeepCacheKey{Fen: fen, WhiteTurn: turn == "white", Depth: nextDepth}
-
-			// If already in DeepCache, don't compute
-			if _, exists := computed.DeepCache[deepKey]; exists {
+			defer release()
+			jobOpts := append(slices.Clip(opts), game_state.WithContext(ctx), game_state.WithWorkers(searchWorkers))
+			move, score := game_state.BestMove(board, nextDepth, color, jobOpts...)
+			if ctx.Err() != nil {
 				return
 			}
+			results.save(key, computed.CacheValue{BestMove: move, Depth: nextDepth, Score: score})
+		})
+		if err != nil {
+			slog.Warn("Not queueing background search", "depth", nextDepth, "err", err)
+		}
+	})
+
+	// Streams one "depth" event per completed iteration, then a "done" event
+	r.GET("/best-move/stream", func(c *gin.Context) {
+		fen := c.DefaultQuery("fen", "")
+		turn := c.DefaultQuery("turn", "white")
+		profile := c.Query("profile")
+		if fen == "" {
+			c.Status(400)
+			return
+		}
+		depthLimit := clientFrom(c).MaxDepth(cfg.MaxDepth)
+		maxDepth, err := strconv.Atoi(c.DefaultQuery("depth", strconv.Itoa(min(6, depthLimit))))
+		if err != nil || maxDepth < 1 || maxDepth > depthLimit {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("depth must be between 1 and %d", depthLimit)})
+			return
+		}
+		timeLimit := clientFrom(c).MaxMoveTimeMS(cfg.MaxMoveTimeMS)
+		moveTime, err := strconv.Atoi(c.DefaultQuery("movetime", strconv.Itoa(min(30000, timeLimit))))
+		if err != nil || moveTime < 1 || moveTime > timeLimit {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("movetime must be between 1 and %d ms", timeLimit)})
+			return
+		}
+		opts, err := optsFor(profile)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		color := game_state.WhiteColor
+		if turn != "white" {
+			color = game_state.BlackColor
+		}
+		board, err := game_state.ParseFEN(fen)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		key := newResultKey(board, turn == "white", profile)
+
+		// the request context also ends the search when the client goes away, and
+		// shutting down ends it with what has been found so far
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(moveTime)*time.Millisecond)
+		defer cancel()
+		defer context.AfterFunc(shutdown, cancel)()
+		clearWriteDeadline(c)
+
+		updates := make(chan game_state.SearchResult)
+		go func() {
+			defer close(updates)
+			opts := append(slices.Clip(opts), game_state.WithContext(ctx))
+			game_state.Analyze(board, maxDepth, color, func(res game_state.SearchResult) {
+				select {
+				case updates <- res:
+				case <-ctx.Done():
+				}
+			}, opts...)
+		}()
+
+		reached := 0
+		c.Stream(func(w io.Writer) bool {
+			res, ok := <-updates
+			if !ok {
+				return false
+			}
+			reached = res.Depth
+			c.SSEvent("depth", res)
+
+			// completed depths are as good as background results, so keep them
+			if res.Depth >= defaultDepth {
+				results.save(key, computed.CacheValue{BestMove: res.BestMove, Depth: res.Depth, Score: res.Score})
+			}
+			return true
+		})
 
-			// If already computing, don't start again
-			if _, alreadyComputing := computed.InProgress.LoadOrStore(deepKey, struct{}{}); alreadyComputing {
+		reason := "depth"
+		if reached < maxDepth {
+			reason = "movetime"
+			if shutdown.Err() != nil {
+				reason = "shutdown"
+			}
+			if c.Request.Context().Err() != nil {
 				return
 			}
+		}
+		c.SSEvent("done", gin.H{"reason": reason, "depth": reached})
+	})
+
+	r.GET("/image", func(c *gin.Context) {
+		fen := c.DefaultQuery("fen", "")
+		if fen == "" {
+			c.Status(400)
+			return
+		}
+		opts, err := imageOptions(c)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		board, err := game_state.ParseFEN(fen)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
 
-			// Defer removing from in-progress after we're done
-			defer computed.InProgress.Delete(deepKey)
+		var buf bytes.Buffer
+		contentType := "image/png"
+		switch c.DefaultQuery("format", "png") {
+		case "png":
+			err = render.PNG(&buf, board, opts)
+		case "svg":
+			contentType = "image/svg+xml"
+			err = render.SVG(&buf, board, opts)
+		default:
+			c.JSON(400, gin.H{"error": "format must be png or svg"})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Data(200, contentType, buf.Bytes())
+	})
+
+	registerGameRoutes(r, games.NewStore(cfg.MaxGames), optsFor, min(maxGameDepth, cfg.MaxDepth), searchTimeout, cfg.MaxMoveTimeMS, openingBook)
+	batches := newBatchRunner(results, optsFor, cfg, shutdown)
+	batches.register(r)
+	registerReviewRoutes(r, batches)
+
+	r.GET("/jobs", func(c *gin.Context) {
+		c.JSON(200, gin.H{"jobs": scheduler.Jobs(clientFrom(c).ID())})
+	})
+
+	r.DELETE("/jobs/:id", func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.Status(400)
+			return
+		}
+		if !scheduler.Cancel(id, clientFrom(c).ID()) {
+			c.Status(404)
+			return
+		}
+		c.Status(204)
+	})
+
+	r.GET("/position", func(c *gin.Context) {
+		fen := c.Query("fen")
+		if fen == "" {
+			c.JSON(400, gin.H{"error": "fen is required"})
+			return
+		}
+		board, err := game_state.ParseFEN(fen)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, describePosition(board, fen))
+	})
+
+	r.GET("/eval", func(c *gin.Context) {
+		fen := c.DefaultQuery("fen", "")
+		if fen == "" {
+			c.Status(400)
+			return
+		}
 
-			fmt.Printf("Computing deeper best move for depth %d...\n", nextDepth)
-			move, _ := game_state.BestMove(board, nextDepth, color)
-			computed.DeepCache[deepKey] = computed.DeepCacheValue{
-				BestMove: move,
-				Depth:    nextDepth,
+		params := game_state.ActiveEvalParams()
+		if profile := c.Query("profile"); profile != "" {
+			var err error
+			if params, err = profiles.get(profile); err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
 			}
-		}(fen, turn, board, currentDepth)
+		}
+
+		board, err := game_state.ParseFEN(fen)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, game_state.EvaluateTraceWithParams(board, params))
+	})
 
+	r.GET("/profile", func(c *gin.Context) {
+		name := c.Query("name")
+		if name == "" {
+			c.JSON(200, gin.H{"name": activeProfile, "params": game_state.ActiveEvalParams()})
+			return
+		}
+
+		params, err := profiles.get(name)
+		if err != nil {
+			c.JSON(404, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"name": name, "params": params})
 	})
 
-	r.Run(":" + PORT)
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(200, "application/json", api.Spec)
+	})
+	for _, route := range r.Routes() {
+		if !api.Documented(route.Method, route.Path) {
+			slog.Warn("Route missing from api/openapi.json", "method", route.Method, "path", route.Path)
+		}
+	}
+
+	// Optional gRPC service on its own port, behind the same keys and limits
+	var grpcServer *grpc.Server
+	if cfg.GRPCPort != 0 {
+		unary := []grpc.UnaryServerInterceptor{serverMetrics.unaryInterceptor()}
+		stream := []grpc.StreamServerInterceptor{serverMetrics.streamInterceptor()}
+		if keys != nil {
+			authUnary, authStream := grpcAuthenticate(keys, serverMetrics)
+			unary, stream = append(unary, authUnary), append(stream, authStream)
+		}
+		grpcServer = grpc.NewServer(grpc.ChainUnaryInterceptor(unary...), grpc.ChainStreamInterceptor(stream...))
+		enginepb.RegisterEngineServer(grpcServer, &engineServer{
+			results:       results,
+			optsFor:       optsFor,
+			cfg:           cfg,
+			searchTimeout: searchTimeout,
+			shutdown:      shutdown,
+			book:          openingBook,
+		})
+	}
+
+	ready.Store(true)
+	err = serve(cfg, r, grpcServer, func() {
+		ready.Store(false)
+		startShutdown()
+	})
+	// the deferred calls cancel background searches and flush the analysis store
+	slog.Info("Stopping background searches")
+	return err
+}
+
+// imageOptions reads the /image query: size, orientation (white|black), coords,
+// lastmove (e.g. e2e4) and arrows (comma-separated, e.g. g1f3,d2d4).
+func imageOptions(c *gin.Context) (render.Options, error) {
+	var opts render.Options
+	if size := c.Query("size"); size != "" {
+		n, err := strconv.Atoi(size)
+		if err != nil || n < render.MinSize || n > render.MaxSize {
+			return opts, fmt.Errorf("size must be between %d and %d", render.MinSize, render.MaxSize)
+		}
+		opts.Size = n
+	}
+	switch c.DefaultQuery("orientation", "white") {
+	case "white":
+	case "black":
+		opts.Flipped = true
+	default:
+		return opts, errors.New("orientation must be white or black")
+	}
+	opts.Coordinates = c.Query("coords") == "true" || c.Query("coords") == "1"
+	if lm := c.Query("lastmove"); lm != "" {
+		m, err := game_state.ParseUCIMove(lm)
+		if err != nil {
+			return opts, err
+		}
+		opts.LastMove = &m
+	}
+	if arrows := c.Query("arrows"); arrows != "" {
+		for _, a := range strings.Split(arrows, ",") {
+			m, err := game_state.ParseUCIMove(strings.TrimSpace(a))
+			if err != nil {
+				return opts, err
+			}
+			opts.Arrows = append(opts.Arrows, render.Arrow{From: m.From, To: m.To})
+		}
+	}
+	return opts, nil
+}
+
+// bookMove picks a move for a FEN from the opening book, with the FEN's castling rights,
+// en passant square and move number.
+func bookMove(b *book.Book, board game_state.Board, fen string) (game_state.Move, bool) {
+	if b == nil {
+		return game_state.Move{}, false
+	}
+	key, err := book.KeyFEN(fen)
+	if err != nil {
+		return game_state.Move{}, false
+	}
+	_, fullmove := game_state.FENCounters(fen)
+	return b.Pick(board, key, book.Ply(fullmove, game_state.SideToMove(board)))
 }
@@ -0,0 +1,244 @@
+// Package metrics keeps counters, gauges and histograms and writes them in the Prometheus
+// text exposition format. It covers what the server exports and nothing more: no summaries,
+// no exemplars, no registry per request.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets suit request latencies in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60}
+
+// Registry holds every metric the /metrics endpoint writes, in registration order.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []collector
+	names   map[string]bool
+}
+
+type collector interface {
+	describe() (name, help, kind string)
+	write(w io.Writer)
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	name, _, _ := c.describe()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	r.metrics = append(r.metrics, c)
+}
+
+// Write writes all metrics in the Prometheus text format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	metrics := append([]collector(nil), r.metrics...)
+	r.mu.Unlock()
+
+	for _, c := range metrics {
+		name, help, kind := c.describe()
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		c.write(w)
+	}
+}
+
+// desc is the part every metric shares.
+type desc struct {
+	name, help string
+	labels     []string
+}
+
+// series formats name{l1="v1",...} for a set of label values.
+func (d desc) series(name string, values []string, extra ...string) string {
+	pairs := make([]string, 0, len(values)+len(extra)/2)
+	for i, v := range values {
+		pairs = append(pairs, d.labels[i]+`="`+escape(v)+`"`)
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, extra[i]+`="`+escape(extra[i+1])+`"`)
+	}
+	if len(pairs) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (d desc) check(values []string) {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+}
+
+// CounterVec is a family of counters split by labels.
+type CounterVec struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{desc: desc{name, help, labels}, values: make(map[string]*counterValue)}
+	r.register(c)
+	return c
+}
+
+// Add increases the counter for the label values by delta, which must not be negative.
+func (c *CounterVec) Add(delta float64, values ...string) {
+	c.check(values)
+	key := strings.Join(values, "\xff")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labels: values}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *CounterVec) describe() (string, string, string) { return c.name, c.help, "counter" }
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		fmt.Fprintf(w, "%s %s\n", c.series(c.name, v.labels), formatFloat(v.value))
+	}
+}
+
+// HistogramVec is a family of histograms split by labels.
+type HistogramVec struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labels []string
+	counts []uint64 // per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewHistogramVec uses buckets as upper bounds, which must be sorted; +Inf is implied.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{desc: desc{name, help, labels}, buckets: buckets, values: make(map[string]*histogramValue)}
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.check(values)
+	key := strings.Join(values, "\xff")
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[key]
+	if !ok {
+		hv = &histogramValue{labels: values, counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hv
+	}
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.count++
+	hv.sum += v
+}
+
+func (h *HistogramVec) describe() (string, string, string) { return h.name, h.help, "histogram" }
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, key := range sortedKeys(h.values) {
+		hv := h.values[key]
+		var cumulative uint64
+		for i, le := range h.buckets {
+			cumulative += hv.counts[i]
+			fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", hv.labels, "le", formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_bucket", hv.labels, "le", "+Inf"), hv.count)
+		fmt.Fprintf(w, "%s %s\n", h.series(h.name+"_sum", hv.labels), formatFloat(hv.sum))
+		fmt.Fprintf(w, "%s %d\n", h.series(h.name+"_count", hv.labels), hv.count)
+	}
+}
+
+// Sample is one labelled value read by a func metric.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// funcMetric reads its values when scraped, for state another package already tracks.
+type funcMetric struct {
+	desc
+	kind string
+	read func() []Sample
+}
+
+// NewGaugeFunc registers a gauge whose samples read returns at scrape time.
+func (r *Registry) NewGaugeFunc(name, help string, read func() []Sample, labels ...string) {
+	r.register(&funcMetric{desc: desc{name, help, labels}, kind: "gauge", read: read})
+}
+
+// NewCounterFunc registers a counter whose samples read returns at scrape time. The values
+// must only grow.
+func (r *Registry) NewCounterFunc(name, help string, read func() []Sample, labels ...string) {
+	r.register(&funcMetric{desc: desc{name, help, labels}, kind: "counter", read: read})
+}
+
+func (f *funcMetric) describe() (string, string, string) { return f.name, f.help, f.kind }
+
+func (f *funcMetric) write(w io.Writer) {
+	for _, s := range f.read() {
+		f.check(s.Labels)
+		fmt.Fprintf(w, "%s %s\n", f.series(f.name, s.Labels), formatFloat(s.Value))
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
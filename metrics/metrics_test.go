@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	reg := NewRegistry()
+	requests := reg.NewCounterVec("http_requests_total", "Requests.", "route", "code")
+	latency := reg.NewHistogramVec("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+	reg.NewGaugeFunc("queue", "Queued jobs.", func() []Sample {
+		return []Sample{{Labels: []string{"deep"}, Value: 3}, {Labels: []string{"default"}, Value: 0.5}}
+	}, "cache")
+	reg.NewCounterFunc("uptime_total", "Ticks.", func() []Sample {
+		return []Sample{{Value: math.Inf(1)}}
+	})
+
+	requests.Inc("/move", "200")
+	requests.Inc("/move", "200")
+	requests.Add(2.5, `/a"b\c`, "500")
+	latency.Observe(0.05, "/move")
+	latency.Observe(0.1, "/move") // upper bounds are inclusive
+	latency.Observe(0.5, "/move")
+	latency.Observe(7, "/move")
+
+	var sb strings.Builder
+	reg.Write(&sb)
+	want := `# HELP http_requests_total Requests.
+# TYPE http_requests_total counter
+http_requests_total{route="/a\"b\\c",code="500"} 2.5
+http_requests_total{route="/move",code="200"} 2
+# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/move",le="0.1"} 2
+latency_seconds_bucket{route="/move",le="1"} 3
+latency_seconds_bucket{route="/move",le="+Inf"} 4
+latency_seconds_sum{route="/move"} 7.65
+latency_seconds_count{route="/move"} 4
+# HELP queue Queued jobs.
+# TYPE queue gauge
+queue{cache="deep"} 3
+queue{cache="default"} 0.5
+# HELP uptime_total Ticks.
+# TYPE uptime_total counter
+uptime_total +Inf
+`
+	if got := sb.String(); got != want {
+		t.Errorf("Write =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistryPanics(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(reg *Registry)
+		want string
+	}{
+		{"duplicate name", func(reg *Registry) {
+			reg.NewCounterVec("x_total", "X.")
+			reg.NewHistogramVec("x_total", "X.", DefaultBuckets)
+		}, "duplicate metric x_total"},
+		{"missing label value", func(reg *Registry) {
+			reg.NewCounterVec("x_total", "X.", "route", "code").Inc("/move")
+		}, "wants 2 label values, got 1"},
+		{"extra label value", func(reg *Registry) {
+			reg.NewHistogramVec("x_seconds", "X.", DefaultBuckets).Observe(1, "/move")
+		}, "wants 0 label values, got 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				p := recover()
+				if s, _ := p.(string); !strings.Contains(s, tt.want) {
+					t.Errorf("panic = %v, want %q", p, tt.want)
+				}
+			}()
+			tt.f(NewRegistry())
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCORS(t *testing.T) {
+	tests := []struct {
+		name        string
+		origins     []string
+		origin      string
+		preflight   bool
+		code        int
+		allowOrigin string
+		vary        string
+	}{
+		{"any origin", []string{"*"}, "https://a.example", false, 200, "*", ""},
+		{"listed origin", []string{"https://a.example"}, "https://a.example", false, 200, "https://a.example", "Origin"},
+		{"other origin", []string{"https://a.example"}, "https://b.example", false, 200, "", ""},
+		{"no origins", nil, "https://a.example", false, 200, "", ""},
+		{"preflight", []string{"*"}, "https://a.example", true, 204, "*", ""},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(cors(tt.origins))
+			r.GET("/position", func(c *gin.Context) { c.Status(200) })
+			r.OPTIONS("/position", func(c *gin.Context) { c.Status(200) })
+
+			method := "GET"
+			if tt.preflight {
+				method = "OPTIONS"
+			}
+			req := httptest.NewRequest(method, "/position", nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "GET")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			h := w.Header()
+			if w.Code != tt.code || h.Get("Access-Control-Allow-Origin") != tt.allowOrigin || h.Get("Vary") != tt.vary {
+				t.Errorf("%d, allow origin %q, vary %q; want %d, %q, %q", w.Code, h.Get("Access-Control-Allow-Origin"), h.Get("Vary"), tt.code, tt.allowOrigin, tt.vary)
+			}
+			if want := "Content-Type, Authorization, X-API-Key"; tt.preflight && h.Get("Access-Control-Allow-Headers") != want {
+				t.Errorf("allow headers %q, want %q", h.Get("Access-Control-Allow-Headers"), want)
+			}
+		})
+	}
+}
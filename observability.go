@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/jobs"
+	"github.com/g0g05arui/chess-engine/metrics"
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// serverMetrics are the series /metrics exports.
+type serverMetrics struct {
+	registry *metrics.Registry
+
+	requests        *metrics.CounterVec
+	requestDuration *metrics.HistogramVec
+	searches        *metrics.CounterVec
+	nodes           *metrics.CounterVec
+	nps             *metrics.HistogramVec
+	depth           *metrics.HistogramVec
+	apiRequests     *metrics.CounterVec
+	apiRejected     *metrics.CounterVec
+	grpcRequests    *metrics.CounterVec
+	grpcDuration    *metrics.HistogramVec
+}
+
+func newServerMetrics(scheduler *jobs.Scheduler) *serverMetrics {
+	reg := metrics.NewRegistry()
+	m := &serverMetrics{
+		registry: reg,
+		requests: reg.NewCounterVec("chess_http_requests_total",
+			"HTTP requests by route and status code.", "method", "route", "code"),
+		requestDuration: reg.NewHistogramVec("chess_http_request_duration_seconds",
+			"HTTP request latency by route.", metrics.DefaultBuckets, "method", "route"),
+		searches: reg.NewCounterVec("chess_searches_total",
+			"Completed fixed-depth searches, counting each iteration of iterative deepening.", "profile"),
+		nodes: reg.NewCounterVec("chess_search_nodes_total",
+			"Positions visited by completed searches.", "profile"),
+		nps: reg.NewHistogramVec("chess_search_nodes_per_second",
+			"Search speed of completed searches.",
+			[]float64{1e3, 5e3, 1e4, 5e4, 1e5, 2.5e5, 5e5, 1e6, 2.5e6, 5e6}, "profile"),
+		depth: reg.NewHistogramVec("chess_search_depth",
+			"Depth of completed searches.", []float64{1, 2, 3, 4, 5, 6, 7, 8, 10, 12}, "profile"),
+		apiRequests: reg.NewCounterVec("chess_api_requests_total",
+			"Authenticated requests by API key name and status code.", "key", "code"),
+		apiRejected: reg.NewCounterVec("chess_api_rejected_total",
+			"Requests refused by authentication, rate limits or search quotas.", "key", "reason"),
+		grpcRequests: reg.NewCounterVec("chess_grpc_requests_total",
+			"gRPC calls by method and status code.", "method", "code"),
+		grpcDuration: reg.NewHistogramVec("chess_grpc_request_duration_seconds",
+			"gRPC call latency by method, including the whole of streams.", metrics.DefaultBuckets, "method"),
+	}
+
+	// computed.Cache holds default-depth results, computed.DeepCache the others
+	cacheSamples := func(field func(computed.CacheStats) float64) func() []metrics.Sample {
+		return func() []metrics.Sample {
+			return []metrics.Sample{
+				{Labels: []string{"default"}, Value: field(computed.Cache.Stats())},
+				{Labels: []string{"deep"}, Value: field(computed.DeepCache.Stats())},
+			}
+		}
+	}
+	reg.NewCounterFunc("chess_cache_hits_total", "Result cache lookups that found an entry.",
+		cacheSamples(func(s computed.CacheStats) float64 { return float64(s.Hits) }), "cache")
+	reg.NewCounterFunc("chess_cache_misses_total", "Result cache lookups that found nothing.",
+		cacheSamples(func(s computed.CacheStats) float64 { return float64(s.Misses) }), "cache")
+	reg.NewCounterFunc("chess_cache_evictions_total", "Entries dropped to make room.",
+		cacheSamples(func(s computed.CacheStats) float64 { return float64(s.Evictions) }), "cache")
+	reg.NewGaugeFunc("chess_cache_entries", "Entries in the result cache.",
+		cacheSamples(func(s computed.CacheStats) float64 { return float64(s.Len) }), "cache")
+
+	reg.NewGaugeFunc("chess_background_jobs", "Background deepening searches by state.", func() []metrics.Sample {
+		stats := scheduler.Stats()
+		return []metrics.Sample{
+			{Labels: []string{string(jobs.Queued)}, Value: float64(stats.Queued)},
+			{Labels: []string{string(jobs.Running)}, Value: float64(stats.Running)},
+		}
+	}, "state")
+	return m
+}
+
+// middleware counts requests and their latency, labelled by route pattern rather than URL
+// so query strings and ids do not explode the series count.
+func (m *serverMetrics) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		m.requests.Inc(c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+		m.requestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route)
+	}
+}
+
+// searchObserver records completed searches for a profile; see game_state.WithObserver.
+func (m *serverMetrics) searchObserver(profile string) game_state.SearchOption {
+	if profile == "" {
+		profile = "default"
+	}
+	return game_state.WithObserver(func(res game_state.SearchResult, elapsed time.Duration) {
+		m.searches.Inc(profile)
+		m.nodes.Add(float64(res.Nodes), profile)
+		m.depth.Observe(float64(res.Depth), profile)
+		if secs := elapsed.Seconds(); secs > 0 {
+			m.nps.Observe(float64(res.Nodes)/secs, profile)
+		}
+	})
+}
+
+// unaryInterceptor and streamInterceptor are middleware for gRPC calls. Like Recovery for
+// HTTP they turn a panic into an error, here Internal, and count it.
+func (m *serverMetrics) unaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer m.observeCall(info.FullMethod, time.Now(), &err)
+		return handler(ctx, req)
+	}
+}
+
+func (m *serverMetrics) streamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer m.observeCall(info.FullMethod, time.Now(), &err)
+		return handler(srv, ss)
+	}
+}
+
+func (m *serverMetrics) observeCall(fullMethod string, start time.Time, err *error) {
+	if p := recover(); p != nil {
+		slog.Error("Panic in gRPC call", "method", fullMethod, "panic", p, "stack", string(debug.Stack()))
+		*err = status.Error(codes.Internal, "internal error")
+	}
+	method := path.Base(fullMethod)
+	m.grpcRequests.Inc(method, status.Code(*err).String())
+	m.grpcDuration.Observe(time.Since(start).Seconds(), method)
+}
+
+func (m *serverMetrics) register(r *gin.Engine, ready *atomic.Bool, scheduler *jobs.Scheduler) {
+	r.GET("/metrics", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(200)
+		m.registry.Write(c.Writer)
+	})
+
+	// Liveness: the process is up and serving HTTP
+	r.GET("/healthz", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": "ok"})
+	})
+
+	// Readiness: started, not shutting down, and background searches can still be queued
+	r.GET("/readyz", func(c *gin.Context) {
+		stats := scheduler.Stats()
+		switch {
+		case !ready.Load():
+			c.JSON(503, gin.H{"status": "not ready"})
+		case stats.Closed:
+			c.JSON(503, gin.H{"status": "shutting down"})
+		case stats.Queued >= stats.MaxQueue:
+			c.JSON(503, gin.H{"status": "job queue full", "jobs": stats})
+		default:
+			c.JSON(200, gin.H{"status": "ready", "jobs": stats})
+		}
+	})
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+func newObservedServer(t *testing.T, opts jobs.Options) (*gin.Engine, *serverMetrics, *atomic.Bool, *jobs.Scheduler) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	scheduler := jobs.New(opts)
+	t.Cleanup(scheduler.Close)
+	m := newServerMetrics(scheduler)
+	ready := new(atomic.Bool)
+	r := gin.New()
+	r.Use(m.middleware())
+	m.register(r, ready, scheduler)
+	r.GET("/games/:id", func(c *gin.Context) { c.Status(404) })
+	return r, m, ready, scheduler
+}
+
+func get(r *gin.Engine, url string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+	return w
+}
+
+func scrape(t *testing.T, r *gin.Engine) string {
+	t.Helper()
+	w := get(r, "/metrics")
+	if w.Code != 200 || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("/metrics: %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	return w.Body.String()
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	r, _, _, _ := newObservedServer(t, jobs.Options{})
+	get(r, "/games/7?fen=x")
+	get(r, "/games/8")
+	get(r, "/nowhere")
+	get(r, "/healthz")
+
+	body := scrape(t, r)
+	for _, line := range []string{
+		`chess_http_requests_total{method="GET",route="/games/:id",code="404"} 2`,
+		`chess_http_requests_total{method="GET",route="unmatched",code="404"} 1`,
+		`chess_http_requests_total{method="GET",route="/healthz",code="200"} 1`,
+		`chess_http_request_duration_seconds_count{method="GET",route="/games/:id"} 2`,
+		`chess_cache_hits_total{cache="default"}`,
+		`chess_cache_entries{cache="deep"}`,
+		`chess_background_jobs{state="queued"} 0`,
+		"# TYPE chess_search_depth histogram",
+	} {
+		if !strings.Contains(body, line+"\n") && !strings.Contains(body, line+" ") {
+			t.Errorf("/metrics lacks %s", line)
+		}
+	}
+	if strings.Contains(body, "/games/7") {
+		t.Error("a request URL was used as a label")
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name   string
+		ready  bool
+		fill   bool // occupy the worker and fill the queue
+		close  bool
+		code   int
+		status string
+	}{
+		{"starting", false, false, false, 503, "not ready"},
+		{"ready", true, false, false, 200, "ready"},
+		{"queue full", true, true, false, 503, "job queue full"},
+		{"shutting down", true, false, true, 503, "shutting down"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, ready, scheduler := newObservedServer(t, jobs.Options{Workers: 1, MaxQueue: 1})
+			ready.Store(tt.ready)
+			if tt.fill {
+				started, done := make(chan struct{}), make(chan struct{})
+				defer close(done)
+				if _, err := scheduler.Submit("running", "", nil, func(context.Context) { close(started); <-done }); err != nil {
+					t.Fatal(err)
+				}
+				<-started
+				if _, err := scheduler.Submit("queued", "", nil, func(context.Context) {}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.close {
+				scheduler.Close()
+			}
+
+			w := get(r, "/readyz")
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), `"status":"`+tt.status+`"`) {
+				t.Errorf("/readyz = %d %s, want %d %q", w.Code, w.Body, tt.code, tt.status)
+			}
+			if w := get(r, "/healthz"); w.Code != 200 {
+				t.Errorf("/healthz = %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestSearchObserver(t *testing.T) {
+	r, m, _, _ := newObservedServer(t, jobs.Options{})
+	board, err := game_state.ParseFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	game_state.Analyze(board, 3, game_state.WhiteColor, func(game_state.SearchResult) {}, m.searchObserver(""))
+	game_state.Analyze(board, 2, game_state.WhiteColor, func(game_state.SearchResult) {}, m.searchObserver("sharp"))
+
+	body := scrape(t, r)
+	for _, line := range []string{
+		`chess_searches_total{profile="default"} 3`,
+		`chess_searches_total{profile="sharp"} 2`,
+		`chess_search_depth_bucket{profile="default",le="2"} 2`,
+		`chess_search_depth_count{profile="sharp"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("/metrics lacks %s", line)
+		}
+	}
+	if !strings.Contains(body, `chess_search_nodes_total{profile="default"} `) {
+		t.Error("/metrics lacks the node count")
+	}
+}
@@ -0,0 +1,281 @@
+// Package pgn reads games in Portable Game Notation and replays them on the engine's board.
+// Comments, variations and NAGs are skipped. Castling cannot be replayed because the
+// engine does not implement it.
+package pgn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+
+var ErrCastling = errors.New("castling is not supported by the engine")
+
+type Game struct {
+	Tags   map[string]string
+	Moves  []string // SAN, without move numbers or annotations
+	Result string   // "1-0", "0-1", "1/2-1/2" or "*"
+}
+
+// Step is one move of a replayed game.
+type Step struct {
+	Ply      int // from 0
+	Before   engine.Board
+	FEN      string // position before the move, with counters
+	Move     engine.Move
+	SAN      string
+	Halfmove int // halfmove clock before the move
+}
+
+// StartFEN returns the FEN tag, or the standard start position.
+func (g Game) StartFEN() string {
+	if fen := g.Tags["FEN"]; fen != "" {
+		return fen
+	}
+	return startFEN
+}
+
+// Parse reads every game in text.
+func Parse(text string) ([]Game, error) {
+	var games []Game
+	cur := Game{Tags: map[string]string{}}
+	inMoves := false
+
+	flush := func() {
+		if len(cur.Tags) > 0 || len(cur.Moves) > 0 {
+			if cur.Result == "" {
+				cur.Result = cur.Tags["Result"]
+			}
+			games = append(games, cur)
+		}
+		cur = Game{Tags: map[string]string{}}
+		inMoves = false
+	}
+
+	toks, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, tok := range toks {
+		switch {
+		case strings.HasPrefix(tok, "["):
+			if inMoves {
+				flush()
+			}
+			name, value, err := parseTag(tok)
+			if err != nil {
+				return nil, err
+			}
+			cur.Tags[name] = value
+		case tok == "1-0" || tok == "0-1" || tok == "1/2-1/2" || tok == "*":
+			cur.Result = tok
+			flush()
+		default:
+			inMoves = true
+			if san := strings.TrimRight(stripMoveNumber(tok), "!?"); san != "" {
+				cur.Moves = append(cur.Moves, san)
+			}
+		}
+	}
+	flush()
+
+	if len(games) == 0 {
+		return nil, errors.New("no games found")
+	}
+	return games, nil
+}
+
+// ParseEach reads every game in text like Parse, except that a game that cannot be read
+// is skipped, with its error in errs, instead of failing the whole text. Games are told
+// apart by their tag sections, so a broken game loses no more than itself.
+func ParseEach(text string) (games []Game, errs []error) {
+	var chunk strings.Builder
+	number, inMoves := 0, false
+	flush := func() {
+		text := chunk.String()
+		chunk.Reset()
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		number++
+		parsed, err := Parse(text)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("game %d: %w", number, err))
+		}
+		games = append(games, parsed...)
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			if inMoves {
+				flush()
+				inMoves = false
+			}
+		case trimmed != "":
+			inMoves = true
+		}
+		chunk.WriteString(line)
+	}
+	flush()
+	return games, errs
+}
+
+// Replay plays the game's moves from its start position, stopping at the first move the
+// engine cannot play.
+func (g Game) Replay() ([]Step, error) {
+	fen := g.StartFEN()
+	board, err := engine.ParseFEN(fen)
+	if err != nil {
+		return nil, err
+	}
+	board.Played = map[string]int{engine.BoardToFEN(board): 1}
+	halfmove, fullmove := engine.FENCounters(fen)
+
+	steps := make([]Step, 0, len(g.Moves))
+	for i, san := range g.Moves {
+		if strings.HasPrefix(san, "O-O") || strings.HasPrefix(san, "0-0") {
+			return steps, fmt.Errorf("move %d (%s): %w", i+1, san, ErrCastling)
+		}
+		m, err := engine.ParseMove(board, san)
+		if err != nil {
+			return steps, fmt.Errorf("move %d: %w", i+1, err)
+		}
+
+		steps = append(steps, Step{
+			Ply:      i,
+			Before:   board,
+			FEN:      engine.FENWithCounters(board, halfmove, fullmove),
+			Move:     m,
+			SAN:      engine.MoveToSAN(board, m),
+			Halfmove: halfmove,
+		})
+
+		moved := board.PiecesMatrix[m.From.Line][m.From.Column]
+		captured := board.PiecesMatrix[m.To.Line][m.To.Column].Type != 0
+		halfmove++
+		if moved.Type == engine.Pawn || captured {
+			halfmove = 0
+		}
+		if !board.WhiteTurn {
+			fullmove++
+		}
+		board = engine.BoardAfterMove(m, board)
+	}
+	return steps, nil
+}
+
+// tokenize splits movetext into tags, move tokens and results, dropping comments,
+// variations and NAGs.
+func tokenize(text string) ([]string, error) {
+	var toks []string
+	depth := 0 // variation nesting
+	for i := 0; i < len(text); {
+		ch := text[i]
+		switch {
+		case ch == '{':
+			end := strings.IndexByte(text[i:], '}')
+			if end < 0 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end + 1
+		case ch == '}':
+			return nil, errors.New("unbalanced '}'")
+		case ch == ';':
+			end := strings.IndexByte(text[i:], '\n')
+			if end < 0 {
+				end = len(text) - i
+			}
+			i += end
+		case ch == '(':
+			depth++
+			i++
+		case ch == ')':
+			if depth == 0 {
+				return nil, errors.New("unbalanced ')'")
+			}
+			depth--
+			i++
+		case ch == '[':
+			if depth > 0 {
+				return nil, errors.New("tag inside a variation")
+			}
+			end := tagLength(text[i:])
+			if end < 0 {
+				return nil, errors.New("unterminated tag")
+			}
+			toks = append(toks, text[i:i+end])
+			i += end
+		case ch == ']':
+			return nil, errors.New("unbalanced ']'")
+		case unicode.IsSpace(rune(ch)):
+			i++
+		default:
+			start := i
+			for i < len(text) && !unicode.IsSpace(rune(text[i])) && strings.IndexByte("{}();[", text[i]) < 0 {
+				i++
+			}
+			if depth == 0 && text[start] != '$' {
+				toks = append(toks, text[start:i])
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.New("unbalanced '('")
+	}
+	return toks, nil
+}
+
+// tagLength returns the length of the tag text starts with, up to its closing ']', or -1
+// when it is not closed. A quoted value may hold ']' and backslash-escaped quotes.
+func tagLength(text string) int {
+	quoted := false
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quoted && text[i] == '\\':
+			i++ // the escaped character cannot end the value
+		case text[i] == '"':
+			quoted = !quoted
+		case !quoted && text[i] == ']':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func parseTag(tok string) (name, value string, err error) {
+	inner := strings.TrimSpace(tok[1 : len(tok)-1])
+	sp := strings.IndexFunc(inner, unicode.IsSpace)
+	if sp < 0 {
+		return "", "", fmt.Errorf("invalid tag %s", tok)
+	}
+	name = inner[:sp]
+	value = strings.TrimSpace(inner[sp:])
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return "", "", fmt.Errorf("invalid tag %s", tok)
+	}
+	value = tagUnescaper.Replace(value[1 : len(value)-1])
+	return name, value, nil
+}
+
+var tagUnescaper = strings.NewReplacer(`\"`, `"`, `\\`, `\`)
+
+// stripMoveNumber turns "12.e4" or "12..." into "e4" and "".
+func stripMoveNumber(tok string) string {
+	i := 0
+	for i < len(tok) && tok[i] >= '0' && tok[i] <= '9' {
+		i++
+	}
+	if i > 0 && i < len(tok) && tok[i] == '.' {
+		return strings.TrimLeft(tok[i:], ".")
+	}
+	if i == len(tok) {
+		return ""
+	}
+	return tok
+}
@@ -0,0 +1,224 @@
+package pgn
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		tag   string
+		want  string
+		moves []string
+	}{
+		{"plain", `[Event "Casual game"] 1. e4 e5 *`, "Event", "Casual game", []string{"e4", "e5"}},
+		{"bracket in value", `[Event "Open [A]"] 1. e4 e5 *`, "Event", "Open [A]", []string{"e4", "e5"}},
+		{"escaped quote", `[Event "The \"Immortal\" ]game"] 1. d4 *`, "Event", `The "Immortal" ]game`, []string{"d4"}},
+		{"escaped backslash", `[Site "C:\\games\\"] 1. d4 *`, "Site", `C:\games\`, []string{"d4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			games, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(games) != 1 {
+				t.Fatalf("got %d games, want 1", len(games))
+			}
+			if got := games[0].Tags[tt.tag]; got != tt.want {
+				t.Errorf("%s = %q, want %q", tt.tag, got, tt.want)
+			}
+			if !slices.Equal(games[0].Moves, tt.moves) {
+				t.Errorf("moves = %v, want %v", games[0].Moves, tt.moves)
+			}
+		})
+	}
+}
+
+func TestParseEachKeepsGamesWithBracketsInTags(t *testing.T) {
+	text := "[Event \"Open [A]\"]\n[Result \"1-0\"]\n\n1. e4 e5 1-0\n\n[Event \"Open [B]\"]\n[Result \"0-1\"]\n\n1. d4 d5 0-1\n"
+	games, errs := ParseEach(text)
+	if len(errs) != 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	var events []string
+	for _, g := range games {
+		events = append(events, g.Tags["Event"])
+	}
+	if want := []string{"Open [A]", "Open [B]"}; !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+}
+
+func TestParseMovetext(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		moves  []string
+		result string
+	}{
+		{"numbers and spacing", "1.e4 e5 2. Nf3 Nc6 1-0", []string{"e4", "e5", "Nf3", "Nc6"}, "1-0"},
+		{"black to move", "[FEN \"8/8/8/8/8/8/8/8 b - - 0 12\"] 12... Kd7 13. Kd2 *", []string{"Kd7", "Kd2"}, "*"},
+		{"comments and NAGs", "1. e4 {best by test} e5 $1 2. Nf3! ; rest of line\nNc6?! 0-1", []string{"e4", "e5", "Nf3", "Nc6"}, "0-1"},
+		{"nested variations", "1. e4 (1. d4 d5 (1... Nf6)) e5 1/2-1/2", []string{"e4", "e5"}, "1/2-1/2"},
+		{"result from tag", "[Result \"1-0\"]\n1. e4 e5", []string{"e4", "e5"}, "1-0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			games, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(games) != 1 {
+				t.Fatalf("got %d games, want 1", len(games))
+			}
+			if !slices.Equal(games[0].Moves, tt.moves) {
+				t.Errorf("moves = %v, want %v", games[0].Moves, tt.moves)
+			}
+			if games[0].Result != tt.result {
+				t.Errorf("result = %q, want %q", games[0].Result, tt.result)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"empty", "  \n"},
+		{"unterminated comment", "1. e4 { e5"},
+		{"stray brace", "1. e4 } e5"},
+		{"unbalanced variation", "1. e4 (1. d4 e5"},
+		{"stray parenthesis", "1. e4 ) e5"},
+		{"tag in variation", "1. e4 ([Event \"x\"]) e5"},
+		{"unterminated tag", "[Event \"x\"\n1. e4"},
+		{"stray bracket", "1. e4 ] e5"},
+		{"unquoted tag", "[Event x] 1. e4 *"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.text); err == nil {
+				t.Error("Parse succeeded")
+			}
+		})
+	}
+}
+
+func TestReplayRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		wantFEN []string // position before each move
+	}{
+		{
+			name: "from the start",
+			text: "1. e4 e5 2. Nf3 Nc6 3. Bb5 a6 4. Bxc6 dxc6 *",
+			wantFEN: []string{
+				"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+				"rnbqkbnr/pppppppp/8/8/4P3/8/PPPP1PPP/RNBQKBNR b - - 0 1",
+				"rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w - - 0 2",
+				"rnbqkbnr/pppp1ppp/8/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R b - - 1 2",
+				"r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w - - 2 3",
+				"r1bqkbnr/pppp1ppp/2n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R b - - 3 3",
+				"r1bqkbnr/1ppp1ppp/p1n5/1B2p3/4P3/5N2/PPPP1PPP/RNBQK2R w - - 0 4",
+				"r1bqkbnr/1ppp1ppp/p1B5/4p3/4P3/5N2/PPPP1PPP/RNBQK2R b - - 0 4",
+			},
+		},
+		{
+			name: "from a FEN tag with black to move",
+			text: "[FEN \"4k3/8/8/8/8/8/4P3/4K3 b - - 5 30\"]\n30... Kd7 31. e4 Ke6 *",
+			wantFEN: []string{
+				"4k3/8/8/8/8/8/4P3/4K3 b - - 5 30",
+				"8/3k4/8/8/8/8/4P3/4K3 w - - 6 31",
+				"8/3k4/8/8/4P3/8/8/4K3 b - - 0 31",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			games, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			steps, err := games[0].Replay()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(steps) != len(games[0].Moves) {
+				t.Fatalf("replayed %d of %d moves", len(steps), len(games[0].Moves))
+			}
+			for i, step := range steps {
+				if step.SAN != games[0].Moves[i] {
+					t.Errorf("ply %d: SAN %q, want %q", i, step.SAN, games[0].Moves[i])
+				}
+				if step.FEN != tt.wantFEN[i] {
+					t.Errorf("ply %d: FEN %q, want %q", i, step.FEN, tt.wantFEN[i])
+				}
+			}
+
+			// the replayed SAN reads back as the same game
+			var movetext []string
+			for _, step := range steps {
+				movetext = append(movetext, step.SAN)
+			}
+			again, err := Parse("[FEN \"" + games[0].StartFEN() + "\"]\n" + strings.Join(movetext, " ") + " *")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(again[0].Moves, games[0].Moves) {
+				t.Errorf("reparsed moves = %v, want %v", again[0].Moves, games[0].Moves)
+			}
+		})
+	}
+}
+
+func TestReplayStops(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantSteps int
+		wantErr   error
+	}{
+		{"castling", "1. e4 e5 2. Nf3 Nc6 3. Bc4 Bc5 4. O-O *", 6, ErrCastling},
+		{"illegal move", "1. e4 e5 2. Ke3 *", 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			games, err := Parse(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			steps, err := games[0].Replay()
+			if err == nil {
+				t.Fatal("Replay succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if len(steps) != tt.wantSteps {
+				t.Errorf("replayed %d moves before stopping, want %d", len(steps), tt.wantSteps)
+			}
+		})
+	}
+}
+
+func TestParseEachSkipsBrokenGames(t *testing.T) {
+	text := "[Event \"A\"]\n\n1. e4 e5 1-0\n\n[Event \"B\"]\n\n1. d4 { never closed 0-1\n\n[Event \"C\"]\n\n1. c4 *\n"
+	games, errs := ParseEach(text)
+	var events []string
+	for _, g := range games {
+		events = append(events, g.Tags["Event"])
+	}
+	if want := []string{"A", "C"}; !slices.Equal(events, want) {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), "game 2:") {
+		t.Errorf("errs = %v, want one error for game 2", errs)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+// Conventional piece values in pawns, as used for the material balance
+var materialPoints = map[game_state.PieceType]int{
+	game_state.Pawn:   1,
+	game_state.Knight: 3,
+	game_state.Bishop: 3,
+	game_state.Rook:   5,
+	game_state.Queen:  9,
+}
+
+type legalMove struct {
+	UCI     string `json:"uci"`
+	SAN     string `json:"san"`
+	Capture bool   `json:"capture"`
+}
+
+type sideMaterial struct {
+	Pieces game_state.PieceTable[int] `json:"pieces"`
+	Points int                        `json:"points"`
+}
+
+type positionInfo struct {
+	FEN        string            `json:"fen"`
+	Turn       string            `json:"turn"`
+	InCheck    bool              `json:"in_check"`
+	Status     game_state.Status `json:"status"`
+	LegalMoves []legalMove       `json:"legal_moves"`
+	Material   struct {
+		White   sideMaterial `json:"white"`
+		Black   sideMaterial `json:"black"`
+		Balance int          `json:"balance"` // white minus black, in pawns
+	} `json:"material"`
+}
+
+// describePosition gathers everything a client needs to follow the rules without
+// re-implementing them. The counters come from the FEN since the board does not keep them.
+func describePosition(board game_state.Board, fen string) positionInfo {
+	halfmove, fullmove := game_state.FENCounters(fen)
+	side := game_state.SideToMove(board)
+
+	info := positionInfo{
+		FEN:        game_state.FENWithCounters(board, halfmove, fullmove),
+		Turn:       "white",
+		InCheck:    game_state.IsKingInCheck(board, side),
+		Status:     game_state.GameStatus(board, halfmove),
+		LegalMoves: []legalMove{},
+	}
+	if side == game_state.BlackColor {
+		info.Turn = "black"
+	}
+
+	for _, m := range game_state.LegalMoves(board) {
+		info.LegalMoves = append(info.LegalMoves, legalMove{
+			UCI:     game_state.MoveToUCI(board, m),
+			SAN:     game_state.MoveToSAN(board, m),
+			Capture: board.PiecesMatrix[m.To.Line][m.To.Column].Type != 0,
+		})
+	}
+
+	for _, p := range board.PiecesSlice {
+		side := &info.Material.White
+		if p.Color == game_state.BlackColor {
+			side = &info.Material.Black
+		}
+		switch p.Type {
+		case game_state.Pawn:
+			side.Pieces.Pawn++
+		case game_state.Knight:
+			side.Pieces.Knight++
+		case game_state.Bishop:
+			side.Pieces.Bishop++
+		case game_state.Rook:
+			side.Pieces.Rook++
+		case game_state.Queen:
+			side.Pieces.Queen++
+		}
+		side.Points += materialPoints[p.Type]
+	}
+	info.Material.Balance = info.Material.White.Points - info.Material.Black.Points
+	return info
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+func TestDescribePosition(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		wantFEN   string
+		turn      string
+		inCheck   bool
+		status    game_state.Status
+		moves     int
+		wantMove  legalMove
+		white     int
+		black     int
+		balance   int
+		whiteKnts int
+	}{
+		{
+			name:      "start",
+			fen:       "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+			wantFEN:   "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1",
+			turn:      "white",
+			status:    game_state.Status{State: game_state.Ongoing},
+			moves:     20,
+			wantMove:  legalMove{UCI: "g1f3", SAN: "Nf3"},
+			white:     39,
+			black:     39,
+			whiteKnts: 2,
+		},
+		{
+			name:     "capture, counters kept",
+			fen:      "4k3/8/8/3p4/4P3/8/8/4K3 w - - 7 31",
+			wantFEN:  "4k3/8/8/3p4/4P3/8/8/4K3 w - - 7 31",
+			turn:     "white",
+			status:   game_state.Status{State: game_state.Ongoing},
+			moves:    7,
+			wantMove: legalMove{UCI: "e4d5", SAN: "exd5", Capture: true},
+			white:    1,
+			black:    1,
+		},
+		{
+			name:     "counters default",
+			fen:      "4k3/8/8/8/8/8/8/Q3K3 b",
+			wantFEN:  "4k3/8/8/8/8/8/8/Q3K3 b - - 0 1",
+			turn:     "black",
+			status:   game_state.Status{State: game_state.Ongoing},
+			moves:    5,
+			wantMove: legalMove{UCI: "e8d7", SAN: "Kd7"},
+			white:    9,
+			balance:  9,
+		},
+		{
+			name:      "checkmate",
+			fen:       "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w - - 1 3",
+			wantFEN:   "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w - - 1 3",
+			turn:      "white",
+			inCheck:   true,
+			status:    game_state.Status{State: game_state.Checkmate, Winner: "black"},
+			white:     39,
+			black:     39,
+			whiteKnts: 2,
+		},
+		{
+			name:    "stalemate",
+			fen:     "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+			wantFEN: "7k/5Q2/6K1/8/8/8/8/8 b - - 0 1",
+			turn:    "black",
+			status:  game_state.Status{State: game_state.Stalemate},
+			white:   9,
+			balance: 9,
+		},
+		{
+			name:     "fifty-move rule",
+			fen:      "4k3/8/8/8/8/8/4P3/4K3 w - - 100 80",
+			wantFEN:  "4k3/8/8/8/8/8/4P3/4K3 w - - 100 80",
+			turn:     "white",
+			status:   game_state.Status{State: game_state.Draw, Reason: game_state.FiftyMoveRule},
+			moves:    6,
+			wantMove: legalMove{UCI: "e2e4", SAN: "e4"},
+			white:    1,
+			balance:  1,
+		},
+		{
+			name:      "insufficient material",
+			fen:       "4k3/8/8/8/8/8/8/3NK3 w - - 0 1",
+			wantFEN:   "4k3/8/8/8/8/8/8/3NK3 w - - 0 1",
+			turn:      "white",
+			status:    game_state.Status{State: game_state.Draw, Reason: game_state.InsufficientMaterial},
+			moves:     8,
+			wantMove:  legalMove{UCI: "d1c3", SAN: "Nc3"},
+			white:     3,
+			balance:   3,
+			whiteKnts: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := game_state.ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info := describePosition(board, tt.fen)
+
+			if info.FEN != tt.wantFEN {
+				t.Errorf("fen = %q, want %q", info.FEN, tt.wantFEN)
+			}
+			if info.Turn != tt.turn || info.InCheck != tt.inCheck || info.Status != tt.status {
+				t.Errorf("turn %s, in check %v, status %+v; want %s, %v, %+v", info.Turn, info.InCheck, info.Status, tt.turn, tt.inCheck, tt.status)
+			}
+			if info.LegalMoves == nil || len(info.LegalMoves) != tt.moves {
+				t.Fatalf("%d legal moves, want %d", len(info.LegalMoves), tt.moves)
+			}
+			if tt.moves > 0 && !containsMove(info.LegalMoves, tt.wantMove) {
+				t.Errorf("legal moves %v lack %+v", info.LegalMoves, tt.wantMove)
+			}
+			m := info.Material
+			if m.White.Points != tt.white || m.Black.Points != tt.black || m.Balance != tt.balance {
+				t.Errorf("material %d-%d balance %d, want %d-%d balance %d", m.White.Points, m.Black.Points, m.Balance, tt.white, tt.black, tt.balance)
+			}
+			if m.White.Pieces.Knight != tt.whiteKnts {
+				t.Errorf("white has %d knights, want %d", m.White.Pieces.Knight, tt.whiteKnts)
+			}
+		})
+	}
+}
+
+func containsMove(moves []legalMove, want legalMove) bool {
+	for _, m := range moves {
+		if m == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// evalProfiles serves named evaluation profiles from <dir>/<name>.json. A profile is
+// reloaded when its file changes, so tunings can be swapped without a restart.
+type evalProfiles struct {
+	dir    string
+	mu     sync.Mutex
+	loaded map[string]loadedProfile
+}
+
+type loadedProfile struct {
+	params      *game_state.EvalParams
+	fingerprint string
+	modTime     time.Time
+}
+
+func newEvalProfiles(dir string) *evalProfiles {
+	return &evalProfiles{dir: dir, loaded: make(map[string]loadedProfile)}
+}
+
+func (p *evalProfiles) get(name string) (*game_state.EvalParams, error) {
+	loaded, err := p.load(name)
+	return loaded.params, err
+}
+
+// fingerprint identifies the current weights of a profile, or is empty when it cannot be
+// loaded.
+func (p *evalProfiles) fingerprint(name string) string {
+	loaded, _ := p.load(name)
+	return loaded.fingerprint
+}
+
+func (p *evalProfiles) load(name string) (loadedProfile, error) {
+	if p.dir == "" {
+		return loadedProfile{}, errors.New("evaluation profiles are not enabled, set EVAL_PROFILE_DIR")
+	}
+	if !profileNamePattern.MatchString(name) {
+		return loadedProfile{}, fmt.Errorf("invalid profile name %q", name)
+	}
+
+	path := filepath.Join(p.dir, name+".json")
+	info, err := os.Stat(path)
+	if err != nil {
+		return loadedProfile{}, fmt.Errorf("unknown profile %q", name)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.loaded[name]; ok && cached.modTime.Equal(info.ModTime()) {
+		return cached, nil
+	}
+	params, err := game_state.LoadEvalParams(path)
+	if err != nil {
+		// keep the server's directory layout out of client-facing errors
+		return loadedProfile{}, fmt.Errorf("profile %q: %s", name, strings.TrimPrefix(err.Error(), path+": "))
+	}
+	loaded := loadedProfile{params: params, fingerprint: paramsFingerprint(params), modTime: info.ModTime()}
+	p.loaded[name] = loaded
+	return loaded, nil
+}
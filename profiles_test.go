@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+func writeProfile(t *testing.T, dir, name string, params *game_state.EvalParams, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, name+".json")
+	if err := params.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEvalProfilesLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeProfile(t, dir, "sharp", game_state.DefaultEvalParams(), time.Now())
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"piece_values": {"pawn": -1}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		profile string
+		wantErr string
+	}{
+		{"loads", dir, "sharp", ""},
+		{"disabled", "", "sharp", "EVAL_PROFILE_DIR"},
+		{"path in the name", dir, "../sharp", "invalid profile name"},
+		{"unknown", dir, "missing", `unknown profile "missing"`},
+		{"invalid weights", dir, "broken", `profile "broken": piece_values.pawn`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := newEvalProfiles(tt.dir).get(tt.profile)
+			if tt.wantErr == "" {
+				if err != nil || params == nil {
+					t.Fatalf("get = %v, %v", params, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("get = %v, want an error containing %q", err, tt.wantErr)
+			}
+			if strings.Contains(err.Error(), dir) {
+				t.Errorf("error %q reveals the profile directory", err)
+			}
+		})
+	}
+}
+
+func TestEvalProfilesReload(t *testing.T) {
+	dir := t.TempDir()
+	modTime := time.Now().Add(-time.Hour)
+	writeProfile(t, dir, "sharp", game_state.DefaultEvalParams(), modTime)
+
+	profiles := newEvalProfiles(dir)
+	first, err := profiles.get("sharp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := profiles.fingerprint("sharp")
+	if again, _ := profiles.get("sharp"); again != first {
+		t.Error("an unchanged profile was loaded again")
+	}
+
+	changed := game_state.DefaultEvalParams()
+	changed.PieceValues.Knight = 350
+	writeProfile(t, dir, "sharp", changed, modTime.Add(time.Minute))
+
+	reloaded, err := profiles.get("sharp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.PieceValues.Knight != 350 {
+		t.Errorf("knight = %d after the file changed, want 350", reloaded.PieceValues.Knight)
+	}
+	if after := profiles.fingerprint("sharp"); after == before || after == "" {
+		t.Errorf("fingerprint %q did not change from %q", after, before)
+	}
+	if profiles.fingerprint("missing") != "" {
+		t.Error("a missing profile has a fingerprint")
+	}
+}
+
+func TestResultCacheKeyedOnWeights(t *testing.T) {
+	board, err := game_state.ParseFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	eval := "v1"
+	rc := resultCache{defaultDepth: 4, evalID: func(string) string { return eval }}
+	k := newResultKey(board, true, "test-weights")
+
+	for _, depth := range []int{4, 6} {
+		rc.save(k, computed.CacheValue{Depth: depth, Score: 10 * depth})
+		if v, ok := rc.lookup(k, depth); !ok || v.Score != 10*depth {
+			t.Errorf("depth %d: lookup = %+v, %v after save", depth, v, ok)
+		}
+	}
+
+	eval = "v2"
+	for _, depth := range []int{4, 6} {
+		if _, ok := rc.lookup(k, depth); ok {
+			t.Errorf("depth %d: found a result saved under other weights", depth)
+		}
+		if rc.has(k, depth) {
+			t.Errorf("depth %d: has a result saved under other weights", depth)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"sync"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/ui/assets"
+)
+
+var (
+	pieceBitmapsOnce sync.Once
+	pieceBitmaps     map[string]image.Image
+	pieceBitmapsErr  error
+)
+
+func loadPieceBitmaps() (map[string]image.Image, error) {
+	pieceBitmapsOnce.Do(func() {
+		pieceBitmaps = make(map[string]image.Image)
+		for _, t := range []engine.PieceType{engine.Pawn, engine.Knight, engine.Bishop, engine.Rook, engine.Queen, engine.King} {
+			for _, c := range []engine.PieceColor{engine.WhiteColor, engine.BlackColor} {
+				name := assets.PieceName(engine.Piece{Type: t, Color: c})
+				img, err := assets.PNG(name)
+				if err != nil {
+					pieceBitmapsErr = err
+					return
+				}
+				pieceBitmaps[name] = img
+			}
+		}
+	})
+	return pieceBitmaps, pieceBitmapsErr
+}
+
+// PNG draws the board as a PNG image.
+func PNG(w io.Writer, board engine.Board, opts Options) error {
+	bitmaps, err := loadPieceBitmaps()
+	if err != nil {
+		return err
+	}
+
+	sq := opts.squareSize()
+	img := image.NewNRGBA(image.Rect(0, 0, sq*8, sq*8))
+
+	for _, p := range squares() {
+		x, y := opts.origin(p, sq)
+		rect := image.Rect(x, y, x+sq, y+sq)
+		draw.Draw(img, rect, image.NewUniform(squareColor(p)), image.Point{}, draw.Src)
+		if opts.LastMove != nil && (p == opts.LastMove.From || p == opts.LastMove.To) {
+			draw.Draw(img, rect, image.NewUniform(lastMoveColor), image.Point{}, draw.Over)
+		}
+	}
+
+	if opts.Coordinates {
+		drawCoordinatesPNG(img, opts, sq)
+	}
+
+	margin := sq / 20
+	for _, piece := range board.PiecesSlice {
+		src := bitmaps[assets.PieceName(piece)]
+		if src == nil || !onBoard(piece.Pos) {
+			continue
+		}
+		x, y := opts.origin(piece.Pos, sq)
+		dst := image.Rect(x+margin, y+margin, x+sq-margin, y+sq-margin)
+		draw.CatmullRom.Scale(img, dst, src, src.Bounds(), draw.Over, nil)
+	}
+
+	for _, a := range opts.Arrows {
+		if !onBoard(a.From) || !onBoard(a.To) {
+			continue
+		}
+		outline := arrowOutline(opts.center(a.From, sq), opts.center(a.To, sq), sq)
+		if outline == nil {
+			continue
+		}
+		c := a.Color
+		if c == (color.NRGBA{}) {
+			c = DefaultArrowColor
+		}
+
+		r := vector.NewRasterizer(sq*8, sq*8)
+		r.MoveTo(float32(outline[0].x), float32(outline[0].y))
+		for _, pt := range outline[1:] {
+			r.LineTo(float32(pt.x), float32(pt.y))
+		}
+		r.ClosePath()
+		r.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{})
+	}
+
+	return png.Encode(w, img)
+}
+
+// drawCoordinatesPNG labels files along the bottom edge and ranks along the left edge,
+// each in the color of the opposite square so it stays readable.
+func drawCoordinatesPNG(img *image.NRGBA, opts Options, sq int) {
+	face := basicfont.Face7x13
+	pad := max(sq/24, 1)
+
+	for i := int8(1); i <= 8; i++ {
+		bottom := engine.Position{Line: 1, Column: i}
+		left := engine.Position{Line: i, Column: 1}
+		if opts.Flipped {
+			bottom.Line = 8
+			left.Column = 8
+		}
+
+		label := engine.SquareName(bottom)[:1]
+		x, y := opts.origin(bottom, sq)
+		drawLabel(img, face, label, x+sq-pad-7, y+sq-pad-3, oppositeColor(bottom))
+
+		label = engine.SquareName(left)[1:]
+		x, y = opts.origin(left, sq)
+		drawLabel(img, face, label, x+pad, y+pad+10, oppositeColor(left))
+	}
+}
+
+func drawLabel(img *image.NRGBA, face font.Face, label string, x, y int, c color.NRGBA) {
+	d := font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(x, y),
+	}
+	d.DrawString(label)
+}
+
+func oppositeColor(p engine.Position) color.NRGBA {
+	if squareColor(p) == lightColor {
+		return darkColor
+	}
+	return lightColor
+}
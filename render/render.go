@@ -0,0 +1,110 @@
+// Package render draws board diagrams as PNG or SVG from the embedded piece assets.
+package render
+
+import (
+	"image/color"
+	"math"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+const (
+	DefaultSize = 400
+	MinSize     = 64
+	MaxSize     = 2048
+)
+
+// Same palette as the desktop UI
+var (
+	lightColor        = color.NRGBA{R: 240, G: 217, B: 181, A: 255}
+	darkColor         = color.NRGBA{R: 181, G: 136, B: 99, A: 255}
+	lastMoveColor     = color.NRGBA{R: 255, G: 255, B: 0, A: 100}
+	DefaultArrowColor = color.NRGBA{R: 21, G: 120, B: 27, A: 170}
+)
+
+// Arrow points from one square to another. A zero Color uses DefaultArrowColor.
+type Arrow struct {
+	From, To engine.Position
+	Color    color.NRGBA
+}
+
+// Options controls how a board is drawn. The zero value draws a 400px board from white's side.
+type Options struct {
+	Size        int  // edge length in pixels, rounded down to a multiple of 8
+	Flipped     bool // black at the bottom
+	Coordinates bool // file and rank labels along the edges
+	LastMove    *engine.Move
+	Arrows      []Arrow
+}
+
+func (o Options) squareSize() int {
+	size := o.Size
+	if size == 0 {
+		size = DefaultSize
+	}
+	size = min(max(size, MinSize), MaxSize)
+	return size / 8
+}
+
+// origin returns the top-left pixel of a square.
+func (o Options) origin(p engine.Position, sq int) (x, y int) {
+	if o.Flipped {
+		return (8 - int(p.Column)) * sq, (int(p.Line) - 1) * sq
+	}
+	return (int(p.Column) - 1) * sq, (8 - int(p.Line)) * sq
+}
+
+func (o Options) center(p engine.Position, sq int) point {
+	x, y := o.origin(p, sq)
+	return point{float64(x) + float64(sq)/2, float64(y) + float64(sq)/2}
+}
+
+func squareColor(p engine.Position) color.NRGBA {
+	if (p.Line+p.Column)%2 == 0 {
+		return darkColor
+	}
+	return lightColor
+}
+
+func onBoard(p engine.Position) bool {
+	return p.Line >= 1 && p.Line <= 8 && p.Column >= 1 && p.Column <= 8
+}
+
+type point struct{ x, y float64 }
+
+// arrowOutline returns the polygon of an arrow between two square centers.
+func arrowOutline(from, to point, sq int) []point {
+	dx, dy := to.x-from.x, to.y-from.y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return nil
+	}
+	ux, uy := dx/length, dy/length
+	nx, ny := -uy, ux
+
+	shaft := float64(sq) * 0.09
+	headWidth := float64(sq) * 0.25
+	headLength := math.Min(float64(sq)*0.45, length)
+	neck := point{to.x - ux*headLength, to.y - uy*headLength}
+
+	return []point{
+		{from.x + nx*shaft, from.y + ny*shaft},
+		{neck.x + nx*shaft, neck.y + ny*shaft},
+		{neck.x + nx*headWidth, neck.y + ny*headWidth},
+		to,
+		{neck.x - nx*headWidth, neck.y - ny*headWidth},
+		{neck.x - nx*shaft, neck.y - ny*shaft},
+		{from.x - nx*shaft, from.y - ny*shaft},
+	}
+}
+
+// squares lists every square in drawing order.
+func squares() []engine.Position {
+	all := make([]engine.Position, 0, 64)
+	for line := int8(1); line <= 8; line++ {
+		for column := int8(1); column <= 8; column++ {
+			all = append(all, engine.Position{Line: line, Column: column})
+		}
+	}
+	return all
+}
@@ -0,0 +1,232 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+const startFEN = "rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w - - 0 1"
+
+func square(name string) engine.Position {
+	return engine.Position{Line: int8(name[1] - '0'), Column: int8(name[0]-'a') + 1}
+}
+
+func TestSquareSize(t *testing.T) {
+	tests := []struct {
+		size, want int
+	}{
+		{0, DefaultSize / 8},
+		{400, 50},
+		{100, 12},
+		{10, MinSize / 8},
+		{5000, MaxSize / 8},
+	}
+	for _, tt := range tests {
+		if got := (Options{Size: tt.size}).squareSize(); got != tt.want {
+			t.Errorf("squareSize(%d) = %d, want %d", tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	tests := []struct {
+		square  string
+		flipped bool
+		x, y    int
+	}{
+		{"a1", false, 0, 350},
+		{"h8", false, 350, 0},
+		{"e4", false, 200, 200},
+		{"a1", true, 350, 0},
+		{"h8", true, 0, 350},
+		{"e4", true, 150, 150},
+	}
+	for _, tt := range tests {
+		x, y := (Options{Flipped: tt.flipped}).origin(square(tt.square), 50)
+		if x != tt.x || y != tt.y {
+			t.Errorf("origin(%s, flipped %v) = %d,%d, want %d,%d", tt.square, tt.flipped, x, y, tt.x, tt.y)
+		}
+	}
+}
+
+func TestArrowOutline(t *testing.T) {
+	if outline := arrowOutline(point{25, 25}, point{25, 25}, 50); outline != nil {
+		t.Errorf("zero-length arrow = %v, want nil", outline)
+	}
+	from, to := point{25, 375}, point{25, 175}
+	outline := arrowOutline(from, to, 50)
+	if len(outline) != 7 || outline[3] != to {
+		t.Fatalf("outline = %v, want 7 points with the tip at %v", outline, to)
+	}
+	for _, pt := range outline {
+		if pt.y < to.y || pt.y > from.y {
+			t.Errorf("point %v lies outside the arrow's length", pt)
+		}
+	}
+}
+
+func renderPNG(t *testing.T, fen string, opts Options) image.Image {
+	t.Helper()
+	board, err := engine.ParseFEN(fen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := PNG(&buf, board, opts); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+func pixel(img image.Image, x, y int) color.NRGBA {
+	return color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+}
+
+func TestPNG(t *testing.T) {
+	e2e4 := engine.Move{From: square("e2"), To: square("e4")}
+	tests := []struct {
+		name   string
+		opts   Options
+		square string
+		want   color.NRGBA // at the square's top-left corner, clear of the piece margin
+	}{
+		{"light square", Options{}, "h1", lightColor},
+		{"dark square", Options{}, "a1", darkColor},
+		{"flipped", Options{Flipped: true}, "h8", darkColor},
+		{"last move", Options{LastMove: &e2e4}, "e4", blend(lightColor, lastMoveColor)},
+		{"untouched by the last move", Options{LastMove: &e2e4}, "e5", darkColor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := renderPNG(t, startFEN, tt.opts)
+			if got := img.Bounds().Size(); got != image.Pt(400, 400) {
+				t.Fatalf("size = %v, want 400x400", got)
+			}
+			x, y := tt.opts.origin(square(tt.square), 50)
+			if got := pixel(img, x, y); !near(got, tt.want) {
+				t.Errorf("%s = %v, want %v", tt.square, got, tt.want)
+			}
+		})
+	}
+
+	img := renderPNG(t, startFEN, Options{Size: 160})
+	if got := img.Bounds().Size(); got != image.Pt(160, 160) {
+		t.Errorf("size = %v, want 160x160", got)
+	}
+	// a piece covers the middle of its square
+	x, y := Options{}.origin(square("e1"), 20)
+	if near(pixel(img, x+10, y+10), darkColor) {
+		t.Error("the white king was not drawn on e1")
+	}
+}
+
+func TestPNGArrow(t *testing.T) {
+	arrow := Options{Arrows: []Arrow{{From: square("e2"), To: square("e5")}}}
+	x, y := arrow.origin(square("e3"), 50)
+	plain := pixel(renderPNG(t, startFEN, Options{}), x+25, y+25)
+	drawn := pixel(renderPNG(t, startFEN, arrow), x+25, y+25)
+	if near(plain, drawn) {
+		t.Errorf("e3 is %v with and without an arrow through it", drawn)
+	}
+}
+
+// blend composites an NRGBA color over an opaque one.
+func blend(dst, src color.NRGBA) color.NRGBA {
+	mix := func(d, s uint8) uint8 {
+		return uint8((int(s)*int(src.A) + int(d)*(255-int(src.A))) / 255)
+	}
+	return color.NRGBA{R: mix(dst.R, src.R), G: mix(dst.G, src.G), B: mix(dst.B, src.B), A: 255}
+}
+
+func near(a, b color.NRGBA) bool {
+	diff := func(x, y uint8) int { return max(int(x)-int(y), int(y)-int(x)) }
+	return diff(a.R, b.R) <= 2 && diff(a.G, b.G) <= 2 && diff(a.B, b.B) <= 2 && diff(a.A, b.A) <= 2
+}
+
+func TestSVG(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		opts      Options
+		pieces    int
+		texts     int
+		polygons  int
+		wantAttrs string
+	}{
+		{"start", startFEN, Options{}, 32, 0, 0, `width="400" height="400"`},
+		{"coordinates", startFEN, Options{Coordinates: true, Size: 256}, 32, 16, 0, `width="256" height="256"`},
+		{"arrows", "4k3/8/8/8/8/8/8/4K3 w - - 0 1", Options{Arrows: []Arrow{
+			{From: square("e1"), To: square("e2")},
+			{From: square("e1"), To: square("e1")}, // zero length, skipped
+			{From: square("e1"), To: engine.Position{Line: 9, Column: 5}},
+		}}, 2, 0, 1, `width="400"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			board, err := engine.ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := SVG(&buf, board, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(buf.String(), tt.wantAttrs) {
+				t.Errorf("document lacks %s", tt.wantAttrs)
+			}
+
+			counts := map[string]int{}
+			depth := 0
+			dec := xml.NewDecoder(&buf)
+			for {
+				tok, err := dec.Token()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("invalid SVG: %v", err)
+				}
+				switch tok := tok.(type) {
+				case xml.StartElement:
+					depth++
+					if tok.Name.Local == "svg" && depth == 2 {
+						counts["piece"]++
+					}
+					counts[tok.Name.Local]++
+				case xml.EndElement:
+					depth--
+				}
+			}
+			if counts["piece"] != tt.pieces || counts["text"] != tt.texts || counts["polygon"] != tt.polygons {
+				t.Errorf("%d pieces, %d labels, %d arrows; want %d, %d, %d", counts["piece"], counts["text"], counts["polygon"], tt.pieces, tt.texts, tt.polygons)
+			}
+		})
+	}
+}
+
+func TestSVGBody(t *testing.T) {
+	tests := []struct {
+		doc, want string
+	}{
+		{`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" width="45"><g><path d="M0 0"/></g></svg>`, `<g><path d="M0 0"/></g>`},
+		{`<g/>`, ""},
+		{`<svg><g/>`, ""},
+	}
+	for _, tt := range tests {
+		if got := svgBody([]byte(tt.doc)); got != tt.want {
+			t.Errorf("svgBody(%q) = %q, want %q", tt.doc, got, tt.want)
+		}
+	}
+}
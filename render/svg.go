@@ -0,0 +1,112 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/ui/assets"
+)
+
+// SVG draws the board as a standalone SVG document. Pieces are inlined from the
+// embedded SVG assets, so the output has no external references.
+func SVG(w io.Writer, board engine.Board, opts Options) error {
+	sq := opts.squareSize()
+	size := sq * 8
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n", size, size, size, size)
+
+	for _, p := range squares() {
+		x, y := opts.origin(p, sq)
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n", x, y, sq, sq, hexColor(squareColor(p)))
+		if opts.LastMove != nil && (p == opts.LastMove.From || p == opts.LastMove.To) {
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" fill-opacity="%.2f"/>`+"\n",
+				x, y, sq, sq, hexColor(lastMoveColor), opacity(lastMoveColor))
+		}
+	}
+
+	if opts.Coordinates {
+		fontSize := max(sq/5, 8)
+		pad := max(sq/24, 1)
+		for i := int8(1); i <= 8; i++ {
+			bottom := engine.Position{Line: 1, Column: i}
+			left := engine.Position{Line: i, Column: 1}
+			if opts.Flipped {
+				bottom.Line = 8
+				left.Column = 8
+			}
+
+			x, y := opts.origin(bottom, sq)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="sans-serif" font-size="%d" text-anchor="end" fill="%s">%s</text>`+"\n",
+				x+sq-pad, y+sq-pad, fontSize, hexColor(oppositeColor(bottom)), engine.SquareName(bottom)[:1])
+			x, y = opts.origin(left, sq)
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="sans-serif" font-size="%d" fill="%s">%s</text>`+"\n",
+				x+pad, y+pad+fontSize, fontSize, hexColor(oppositeColor(left)), engine.SquareName(left)[1:])
+		}
+	}
+
+	for _, piece := range board.PiecesSlice {
+		name := assets.PieceName(piece)
+		if name == "" || !onBoard(piece.Pos) {
+			continue
+		}
+		doc, err := assets.SVG(name)
+		if err != nil {
+			return err
+		}
+		x, y := opts.origin(piece.Pos, sq)
+		fmt.Fprintf(&b, `<svg x="%d" y="%d" width="%d" height="%d" viewBox="0 0 45 45">`, x, y, sq, sq)
+		b.WriteString(svgBody(doc))
+		b.WriteString("</svg>\n")
+	}
+
+	for _, a := range opts.Arrows {
+		if !onBoard(a.From) || !onBoard(a.To) {
+			continue
+		}
+		outline := arrowOutline(opts.center(a.From, sq), opts.center(a.To, sq), sq)
+		if outline == nil {
+			continue
+		}
+		c := a.Color
+		if c == (color.NRGBA{}) {
+			c = DefaultArrowColor
+		}
+		points := make([]string, len(outline))
+		for i, pt := range outline {
+			points[i] = fmt.Sprintf("%.1f,%.1f", pt.x, pt.y)
+		}
+		fmt.Fprintf(&b, `<polygon points="%s" fill="%s" fill-opacity="%.2f"/>`+"\n", strings.Join(points, " "), hexColor(c), opacity(c))
+	}
+
+	b.WriteString("</svg>\n")
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// svgBody strips the XML prolog and outer <svg> element of a piece asset.
+func svgBody(doc []byte) string {
+	s := string(doc)
+	start := strings.Index(s, "<svg")
+	if start < 0 {
+		return ""
+	}
+	open := strings.Index(s[start:], ">")
+	end := strings.LastIndex(s, "</svg>")
+	if open < 0 || end < 0 {
+		return ""
+	}
+	return s[start+open+1 : end]
+}
+
+func hexColor(c color.NRGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func opacity(c color.NRGBA) float64 {
+	return float64(c.A) / 255
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+// resultKey identifies a position and evaluation profile in every result cache.
+type resultKey struct {
+	fen       string
+	whiteTurn bool
+	profile   string
+	hash      uint64 // Zobrist hash, for the on-disk store
+}
+
+// newResultKey keys board on its normalised FEN, so the same position reached through HTTP
+// and gRPC, or written with other counters, shares its results.
+func newResultKey(board game_state.Board, whiteTurn bool, profile string) resultKey {
+	return resultKey{fen: game_state.BoardToFEN(board), whiteTurn: whiteTurn, profile: profile, hash: positionHash(board, whiteTurn)}
+}
+
+// resultCache layers computed.Cache (default depth) and computed.DeepCache (other depths)
+// over the optional on-disk store.
+type resultCache struct {
+	store        *computed.Store
+	defaultDepth int                         // the depth /best-move answers with straight away
+	evalID       func(profile string) string // fingerprint of a profile's weights
+}
+
+// lookup returns the result searched to exactly depth, if any cache has it.
+func (rc resultCache) lookup(k resultKey, depth int) (computed.CacheValue, bool) {
+	if depth == rc.defaultDepth {
+		if v, ok := computed.Cache.Get(rc.key(k)); ok && v.Depth == depth {
+			return v, true
+		}
+	} else if v, ok := computed.DeepCache.Get(rc.deepKey(k, depth)); ok {
+		return v, true
+	}
+
+	v, ok := rc.store.Get(rc.storeKey(k, depth))
+	if ok {
+		rc.remember(k, v)
+	}
+	return v, ok
+}
+
+// save records a finished search in memory and on disk.
+func (rc resultCache) save(k resultKey, v computed.CacheValue) {
+	rc.remember(k, v)
+	if err := rc.store.Put(rc.storeKey(k, v.Depth), v); err != nil {
+		slog.Error("Error writing analysis store", "err", err)
+	}
+}
+
+func (rc resultCache) remember(k resultKey, v computed.CacheValue) {
+	if v.Depth == rc.defaultDepth {
+		computed.Cache.Put(rc.key(k), v)
+		return
+	}
+	computed.DeepCache.Put(rc.deepKey(k, v.Depth), v)
+}
+
+func (rc resultCache) has(k resultKey, depth int) bool {
+	if depth == rc.defaultDepth {
+		return computed.Cache.Contains(rc.key(k))
+	}
+	return computed.DeepCache.Contains(rc.deepKey(k, depth))
+}
+
+// Every key includes the weights, which may have changed since a result was saved: a
+// profile file edited while serving, or NNUE_FILE and EVAL_PROFILE between runs.
+func (rc resultCache) key(k resultKey) computed.CacheKey {
+	return computed.CacheKey{Fen: k.fen, WhiteTurn: k.whiteTurn, Profile: k.profile, Eval: rc.evalID(k.profile)}
+}
+
+func (rc resultCache) storeKey(k resultKey, depth int) computed.StoreKey {
+	return computed.StoreKey{Hash: k.hash, Profile: k.profile, Eval: rc.evalID(k.profile), Depth: depth}
+}
+
+func (rc resultCache) deepKey(k resultKey, depth int) computed.DeepCacheKey {
+	return computed.DeepCacheKey{Fen: k.fen, WhiteTurn: k.whiteTurn, Profile: k.profile, Eval: rc.evalID(k.profile), Depth: depth}
+}
+
+// positionHash keys stored results. The side to move comes from the request rather than the FEN.
+func positionHash(board game_state.Board, whiteTurn bool) uint64 {
+	board.WhiteTurn = whiteTurn
+	return game_state.ZobristHash(board)
+}
+
+// weightsFingerprint identifies evaluation weights by their content.
+func weightsFingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+func paramsFingerprint(params *game_state.EvalParams) string {
+	data, _ := json.Marshal(params)
+	return weightsFingerprint(data)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+)
+
+func TestNewResultKeyNormalisesFEN(t *testing.T) {
+	tests := []struct {
+		name      string
+		fen       string
+		whiteTurn bool
+		same      bool
+	}{
+		{"placement and side only", "4k3/8/8/8/8/8/4P3/4K3 w", true, true},
+		{"other counters", "4k3/8/8/8/8/8/4P3/4K3 w - - 12 40", true, true},
+		{"extra spaces", "4k3/8/8/8/8/8/4P3/4K3  w  -  -  0  1", true, true},
+		{"other side to move", "4k3/8/8/8/8/8/4P3/4K3 b - - 0 1", false, false},
+		{"other position", "4k3/8/8/8/8/4P3/8/4K3 w - - 0 1", true, false},
+	}
+	board, err := game_state.ParseFEN("4k3/8/8/8/8/8/4P3/4K3 w - - 0 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := newResultKey(board, true, "")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := game_state.ParseFEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := newResultKey(b, tt.whiteTurn, ""); (got == want) != tt.same {
+				t.Errorf("key %+v, want same as %+v: %v", got, want, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/g0g05arui/chess-engine/computed"
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/pgn"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultReviewDepth = 3
+	minReviewDepth     = 2 // the played move is searched one ply shallower than the position
+	maxReviewDepth     = 5
+	maxReviewPlies     = 400
+
+	// centipawn loss thresholds
+	inaccuracyLoss = 50
+	mistakeLoss    = 100
+	blunderLoss    = 300
+
+	// scores are capped before losses are taken, so a lost mate does not count for 100000
+	reviewScoreCap = 1000
+)
+
+// Move classifications
+const (
+	Best       = "best"
+	Good       = "good"
+	Inaccuracy = "inaccuracy"
+	Mistake    = "mistake"
+	Blunder    = "blunder"
+	MissedMate = "missed_mate"
+)
+
+type reviewRequest struct {
+	PGN     string   `json:"pgn"`
+	Game    int      `json:"game"` // 1-based game in a multi-game PGN
+	FEN     string   `json:"fen"`  // start position for moves
+	Moves   []string `json:"moves"`
+	Depth   int      `json:"depth"`
+	Profile string   `json:"profile"`
+
+	searchTimeout time.Duration // of each search, within the client's move time limit
+}
+
+type reviewAlternative struct {
+	UCI   string `json:"uci"`
+	SAN   string `json:"san"`
+	Score int    `json:"score"` // white-relative
+}
+
+type reviewedMove struct {
+	Ply            int                `json:"ply"`
+	Number         int                `json:"number"`
+	Color          string             `json:"color"`
+	UCI            string             `json:"uci"`
+	SAN            string             `json:"san"`
+	FEN            string             `json:"fen"`         // position before the move
+	EvalBefore     int                `json:"eval_before"` // white-relative, with best play
+	EvalAfter      int                `json:"eval_after"`  // white-relative, after the played move
+	CentipawnLoss  int                `json:"cp_loss"`
+	Classification string             `json:"classification"`
+	Alternative    *reviewAlternative `json:"alternative,omitempty"` // the engine's move, for errors
+}
+
+type playerReview struct {
+	Accuracy      float64        `json:"accuracy"`
+	AverageCPLoss int            `json:"acpl"`
+	Moves         int            `json:"moves"`
+	Counts        map[string]int `json:"counts"`
+}
+
+type gameReview struct {
+	Depth   int               `json:"depth"`
+	Profile string            `json:"profile,omitempty"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	White   playerReview      `json:"white"`
+	Black   playerReview      `json:"black"`
+	Moves   []reviewedMove    `json:"moves"`
+}
+
+// registerReviewRoutes serves /review. Reviews take their searches from the batch runner's
+// concurrency budget and share its result caches.
+func registerReviewRoutes(r *gin.Engine, br *batchRunner) {
+	// Evaluates every move of a game and classifies it by centipawn loss
+	r.POST("/review", func(c *gin.Context) {
+		var req reviewRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Depth == 0 {
+			req.Depth = min(defaultReviewDepth, clientFrom(c).MaxDepth(br.maxDepth))
+		}
+		if maxDepth := clientFrom(c).MaxDepth(min(maxReviewDepth, br.maxDepth)); req.Depth < minReviewDepth || req.Depth > maxDepth {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("depth must be between %d and %d", minReviewDepth, maxDepth)})
+			return
+		}
+		opts, err := br.optsFor(req.Profile)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		req.searchTimeout = searchTimeoutFor(clientFrom(c), br.searchTimeout, br.maxMoveTimeMS)
+		game, err := reviewGame(req)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		steps, err := game.Replay()
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if len(steps) == 0 {
+			c.JSON(400, gin.H{"error": "the game has no moves"})
+			return
+		}
+		if len(steps) > maxReviewPlies {
+			c.JSON(400, gin.H{"error": fmt.Sprintf("at most %d plies can be reviewed, got %d", maxReviewPlies, len(steps))})
+			return
+		}
+
+		// a review can outlast the write timeout; each of its searches has a deadline instead
+		clearWriteDeadline(c)
+		review, err := br.review(c.Request.Context(), steps, req, opts)
+		if err != nil {
+			c.JSON(503, gin.H{"error": err.Error()})
+			return
+		}
+		if req.PGN != "" {
+			review.Tags = game.Tags
+		}
+		c.JSON(200, review)
+	})
+}
+
+// reviewGame picks the game to review from the PGN, or builds one from the move list.
+func reviewGame(req reviewRequest) (pgn.Game, error) {
+	if req.PGN == "" {
+		if len(req.Moves) == 0 {
+			return pgn.Game{}, errors.New("expected pgn or moves")
+		}
+		g := pgn.Game{Tags: map[string]string{}, Moves: req.Moves}
+		if req.FEN != "" {
+			g.Tags["FEN"] = req.FEN
+		}
+		return g, nil
+	}
+
+	parsed, err := pgn.Parse(req.PGN)
+	if err != nil {
+		return pgn.Game{}, err
+	}
+	if req.Game == 0 {
+		req.Game = 1
+	}
+	if req.Game < 1 || req.Game > len(parsed) {
+		return pgn.Game{}, fmt.Errorf("game must be between 1 and %d", len(parsed))
+	}
+	return parsed[req.Game-1], nil
+}
+
+// review evaluates the position before every move and the position after it. Steps are
+// searched in parallel within the shared budget.
+func (br *batchRunner) review(ctx context.Context, steps []pgn.Step, req reviewRequest, opts []game_state.SearchOption) (gameReview, error) {
+	opts = append(slices.Clip(opts), game_state.WithWorkers(br.searchWorkers))
+
+	// the first failed search abandons the review
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	moves := make([]reviewedMove, len(steps))
+	br.forEach(ctx, len(steps), func(i int) {
+		if !br.acquire(ctx) {
+			return
+		}
+		defer func() { <-br.slots }()
+		rm, err := br.reviewMove(ctx, steps[i], req, opts)
+		if err != nil {
+			cancel(err)
+			return
+		}
+		moves[i] = rm
+	})
+	if ctx.Err() != nil {
+		return gameReview{}, context.Cause(ctx)
+	}
+
+	review := gameReview{Depth: req.Depth, Profile: req.Profile, Moves: moves}
+	review.White = summarize(moves, "white")
+	review.Black = summarize(moves, "black")
+	return review, nil
+}
+
+func (br *batchRunner) reviewMove(ctx context.Context, st pgn.Step, req reviewRequest, opts []game_state.SearchOption) (reviewedMove, error) {
+	board := st.Before
+	mover := game_state.SideToMove(board)
+	_, fullmove := game_state.FENCounters(st.FEN)
+	rm := reviewedMove{
+		Ply:    st.Ply,
+		Number: fullmove,
+		Color:  colorName(mover),
+		UCI:    game_state.MoveToUCI(board, st.Move),
+		SAN:    st.SAN,
+		FEN:    st.FEN,
+	}
+
+	// scores below are from the mover's point of view
+	best, err := br.searchCached(ctx, board, req.Depth, req.Profile, req.searchTimeout, opts)
+	if err != nil {
+		return rm, err
+	}
+	played := best.Score
+	if st.Move != best.BestMove {
+		child := game_state.BoardAfterMove(st.Move, board)
+		switch game_state.GameStatus(child, 0).State {
+		case game_state.Checkmate:
+			played = game_state.INF
+		case game_state.Stalemate:
+			played = 0
+		default:
+			reply, err := br.searchCached(ctx, child, req.Depth-1, req.Profile, req.searchTimeout, opts)
+			if err != nil {
+				return rm, err
+			}
+			played = -reply.Score
+		}
+	}
+
+	rm.EvalBefore = whiteRelative(best.Score, mover)
+	rm.EvalAfter = whiteRelative(played, mover)
+	rm.CentipawnLoss = max(0, capScore(best.Score)-capScore(played))
+
+	switch {
+	case st.Move == best.BestMove || played >= best.Score:
+		rm.Classification = Best
+		rm.CentipawnLoss = 0
+	case winningMate(best.Score) && !winningMate(played):
+		rm.Classification = MissedMate
+	case rm.CentipawnLoss >= blunderLoss:
+		rm.Classification = Blunder
+	case rm.CentipawnLoss >= mistakeLoss:
+		rm.Classification = Mistake
+	case rm.CentipawnLoss >= inaccuracyLoss:
+		rm.Classification = Inaccuracy
+	default:
+		rm.Classification = Good
+	}
+
+	if rm.Classification != Best && rm.Classification != Good {
+		rm.Alternative = &reviewAlternative{
+			UCI:   game_state.MoveToUCI(board, best.BestMove),
+			SAN:   game_state.MoveToSAN(board, best.BestMove),
+			Score: rm.EvalBefore,
+		}
+	}
+	return rm, nil
+}
+
+// searchCached searches board for its side to move within timeout, going through the result
+// caches.
+func (br *batchRunner) searchCached(ctx context.Context, board game_state.Board, depth int, profile string, timeout time.Duration, opts []game_state.SearchOption) (computed.CacheValue, error) {
+	key := newResultKey(board, board.WhiteTurn, profile)
+	if v, ok := br.results.lookup(key, depth); ok {
+		return v, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	move, score := game_state.BestMove(board, depth, game_state.SideToMove(board), append(slices.Clip(opts), game_state.WithContext(ctx))...)
+	if ctx.Err() != nil {
+		return computed.CacheValue{}, searchErr(ctx)
+	}
+	v := computed.CacheValue{BestMove: move, Depth: depth, Score: score}
+	br.results.save(key, v)
+	return v, nil
+}
+
+// summarize computes a player's accuracy from the change in win probability of each move,
+// the way the big chess sites do.
+func summarize(moves []reviewedMove, color string) playerReview {
+	pr := playerReview{Counts: map[string]int{Best: 0, Good: 0, Inaccuracy: 0, Mistake: 0, Blunder: 0, MissedMate: 0}}
+	totalLoss, totalAccuracy := 0, 0.0
+	for _, m := range moves {
+		if m.Color != color {
+			continue
+		}
+		pr.Moves++
+		pr.Counts[m.Classification]++
+		totalLoss += m.CentipawnLoss
+
+		before, after := winPercent(m.EvalBefore), winPercent(m.EvalAfter)
+		if color == "black" {
+			before, after = 100-before, 100-after
+		}
+		acc := 103.1668*math.Exp(-0.04354*max(0, before-after)) - 3.1669
+		totalAccuracy += min(100, max(0, acc))
+	}
+	if pr.Moves > 0 {
+		pr.AverageCPLoss = totalLoss / pr.Moves
+		pr.Accuracy = math.Round(totalAccuracy/float64(pr.Moves)*10) / 10
+	}
+	return pr
+}
+
+// winPercent maps a white-relative score to white's chance of winning.
+func winPercent(score int) float64 {
+	cp := float64(capScore(score))
+	return 50 + 50*(2/(1+math.Exp(-0.00368208*cp))-1)
+}
+
+// winningMate reports whether a score from the mover's view is a forced mate for the mover.
+func winningMate(score int) bool {
+	return score > 0 && game_state.IsMateScore(score)
+}
+
+func capScore(score int) int {
+	return min(reviewScoreCap, max(-reviewScoreCap, score))
+}
+
+func whiteRelative(score int, color game_state.PieceColor) int {
+	if color == game_state.BlackColor {
+		return -score
+	}
+	return score
+}
+
+func colorName(c game_state.PieceColor) string {
+	if c == game_state.WhiteColor {
+		return "white"
+	}
+	return "black"
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/g0g05arui/chess-engine/game_state"
+	"github.com/gin-gonic/gin"
+)
+
+func TestSummarize(t *testing.T) {
+	moves := []reviewedMove{
+		{Color: "white", Classification: Best, EvalBefore: 30, EvalAfter: 30},
+		{Color: "black", Classification: Good, EvalBefore: 30, EvalAfter: 60, CentipawnLoss: 30},
+		{Color: "white", Classification: Blunder, EvalBefore: 60, EvalAfter: -400, CentipawnLoss: 460},
+		{Color: "black", Classification: Best, EvalBefore: -400, EvalAfter: -400},
+		{Color: "white", Classification: Mistake, EvalBefore: -400, EvalAfter: -520, CentipawnLoss: 120},
+	}
+	tests := []struct {
+		color       string
+		moves       int
+		acpl        int
+		counts      map[string]int
+		minAccuracy float64
+		maxAccuracy float64
+	}{
+		{"white", 3, 193, map[string]int{Best: 1, Blunder: 1, Mistake: 1}, 30, 70},
+		{"black", 2, 15, map[string]int{Best: 1, Good: 1}, 90, 100},
+		{"nobody", 0, 0, map[string]int{}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.color, func(t *testing.T) {
+			pr := summarize(moves, tt.color)
+			if pr.Moves != tt.moves || pr.AverageCPLoss != tt.acpl {
+				t.Errorf("%d moves, acpl %d; want %d, %d", pr.Moves, pr.AverageCPLoss, tt.moves, tt.acpl)
+			}
+			for _, class := range []string{Best, Good, Inaccuracy, Mistake, Blunder, MissedMate} {
+				if n, ok := pr.Counts[class]; !ok || n != tt.counts[class] {
+					t.Errorf("%s count = %d (present %v), want %d", class, n, ok, tt.counts[class])
+				}
+			}
+			if pr.Accuracy < tt.minAccuracy || pr.Accuracy > tt.maxAccuracy {
+				t.Errorf("accuracy = %v, want between %v and %v", pr.Accuracy, tt.minAccuracy, tt.maxAccuracy)
+			}
+		})
+	}
+}
+
+func TestWinPercent(t *testing.T) {
+	tests := []struct {
+		score    int
+		min, max float64
+	}{
+		{0, 50, 50},
+		{300, 74, 76},
+		{-300, 24, 26},
+		{game_state.INF, 97, 98}, // capped at reviewScoreCap
+		{-game_state.INF, 2, 3},
+	}
+	for _, tt := range tests {
+		if got := winPercent(tt.score); got < tt.min || got > tt.max {
+			t.Errorf("winPercent(%d) = %v, want between %v and %v", tt.score, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestReviewGame(t *testing.T) {
+	const twoGames = `[Event "one"]
+
+1. e4 e5 *
+
+[Event "two"]
+
+1. d4 d5 *
+`
+	tests := []struct {
+		name      string
+		req       reviewRequest
+		wantMoves []string
+		wantErr   string
+	}{
+		{"moves", reviewRequest{Moves: []string{"e4", "e5"}}, []string{"e4", "e5"}, ""},
+		{"first game by default", reviewRequest{PGN: twoGames}, []string{"e4", "e5"}, ""},
+		{"second game", reviewRequest{PGN: twoGames, Game: 2}, []string{"d4", "d5"}, ""},
+		{"game out of range", reviewRequest{PGN: twoGames, Game: 3}, nil, "between 1 and 2"},
+		{"nothing to review", reviewRequest{}, nil, "expected pgn or moves"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := reviewGame(tt.req)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("reviewGame = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(g.Moves, " ") != strings.Join(tt.wantMoves, " ") {
+				t.Errorf("moves = %v, want %v", g.Moves, tt.wantMoves)
+			}
+		})
+	}
+
+	g, err := reviewGame(reviewRequest{FEN: "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1", Moves: []string{"e4"}})
+	if err != nil || g.Tags["FEN"] != "4k3/8/8/8/8/8/4P3/4K3 w - - 0 1" {
+		t.Errorf("start position not kept: %v, %v", g.Tags, err)
+	}
+}
+
+func newReviewServer(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	rc := resultCache{defaultDepth: 4, evalID: func(string) string { return "review-test" }}
+	optsFor := func(profile string) ([]game_state.SearchOption, error) { return nil, nil }
+	cfg := Config{BatchConcurrency: 2, JobSearchWorkers: 1, MaxDepth: 8, SearchTimeoutSeconds: 30, MaxMoveTimeMS: 30000}
+	r := gin.New()
+	registerReviewRoutes(r, newBatchRunner(rc, optsFor, cfg, context.Background()))
+	return r
+}
+
+func postReview(t *testing.T, r http.Handler, body any) (int, []byte) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/review", bytes.NewReader(data)))
+	return w.Code, w.Body.Bytes()
+}
+
+func TestReviewRoute(t *testing.T) {
+	r := newReviewServer(t)
+	code, body := postReview(t, r, reviewRequest{Moves: []string{"e4", "e5", "Qh5", "Nc6", "Bc4", "Nf6", "Qxf7#"}, Depth: 2})
+	if code != 200 {
+		t.Fatalf("status %d: %s", code, body)
+	}
+	var review gameReview
+	if err := json.Unmarshal(body, &review); err != nil {
+		t.Fatal(err)
+	}
+	if review.Depth != 2 || len(review.Moves) != 7 {
+		t.Fatalf("depth %d, %d moves", review.Depth, len(review.Moves))
+	}
+	for i, m := range review.Moves {
+		if m.Ply != i || m.Number != i/2+1 {
+			t.Errorf("move %d: ply %d, number %d", i, m.Ply, m.Number)
+		}
+		if m.Classification == Best && m.CentipawnLoss != 0 {
+			t.Errorf("%s is best with a loss of %d", m.SAN, m.CentipawnLoss)
+		}
+		if (m.Alternative != nil) != (m.Classification != Best && m.Classification != Good) {
+			t.Errorf("%s (%s): alternative %+v", m.SAN, m.Classification, m.Alternative)
+		}
+	}
+
+	nf6, mate := review.Moves[5], review.Moves[6]
+	if nf6.Color != "black" || nf6.Classification != Blunder || nf6.CentipawnLoss < blunderLoss {
+		t.Errorf("Nf6 = %+v, want a black blunder", nf6)
+	}
+	if nf6.Alternative == nil || nf6.Alternative.UCI == "g8f6" {
+		t.Errorf("Nf6 alternative = %+v", nf6.Alternative)
+	}
+	if mate.UCI != "h5f7" || mate.Classification != Best || mate.EvalBefore <= reviewScoreCap {
+		t.Errorf("Qxf7# = %+v, want the best move with a mate score", mate)
+	}
+	if review.White.Moves != 4 || review.Black.Moves != 3 || review.Black.Counts[Blunder] != 1 {
+		t.Errorf("white %+v, black %+v", review.White, review.Black)
+	}
+	if review.White.Accuracy <= review.Black.Accuracy {
+		t.Errorf("white accuracy %v not above black's %v", review.White.Accuracy, review.Black.Accuracy)
+	}
+}
+
+func TestReviewRouteErrors(t *testing.T) {
+	r := newReviewServer(t)
+	tests := []struct {
+		name    string
+		req     reviewRequest
+		wantErr string
+	}{
+		{"depth too shallow", reviewRequest{Moves: []string{"e4"}, Depth: 1}, "depth must be between 2 and 5"},
+		{"depth too deep", reviewRequest{Moves: []string{"e4"}, Depth: 6}, "depth must be between 2 and 5"},
+		{"no game", reviewRequest{Depth: 2}, "expected pgn or moves"},
+		{"illegal move", reviewRequest{Moves: []string{"e4", "e4"}, Depth: 2}, "e4"},
+		{"no moves", reviewRequest{PGN: "[Event \"empty\"]\n\n*\n", Depth: 2}, "no moves"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := postReview(t, r, tt.req)
+			if code != 400 || !strings.Contains(string(body), tt.wantErr) {
+				t.Errorf("status %d: %s, want 400 with %q", code, body, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+)
+
+// errSearchTimeout is returned when a search started by a request runs past its deadline.
+var errSearchTimeout = errors.New("search timed out")
+
+// serve runs the HTTP server, and the gRPC server unless it is nil, until SIGINT or SIGTERM,
+// then drains them: no new connections are accepted and in-flight requests and calls get
+// cfg.ShutdownTimeoutSeconds to finish before they are cancelled. shuttingDown is called as
+// soon as the signal arrives, so streams that would otherwise hold the drain open can end early.
+func serve(cfg Config, handler http.Handler, grpcServer *grpc.Server, shuttingDown func()) error {
+	signals, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stopSignals()
+
+	// every request context derives from base, which is cancelled if the drain times out
+	base, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	srv := &http.Server{
+		Addr:              cfg.Addr(),
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Duration(cfg.ReadTimeoutSeconds) * time.Second,
+		WriteTimeout:      time.Duration(cfg.WriteTimeoutSeconds) * time.Second,
+		IdleTimeout:       time.Duration(cfg.IdleTimeoutSeconds) * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return base },
+	}
+
+	errs := make(chan error, 2)
+	go func() {
+		slog.Info("Listening", "addr", srv.Addr)
+		errs <- srv.ListenAndServe()
+	}()
+	if grpcServer != nil {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr())
+		if err != nil {
+			return err
+		}
+		go func() {
+			slog.Info("Listening for gRPC", "addr", lis.Addr().String())
+			errs <- grpcServer.Serve(lis)
+		}()
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	case <-signals.Done():
+	}
+	stopSignals() // a second signal kills the process straight away
+
+	slog.Info("Shutting down, draining requests", "timeout", time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	shuttingDown()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSeconds)*time.Second)
+	defer cancel()
+	grpcDrained := make(chan struct{})
+	go func() {
+		if grpcServer != nil {
+			grpcServer.GracefulStop()
+		}
+		close(grpcDrained)
+	}()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Warn("Requests still running after the shutdown timeout, cancelling them", "err", err)
+		cancelRequests()
+		srv.Close()
+	}
+	if grpcServer == nil {
+		return nil
+	}
+	// both cases can be ready once the timeout has passed, and select would pick either
+	select {
+	case <-grpcDrained:
+		return nil
+	default:
+	}
+	select {
+	case <-grpcDrained:
+	case <-ctx.Done():
+		slog.Warn("gRPC calls still running after the shutdown timeout, cancelling them")
+		grpcServer.Stop()
+	}
+	return nil
+}
+
+// clearWriteDeadline lifts the server's write timeout for a response that streams or
+// otherwise outlives it; such handlers bound their own running time.
+func clearWriteDeadline(c *gin.Context) {
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Debug("Cannot clear write deadline", "err", err)
+	}
+}
+
+// searchErr tells a search cut short by its deadline from one cancelled with the request.
+func searchErr(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errSearchTimeout
+	}
+	return ctx.Err()
+}
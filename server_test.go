@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// serveTestConfig listens on a free loopback port.
+func serveTestConfig(t *testing.T, shutdownSeconds int) Config {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := lis.Addr().(*net.TCPAddr).Port
+	lis.Close()
+	return Config{Bind: "127.0.0.1", Port: port, ReadTimeoutSeconds: 5, WriteTimeoutSeconds: 5, IdleTimeoutSeconds: 5, ShutdownTimeoutSeconds: shutdownSeconds}
+}
+
+// startServe runs serve until it returns, which is reported on the channel.
+func startServe(t *testing.T, cfg Config, handler http.Handler, shuttingDown func()) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- serve(cfg, handler, nil, shuttingDown) }()
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		conn, err := net.Dial("tcp", cfg.Addr())
+		if err == nil {
+			conn.Close()
+			return done
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("server did not start: %v", err)
+		}
+	}
+}
+
+func TestServeDrainsOnSIGTERM(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "finished")
+	})
+	drainStarted := make(chan struct{})
+	cfg := serveTestConfig(t, 10)
+	done := startServe(t, cfg, handler, func() { close(drainStarted) })
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + cfg.Addr() + "/")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+	<-started
+
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+	<-drainStarted
+	select {
+	case err := <-done:
+		t.Fatalf("serve returned %v with a request in flight", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if _, err := net.DialTimeout("tcp", cfg.Addr(), time.Second); err == nil {
+		t.Error("new connections are accepted while draining")
+	}
+
+	close(release)
+	if res := <-responses; res.err != nil || res.body != "finished" {
+		t.Errorf("in-flight request = %q, %v", res.body, res.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("serve = %v", err)
+	}
+}
+
+func TestServeCancelsRequestsAfterShutdownTimeout(t *testing.T) {
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(cancelled)
+	})
+	cfg := serveTestConfig(t, 1)
+	done := startServe(t, cfg, handler, func() {})
+
+	go http.Get("http://" + cfg.Addr() + "/")
+	<-started
+	begin := time.Now()
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the request was not cancelled after the shutdown timeout")
+	}
+	if elapsed := time.Since(begin); elapsed < 900*time.Millisecond {
+		t.Errorf("request cancelled after %v, before the 1s shutdown timeout", elapsed)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("serve = %v", err)
+	}
+}
+
+func TestServeReportsListenErrors(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	cfg := Config{Bind: "127.0.0.1", Port: lis.Addr().(*net.TCPAddr).Port, ShutdownTimeoutSeconds: 1}
+	err = serve(cfg, http.NotFoundHandler(), nil, func() { t.Error("shutting down without a signal") })
+	if err == nil {
+		t.Error("serve on a port in use returned nil")
+	}
+}
+
+func TestSearchErr(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-expired.Done()
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want error
+	}{
+		{"deadline", expired, errSearchTimeout},
+		{"cancelled", cancelled, context.Canceled},
+		{"running", context.Background(), nil},
+	}
+	for _, tt := range tests {
+		if err := searchErr(tt.ctx); !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+			t.Errorf("%s: searchErr = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package assets embeds the piece images shared by the desktop UI and the server's
+// board renderer, so neither depends on the working directory.
+package assets
+
+import (
+	"embed"
+	"image"
+	"image/png"
+
+	engine "github.com/g0g05arui/chess-engine/game_state"
+)
+
+//go:embed pieces/*.png pieces/*.svg
+var Pieces embed.FS
+
+// PieceName returns the asset name of a piece, e.g. "knight_white", or "" for an empty square.
+func PieceName(p engine.Piece) string {
+	var name string
+	switch p.Type {
+	case engine.Pawn:
+		name = "pawn"
+	case engine.Knight:
+		name = "knight"
+	case engine.Bishop:
+		name = "bishop"
+	case engine.Rook:
+		name = "rook"
+	case engine.Queen:
+		name = "queen"
+	case engine.King:
+		name = "king"
+	default:
+		return ""
+	}
+	if p.Color == engine.WhiteColor {
+		return name + "_white"
+	}
+	return name + "_black"
+}
+
+// PNG decodes the 64x64 bitmap of the named piece.
+func PNG(name string) (image.Image, error) {
+	f, err := Pieces.Open("pieces/" + name + ".png")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+// SVG returns the named piece as a standalone 45x45 SVG document.
+func SVG(name string) ([]byte, error) {
+	return Pieces.ReadFile("pieces/" + name + ".svg")
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
 	"image/color"
 	"log"
 	"os"
+	"path/filepath"
 	"time"
 
 	"gioui.org/app"
@@ -21,7 +23,9 @@ import (
 	"gioui.org/widget"
 	"gioui.org/widget/material"
 
+	"github.com/g0g05arui/chess-engine/book"
 	engine "github.com/g0g05arui/chess-engine/game_state"
+	"github.com/g0g05arui/chess-engine/ui/assets"
 )
 
 // game state
@@ -35,6 +39,13 @@ var depthSlider widget.Float
 var selectedDepth int = 4
 var moveStartTime time.Time
 var isCalculatingMove bool = false
+var searchOpts []engine.SearchOption
+var profileName = "default"
+
+// Opening book, and what its keys need that the board does not track
+var openingBook *book.Book
+var lastMove *engine.Move
+var plies int
 
 // Game mode variables
 var botVsBotMode bool = false
@@ -43,6 +54,12 @@ var selectedSquare *engine.Position = nil
 var validMoves []engine.Move
 var boardTag struct{}
 
+// Evaluation side panel, recomputed only when the position changes
+var evalTrace engine.EvalTrace
+var evalTraceFEN string
+
+const panelWidth = 300
+
 // UI elements
 var startButton widget.Clickable
 var newGameButton widget.Clickable
@@ -57,17 +74,11 @@ func loadPieceImages() {
 	}
 
 	for _, name := range files {
-		path := "assets/pieces/" + name + ".png"
-		f, err := os.Open(path)
+		img, err := assets.PNG(name)
 		if err != nil {
 			log.Fatalf("error loading %s: %v", name, err)
 		}
-		img, _, err := image.Decode(f)
-		if err != nil {
-			log.Fatalf("error decoding %s: %v", name, err)
-		}
 		pieceImages[name] = img
-		f.Close()
 	}
 }
 
@@ -76,6 +87,42 @@ func initTheme() {
 }
 
 func main() {
+	nnueFile := flag.String("nnue", "", "path to NNUE weights; the handcrafted evaluation is used when empty")
+	profileFile := flag.String("profile", "", "path to an evaluation profile; the built-in weights are used when empty")
+	bookFile := flag.String("book", "", "path to a Polyglot opening book; the engine searches every move when empty")
+	bookDepth := flag.Int("book-depth", 20, "plies from the start the opening book is used for, no limit when 0")
+	bookBest := flag.Bool("book-best", false, "always play the book's highest-weighted move instead of picking by weight")
+	flag.Parse()
+
+	if *profileFile != "" {
+		params, err := engine.LoadEvalParams(*profileFile)
+		if err != nil {
+			log.Fatalf("error loading evaluation profile: %v", err)
+		}
+		engine.SetEvalParams(params)
+		profileName = filepath.Base(*profileFile)
+	}
+
+	if *nnueFile != "" {
+		net, err := engine.LoadNetwork(*nnueFile)
+		if err != nil {
+			log.Fatalf("error loading NNUE weights: %v", err)
+		}
+		searchOpts = append(searchOpts, engine.WithEvaluator(engine.NNUEEvaluator{Net: net}))
+	}
+
+	if *bookFile != "" {
+		opts := book.Options{Depth: *bookDepth}
+		if *bookBest {
+			opts.Selection = book.Best
+		}
+		var err error
+		openingBook, err = book.Open(*bookFile, opts)
+		if err != nil {
+			log.Fatalf("error loading opening book: %v", err)
+		}
+	}
+
 	loadPieceImages()
 	initTheme()
 	go func() {
@@ -128,6 +175,8 @@ func resetGame() {
 
 	// Reset game counters in the board
 	board.Played = make(map[string]int)
+	lastMove = nil
+	plies = 0
 }
 
 func getSquareFromPosition(x, y int, boardSize int) *engine.Position {
@@ -161,11 +210,11 @@ func run(w *app.Window) error {
 	board = engine.CreateBoard()
 	turn := engine.WhiteColor
 
-	// fixed 600×600 window
+	// fixed window: 600×600 board plus the evaluation panel
 	w.Option(
-		app.Size(600, 600),
-		app.MaxSize(600, 600),
-		app.MinSize(600, 600),
+		app.Size(600+panelWidth, 600),
+		app.MaxSize(600+panelWidth, 600),
+		app.MinSize(600+panelWidth, 600),
 	)
 
 	for {
@@ -180,7 +229,8 @@ func run(w *app.Window) error {
 			// ─── MAIN GAME STATE ────────────────────────────────────────────────
 			if gameStarted && !gameEnded {
 
-				drawChessBoard(gtx, board) // also registers event.Op for boardTag
+				drawChessBoard(boardContext(gtx), board) // also registers event.Op for boardTag
+				drawEvalPanel(gtx, board)
 
 				if ended, reason := checkGameEnd(board, colorTurn); ended {
 					gameEnded = true
@@ -192,8 +242,11 @@ func run(w *app.Window) error {
 						isCalculatingMove = true
 						moveStartTime = time.Now()
 
-						go func(b engine.Board, c engine.PieceColor) {
-							mv, _ := engine.BestMove(b, selectedDepth, c)
+						go func(b engine.Board, c engine.PieceColor, last *engine.Move, ply int) {
+							mv, ok := openingBook.Pick(b, book.Key(b, last), ply)
+							if !ok {
+								mv, _ = engine.BestMove(b, selectedDepth, c, searchOpts...)
+							}
 
 							// ensure at least 1 s thinking time for smoother UX
 							if d := time.Since(moveStartTime); d < time.Millisecond {
@@ -201,6 +254,7 @@ func run(w *app.Window) error {
 							}
 
 							board = engine.BoardAfterMove(mv, board)
+							lastMove, plies = &mv, plies+1
 							if turn == engine.WhiteColor {
 								turn = engine.BlackColor
 							} else {
@@ -209,7 +263,7 @@ func run(w *app.Window) error {
 							colorTurn = turn
 							isCalculatingMove = false
 							w.Invalidate()
-						}(board, turn)
+						}(board, turn, lastMove, plies)
 					}
 
 					// ---------- human move (human is White) -------------------------
@@ -223,7 +277,7 @@ func run(w *app.Window) error {
 							break // no more events this frame
 						}
 						if pe, ok := ev.(pointer.Event); ok && pe.Kind == pointer.Press {
-							boardSize := gtx.Constraints.Max.X
+							boardSize := gtx.Constraints.Max.Y
 							clicked := getSquareFromPosition(int(pe.Position.X), int(pe.Position.Y), boardSize)
 							if clicked == nil {
 								continue
@@ -244,6 +298,7 @@ func run(w *app.Window) error {
 								// second click – try to make a legal move
 								if mv := isValidMove(*selectedSquare, *clicked, validMoves); mv != nil {
 									board = engine.BoardAfterMove(*mv, board)
+									lastMove, plies = mv, plies+1
 									turn = engine.BlackColor
 									colorTurn = turn
 								}
@@ -258,7 +313,8 @@ func run(w *app.Window) error {
 				w.Invalidate()
 
 			} else if gameEnded {
-				drawChessBoard(gtx, board)
+				drawChessBoard(boardContext(gtx), board)
+				drawEvalPanel(gtx, board)
 				drawGameEndOverlay(gtx, w)
 				e.Frame(gtx.Ops)
 
@@ -429,28 +485,94 @@ func drawChessBoard(gtx layout.Context, board engine.Board) {
 	}
 }
 
-func pieceKey(p engine.Piece) string {
-	var name string
-	switch p.Type {
-	case engine.Pawn:
-		name = "pawn"
-	case engine.Knight:
-		name = "knight"
-	case engine.Bishop:
-		name = "bishop"
-	case engine.Rook:
-		name = "rook"
-	case engine.Queen:
-		name = "queen"
-	case engine.King:
-		name = "king"
-	default:
-		return ""
+// boardContext narrows the frame to the square board on the left of the window.
+func boardContext(gtx layout.Context) layout.Context {
+	gtx.Constraints.Max.X = gtx.Constraints.Max.Y
+	return gtx
+}
+
+// drawEvalPanel lists every evaluation term of the current position to the right of the board.
+func drawEvalPanel(gtx layout.Context, b engine.Board) {
+	if theme == nil {
+		initTheme()
 	}
-	if p.Color == engine.WhiteColor {
-		return name + "_white"
+
+	fen := engine.BoardToFEN(b)
+	if fen != evalTraceFEN {
+		evalTrace = engine.EvaluateTrace(b)
+		evalTraceFEN = fen
 	}
-	return name + "_black"
+
+	boardSize := gtx.Constraints.Max.Y
+	defer op.Offset(image.Pt(boardSize, 0)).Push(gtx.Ops).Pop()
+	gtx.Constraints = layout.Exact(image.Pt(gtx.Constraints.Max.X-boardSize, gtx.Constraints.Max.Y))
+
+	background := clip.Rect{Max: gtx.Constraints.Max}.Push(gtx.Ops)
+	paint.Fill(gtx.Ops, color.NRGBA{R: 245, G: 245, B: 245, A: 255})
+	background.Pop()
+
+	grey := color.NRGBA{R: 100, G: 100, B: 100, A: 255}
+	row := func(cells ...string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			children := make([]layout.FlexChild, len(cells))
+			for i, cell := range cells {
+				cell := cell
+				children[i] = layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					label := material.Caption(theme, cell)
+					if i > 0 {
+						label.Alignment = text.End
+					}
+					return label.Layout(gtx)
+				})
+			}
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx, children...)
+		})
+	}
+
+	layout.UniformInset(unit.Dp(12)).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return material.H6(theme, "Evaluation").Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				desc := material.Caption(theme, fmt.Sprintf("Profile %s, phase %d/%d, scores mg/eg", profileName, evalTrace.Phase, evalTrace.MaxPhase))
+				desc.Color = grey
+				return desc.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Spacer{Height: unit.Dp(10)}.Layout(gtx)
+			}),
+			row("Term", "White", "Black", "Total"),
+		}
+
+		for _, t := range evalTrace.Terms {
+			children = append(children, row(
+				t.Name,
+				fmt.Sprintf("%d/%d", t.White.MG, t.White.EG),
+				fmt.Sprintf("%d/%d", t.Black.MG, t.Black.EG),
+				fmt.Sprintf("%d", t.Total),
+			))
+		}
+
+		children = append(children,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return layout.Spacer{Height: unit.Dp(10)}.Layout(gtx)
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				summary := fmt.Sprintf("Score (White): %d", evalTrace.Score)
+				if evalTrace.Terminal != "" {
+					summary += " – " + evalTrace.Terminal
+				}
+				return material.Body1(theme, summary).Layout(gtx)
+			}),
+		)
+
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
+func pieceKey(p engine.Piece) string {
+	return assets.PieceName(p)
 }
 
 func draw_menu(gtx layout.Context, w *app.Window) {